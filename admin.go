@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+const adminSecretHeader = "X-Admin-Secret"
+
+// requireAdminSecret refuses requests whose X-Admin-Secret header does
+// not match Config.AdminSecret with 401 Unauthorized.
+func (s *Server) requireAdminSecret(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		secret := request.Header.Get(adminSecretHeader)
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(s.config.AdminSecret)) != 1 {
+			s.logger.Warn("Отклонён запрос к API администратора", "ip", clientIP(request), "path", request.URL.Path)
+			localizedError(writer, request, MsgAdminSecretInvalid, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// writeJSON marshals value and writes it with the given status. If
+// marshaling fails, it responds with a HTTP 500 Internal Server Error.
+func (s *Server) writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	response, err := json.Marshal(value)
+	if err != nil {
+		s.logger.Error("Не удалось сериализовать ответ", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	writer.Write(response)
+}
+
+// AdminStats describes the state of the running process.
+type AdminStats struct {
+	Goroutines    int    `json:"goroutines"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	HeapAlloc     uint64 `json:"heap_alloc_bytes"`
+	HeapObjects   uint64 `json:"heap_objects"`
+	TotalAlloc    uint64 `json:"total_alloc_bytes"`
+	Sys           uint64 `json:"sys_bytes"`
+	NumGC         uint32 `json:"num_gc"`
+}
+
+// getAdminStats writes the goroutine count, memory statistics and
+// uptime of the server in JSON format to the provided http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - req: The http.Request received from the client. This parameter is ignored in this function.
+func (s *Server) getAdminStats(writer http.ResponseWriter, _ *http.Request) {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	s.writeJSON(writer, http.StatusOK, AdminStats{
+		Goroutines:    runtime.NumGoroutine(),
+		UptimeSeconds: int64(s.clock.Now().Sub(s.startedAt) / time.Second),
+		HeapAlloc:     memory.HeapAlloc,
+		HeapObjects:   memory.HeapObjects,
+		TotalAlloc:    memory.TotalAlloc,
+		Sys:           memory.Sys,
+		NumGC:         memory.NumGC,
+	})
+}
+
+// getAdminTaskCount writes the number of stored tasks as {"count":N}.
+// In case of a storage error, it responds with the status chosen by
+// storageErrorStatus along with the error message.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client.
+func (s *Server) getAdminTaskCount(writer http.ResponseWriter, request *http.Request) {
+	tasks, err := s.store.GetAll(request.Context(), nil)
+	if err != nil {
+		s.logger.Error("Не удалось подсчитать задачи", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, map[string]int{"count": len(tasks)})
+}
+
+// AdminCacheFlushResult tells how many entries POST /admin/cache/flush
+// dropped from each cache.
+type AdminCacheFlushResult struct {
+	IdempotencyResponses int `json:"idempotency_responses"`
+}
+
+// postAdminCacheFlush drops the responses kept for Idempotency-Key
+// replays, so that retried requests are handled again, and writes an
+// AdminCacheFlushResult in JSON format.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client.
+func (s *Server) postAdminCacheFlush(writer http.ResponseWriter, request *http.Request) {
+	flushed, err := s.idempotency.Flush(request.Context())
+	if err != nil {
+		s.logger.Error("Не удалось очистить кэш", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, AdminCacheFlushResult{IdempotencyResponses: flushed})
+	s.logger.Info("Кэш очищен", "idempotency_responses", flushed)
+}
+
+// AdminCompactResult is the response of POST /admin/storage/compact.
+type AdminCompactResult struct {
+	Tasks int `json:"tasks"`
+}
+
+// postAdminStorageCompact compacts the task storage and writes an
+// AdminCompactResult with the number of tasks it holds. A storage that
+// cannot be compacted is reported with a HTTP 501 Not Implemented.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client.
+func (s *Server) postAdminStorageCompact(writer http.ResponseWriter, request *http.Request) {
+	compacter, ok := s.store.(Compacter)
+	if !ok {
+		http.Error(writer, "The storage cannot be compacted", http.StatusNotImplemented)
+		return
+	}
+
+	tasks, err := compacter.Compact(request.Context())
+	if err != nil {
+		s.logger.Error("Не удалось сжать хранилище", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, AdminCompactResult{Tasks: tasks})
+	s.logger.Info("Хранилище сжато", "tasks", tasks)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newAdminTestServer creates a test server over the fixtures with the
+// admin API enabled.
+func newAdminTestServer(t *testing.T) *Server {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.AdminSecret = "s3cret"
+	s, _ := newTestServerWithConfig(t, cfg, LoadFixtures(t))
+	return s
+}
+
+// serveAdmin sends a request with the admin secret through the admin
+// router.
+func serveAdmin(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	var request *http.Request
+	if body == "" {
+		request = httptest.NewRequest(method, target, nil)
+	} else {
+		request = httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set(adminSecretHeader, "s3cret")
+	response := httptest.NewRecorder()
+	s.adminRouter.ServeHTTP(response, request)
+	return response
+}
+
+func TestAdminSecret(t *testing.T) {
+	s := newAdminTestServer(t)
+	for _, secret := range []string{"", "guess", "s3cret "} {
+		request := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		if secret != "" {
+			request.Header.Set(adminSecretHeader, secret)
+		}
+		response := httptest.NewRecorder()
+		s.adminRouter.ServeHTTP(response, request)
+		assertStatus(t, response, http.StatusUnauthorized)
+	}
+
+	if s, _ := newTestServer(t); s.adminRouter != nil {
+		t.Error("the admin API is served without ADMIN_SECRET")
+	}
+}
+
+func TestAdminStats(t *testing.T) {
+	s := newAdminTestServer(t)
+	response := serveAdmin(s, http.MethodGet, "/admin/stats", "")
+
+	assertStatus(t, response, http.StatusOK)
+	var stats AdminStats
+	decodeJSON(t, response.Body.Bytes(), &stats)
+	if stats.Goroutines < 1 || stats.HeapAlloc == 0 || stats.Sys == 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestAdminTaskCount(t *testing.T) {
+	s := newAdminTestServer(t)
+	response := serveAdmin(s, http.MethodGet, "/admin/tasks/count", "")
+
+	assertStatus(t, response, http.StatusOK)
+	var count map[string]int
+	decodeJSON(t, response.Body.Bytes(), &count)
+	if want := len(fixtureTasks(t)); count["count"] != want {
+		t.Errorf("count = %v, want %d", count, want)
+	}
+}
+
+func TestAdminCacheFlush(t *testing.T) {
+	s := newAdminTestServer(t)
+	post := func(key, id string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/tasks",
+			strings.NewReader(`{"id":"`+id+`","description":"Задача `+id+`","note":"","applications":[]}`))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(idempotencyKeyHeader, key)
+		response := httptest.NewRecorder()
+		s.router.ServeHTTP(response, request)
+		return response
+	}
+	assertStatus(t, post("first", "a"), http.StatusCreated)
+	assertStatus(t, post("second", "b"), http.StatusCreated)
+	if replay := post("first", "a"); replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("the first request was not replayed")
+	}
+
+	response := serveAdmin(s, http.MethodPost, "/admin/cache/flush", "")
+	assertStatus(t, response, http.StatusOK)
+	var result AdminCacheFlushResult
+	decodeJSON(t, response.Body.Bytes(), &result)
+	if result.IdempotencyResponses != 2 {
+		t.Errorf("flushed %d responses, want 2", result.IdempotencyResponses)
+	}
+
+	// Once flushed, the retried request is handled again.
+	retried := post("first", "a")
+	assertStatus(t, retried, http.StatusConflict)
+	if retried.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("a flushed response was replayed")
+	}
+}
+
+func TestAdminStorageCompact(t *testing.T) {
+	s := newAdminTestServer(t)
+	assertStatus(t, serve(s, http.MethodDelete, "/tasks/1", ""), http.StatusOK)
+
+	response := serveAdmin(s, http.MethodPost, "/admin/storage/compact", "")
+	assertStatus(t, response, http.StatusOK)
+	var result AdminCompactResult
+	decodeJSON(t, response.Body.Bytes(), &result)
+	if want := len(fixtureTasks(t)) - 1; result.Tasks != want {
+		t.Errorf("tasks = %d, want %d", result.Tasks, want)
+	}
+}
+
+func TestAdminStorageCompactUnsupported(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminSecret = "s3cret"
+	s, _ := newTestServerWithConfig(t, cfg, &MockStorage{})
+	assertStatus(t, serveAdmin(s, http.MethodPost, "/admin/storage/compact", ""), http.StatusNotImplemented)
+}
+
+func TestInMemoryStorageCompact(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryStorage(fixtureTasks(t)...)
+	if err := storage.Delete(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+	generation, _, _ := storage.Snapshot(ctx)
+
+	count, err := storage.Compact(ctx)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if want := len(fixtureTasks(t)) - 1; count != want {
+		t.Errorf("Compact() = %d, want %d", count, want)
+	}
+	if after, _, _ := storage.Snapshot(ctx); after != generation {
+		t.Errorf("generation = %d after compacting, want %d", after, generation)
+	}
+	if _, changes, err := storage.Changes(ctx, 0); err != nil || len(changes) != 1 {
+		t.Errorf("Changes() = %v, %v, want the deletion", changes, err)
+	}
+
+	// The indexes are rebuilt from the remaining tasks.
+	if results, _, _ := storage.Search(ctx, "задание", 0); len(results) != 1 || results[0].Task.ID != "1" {
+		t.Errorf("Search() = %+v, want task 1", results)
+	}
+	if completions, _ := storage.Autocomplete(ctx, "прот", 10); len(completions) != 0 {
+		t.Errorf("Autocomplete() = %v for a deleted task", completions)
+	}
+	err = storage.CreateUnique(ctx, Task{ID: "3", Description: "сделать финальное ЗАДАНИЕ"})
+	if !errors.Is(err, ErrDuplicateDescription) {
+		t.Errorf("CreateUnique() error = %v, want ErrDuplicateDescription", err)
+	}
+}
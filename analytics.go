@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// analyticsBufferSize is how many requests RequestAnalytics remembers.
+// A summary covers at most that many of the latest requests, even if
+// its window reaches further back.
+const analyticsBufferSize = 10000
+
+// requestSample records one handled request.
+type requestSample struct {
+	route    string
+	status   int
+	duration time.Duration
+	at       time.Time
+	country  string
+}
+
+// RequestAnalytics keeps the latest requests in a ring buffer. Slots
+// are claimed with an atomic counter and replaced atomically, so
+// recording a request takes no lock.
+type RequestAnalytics struct {
+	next  atomic.Uint64
+	slots [analyticsBufferSize]atomic.Pointer[requestSample]
+}
+
+func (analytics *RequestAnalytics) record(sample *requestSample) {
+	slot := (analytics.next.Add(1) - 1) % analyticsBufferSize
+	analytics.slots[slot].Store(sample)
+}
+
+// EndpointSummary aggregates the requests to one route. Errors are
+// responses with a 5xx status. Countries counts the requests by the
+// country code geolocate resolved their client to, leaving out the
+// unresolved ones.
+type EndpointSummary struct {
+	Route     string         `json:"route"`
+	Count     int            `json:"count"`
+	ErrorRate float64        `json:"error_rate"`
+	P50Millis float64        `json:"p50_ms"`
+	P95Millis float64        `json:"p95_ms"`
+	P99Millis float64        `json:"p99_ms"`
+	Countries map[string]int `json:"countries,omitempty"`
+}
+
+// Summary aggregates, per route, the remembered requests made at or
+// after since, ordered by route.
+func (analytics *RequestAnalytics) Summary(since time.Time) []EndpointSummary {
+	type group struct {
+		durations []time.Duration
+		errors    int
+		countries map[string]int
+	}
+	groups := make(map[string]*group)
+	for i := range analytics.slots {
+		sample := analytics.slots[i].Load()
+		if sample == nil || sample.at.Before(since) {
+			continue
+		}
+		entry, ok := groups[sample.route]
+		if !ok {
+			entry = &group{}
+			groups[sample.route] = entry
+		}
+		entry.durations = append(entry.durations, sample.duration)
+		if sample.status >= 500 {
+			entry.errors++
+		}
+		if sample.country != "" {
+			if entry.countries == nil {
+				entry.countries = make(map[string]int)
+			}
+			entry.countries[sample.country]++
+		}
+	}
+
+	summaries := make([]EndpointSummary, 0, len(groups))
+	for route, entry := range groups {
+		sort.Slice(entry.durations, func(i, j int) bool { return entry.durations[i] < entry.durations[j] })
+		summaries = append(summaries, EndpointSummary{
+			Route:     route,
+			Count:     len(entry.durations),
+			ErrorRate: float64(entry.errors) / float64(len(entry.durations)),
+			P50Millis: percentileMillis(entry.durations, 0.50),
+			P95Millis: percentileMillis(entry.durations, 0.95),
+			P99Millis: percentileMillis(entry.durations, 0.99),
+			Countries: entry.countries,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Route < summaries[j].Route })
+	return summaries
+}
+
+// percentileMillis returns the nearest-rank percentile of sorted
+// durations in milliseconds.
+func percentileMillis(sorted []time.Duration, percentile float64) float64 {
+	rank := int(math.Ceil(percentile*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return float64(sorted[rank]) / float64(time.Millisecond)
+}
+
+// collectAnalytics records the route, status, latency and client
+// country of every request that matched a route, and logs them.
+func (s *Server) collectAnalytics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		started := s.clock.Now()
+		wrapped := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+		next.ServeHTTP(wrapped, request)
+
+		pattern := chi.RouteContext(request.Context()).RoutePattern()
+		if pattern == "" {
+			return
+		}
+		status := wrapped.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		now := s.clock.Now()
+		country := CountryCodeFromContext(request.Context())
+		s.analytics.record(&requestSample{
+			route:    request.Method + " " + pattern,
+			status:   status,
+			duration: now.Sub(started),
+			at:       now,
+			country:  country,
+		})
+		s.logger.Info("Запрос обработан", "method", request.Method, "route", pattern, "status", status, "duration", now.Sub(started), "country_code", country)
+	})
+}
+
+// getAdminAnalyticsSummary writes per-route call counts, error rates
+// and latency percentiles for the period given by the window query
+// parameter, one hour by default, in JSON format to the provided
+// http.ResponseWriter. A window that is not a positive duration is
+// rejected with a HTTP 400 Bad Request.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the optional window query parameter.
+func (s *Server) getAdminAnalyticsSummary(writer http.ResponseWriter, request *http.Request) {
+	window := time.Hour
+	if param := request.URL.Query().Get("window"); param != "" {
+		var err error
+		if window, err = time.ParseDuration(param); err != nil || window <= 0 {
+			s.logger.Warn("Некорректное окно аналитики", "window", param)
+			http.Error(writer, "window must be a positive duration such as 1h or 15m", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.writeJSON(writer, http.StatusOK, s.analytics.Summary(s.clock.Now().Add(-window)))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"container/heap"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// autocompleteLimit is how many descriptions autocompleteTasks returns.
+const autocompleteLimit = 5
+
+// foldText lowercases text and strips its diacritics, so that words
+// compare case- and accent-insensitively: "Ёлка" folds to "елка".
+func foldText(text string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+	if err != nil {
+		folded = text
+	}
+	return strings.ToLower(folded)
+}
+
+// trieNode is a node of a prefix trie over the folded words of task
+// descriptions. ids holds the tasks containing the word ending at the
+// node.
+type trieNode struct {
+	children map[rune]*trieNode
+	ids      map[string]struct{}
+}
+
+// prefixTrie indexes task descriptions by the prefixes of their words.
+// It is not safe for concurrent use; InMemoryStorage guards it with its
+// own lock.
+type prefixTrie struct {
+	root trieNode
+	// words holds the distinct words of every indexed task, so that
+	// removing a task does not need its text.
+	words map[string][]string
+}
+
+func newPrefixTrie() *prefixTrie {
+	return &prefixTrie{words: make(map[string][]string)}
+}
+
+// add indexes the description of the task, replacing an earlier
+// version of it.
+func (trie *prefixTrie) add(task Task) {
+	trie.remove(task.ID)
+
+	seen := make(map[string]bool)
+	for _, word := range tokenize(foldText(task.Description)) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		trie.words[task.ID] = append(trie.words[task.ID], word)
+
+		node := &trie.root
+		for _, r := range word {
+			if node.children == nil {
+				node.children = make(map[rune]*trieNode)
+			}
+			child, ok := node.children[r]
+			if !ok {
+				child = &trieNode{}
+				node.children[r] = child
+			}
+			node = child
+		}
+		if node.ids == nil {
+			node.ids = make(map[string]struct{})
+		}
+		node.ids[task.ID] = struct{}{}
+	}
+}
+
+func (trie *prefixTrie) remove(id string) {
+	for _, word := range trie.words[id] {
+		removeFromTrie(&trie.root, []rune(word), id)
+	}
+	delete(trie.words, id)
+}
+
+// removeFromTrie removes id from the node reached by word and prunes
+// the nodes left empty. It reports whether node itself is now empty.
+func removeFromTrie(node *trieNode, word []rune, id string) bool {
+	if len(word) == 0 {
+		delete(node.ids, id)
+	} else if child, ok := node.children[word[0]]; ok && removeFromTrie(child, word[1:], id) {
+		delete(node.children, word[0])
+	}
+	return len(node.ids) == 0 && len(node.children) == 0
+}
+
+// complete visits the IDs of the tasks with a word starting with
+// prefix, shorter words first and words of equal length in rune order,
+// until visit returns false.
+func (trie *prefixTrie) complete(prefix string, visit func(id string) bool) {
+	node := &trie.root
+	for _, r := range foldText(prefix) {
+		if node = node.children[r]; node == nil {
+			return
+		}
+	}
+
+	level := []*trieNode{node}
+	for len(level) > 0 {
+		var next []*trieNode
+		for _, node := range level {
+			// A common word has an ID for most tasks, of which visit
+			// usually wants a handful: pop them off a heap rather than
+			// sort them all.
+			ids := make(idHeap, 0, len(node.ids))
+			for id := range node.ids {
+				ids = append(ids, id)
+			}
+			heap.Init(&ids)
+			for ids.Len() > 0 {
+				if !visit(heap.Pop(&ids).(string)) {
+					return
+				}
+			}
+
+			keys := make([]rune, 0, len(node.children))
+			for r := range node.children {
+				keys = append(keys, r)
+			}
+			sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+			for _, r := range keys {
+				next = append(next, node.children[r])
+			}
+		}
+		level = next
+	}
+}
+
+// idHeap is a min-heap of task IDs.
+type idHeap []string
+
+func (ids idHeap) Len() int           { return len(ids) }
+func (ids idHeap) Less(i, j int) bool { return ids[i] < ids[j] }
+func (ids idHeap) Swap(i, j int)      { ids[i], ids[j] = ids[j], ids[i] }
+
+func (ids *idHeap) Push(id interface{}) { *ids = append(*ids, id.(string)) }
+
+func (ids *idHeap) Pop() interface{} {
+	old := *ids
+	id := old[len(old)-1]
+	*ids = old[:len(old)-1]
+	return id
+}
+
+// autocompleteTasks handles the HTTP request to complete the q query
+// parameter to task descriptions. It writes, as a JSON array, up to
+// five distinct descriptions containing a word that starts with q,
+// ignoring case and accents, preferring the shortest completions. A
+// missing q is rejected with a HTTP 400 Bad Request.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the q query parameter.
+func (s *Server) autocompleteTasks(writer http.ResponseWriter, request *http.Request) {
+	prefix := strings.TrimSpace(request.URL.Query().Get("q"))
+	if prefix == "" {
+		s.logger.Warn("Пустой префикс автодополнения")
+		http.Error(writer, "q must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	descriptions, err := s.store.Autocomplete(request.Context(), prefix, autocompleteLimit)
+	if err != nil {
+		s.logger.Error("Не удалось выполнить автодополнение", "q", prefix, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, descriptions)
+}
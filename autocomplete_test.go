@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestAutocompleteTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: "1", Description: "Сделать финальное задание", Applications: []string{}},
+		{ID: "2", Description: "Финал сезона", Applications: []string{}},
+		{ID: "3", Description: "Финал сезона", Applications: []string{}},
+		{ID: "4", Description: "Ёлка и café", Applications: []string{}},
+	}
+	for i := 0; i < 10; i++ {
+		tasks = append(tasks, Task{ID: "f" + strconv.Itoa(i), Description: "Файл " + strconv.Itoa(i), Applications: []string{}})
+	}
+	s, _ := newTestServer(t, tasks...)
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{name: "shortest word first, distinct", prefix: "финал", want: []string{"Финал сезона", "Сделать финальное задание"}},
+		{name: "case and accents", prefix: "ЕЛК", want: []string{"Ёлка и café"}},
+		{name: "accented prefix", prefix: "cafe", want: []string{"Ёлка и café"}},
+		{name: "limited to five", prefix: "файл", want: []string{"Файл 0", "Файл 1", "Файл 2", "Файл 3", "Файл 4"}},
+		{name: "no match", prefix: "отпуск", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := serve(s, http.MethodGet, "/tasks/autocomplete?q="+url.QueryEscape(tt.prefix), "")
+			assertStatus(t, response, http.StatusOK)
+			var got []string
+			decodeJSON(t, response.Body.Bytes(), &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("autocomplete(%q) = %q, want %q", tt.prefix, got, tt.want)
+			}
+		})
+	}
+
+	assertStatus(t, serve(s, http.MethodGet, "/tasks/autocomplete?q=+", ""), http.StatusBadRequest)
+}
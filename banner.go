@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// applicationName is shown in the startup banner.
+const applicationName = "go-rest-api-homework"
+
+// printBanner writes a summary of the build and of the configuration
+// to output. The commit is left out when it is unknown.
+func printBanner(output io.Writer, cfg Config, info BuildInfo) {
+	fmt.Fprintf(output, "%s %s\n", applicationName, info.Version)
+	if info.Commit != "" {
+		fmt.Fprintf(output, "  commit:   %s\n", info.Commit)
+	}
+	fmt.Fprintf(output, "  go:       %s\n", info.GoVersion)
+	fmt.Fprintf(output, "  address:  %s\n", cfg.Addr)
+	if cfg.AdminSecret != "" {
+		fmt.Fprintf(output, "  admin:    %s\n", cfg.AdminAddr)
+	}
+
+	features := enabledFeatures(cfg)
+	if len(features) == 0 {
+		features = []string{"none"}
+	}
+	fmt.Fprintf(output, "  features: %s\n", strings.Join(features, ", "))
+}
+
+// enabledFeatures lists the optional features cfg turns on.
+func enabledFeatures(cfg Config) []string {
+	var features []string
+	add := func(enabled bool, name string) {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+
+	add(cfg.TaskQueueSize > 0 && cfg.FeatureFlags.EnableTaskQueue, "task queue")
+	add(cfg.FeatureFlags.EnableHTMLSanitization, "HTML sanitization")
+	add(cfg.GeoIPDB != "" && cfg.FeatureFlags.EnableGeoIP, "GeoIP")
+	add(cfg.BlocklistFile != "", "blocklist")
+	add(cfg.CSRFProtection, "CSRF protection")
+	add(cfg.WrapResponses, "response envelope")
+	add(cfg.SlackSigningSecret != "", "Slack command")
+	add(cfg.Debug, "debug")
+	return features
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchTaskCount is the number of tasks the benchmarks seed.
+const benchTaskCount = 10000
+
+// newBenchServer creates a server over count seeded tasks. Run the
+// benchmarks with -benchmem to see the allocations per request.
+func newBenchServer(b *testing.B, count int) *Server {
+	b.Helper()
+	tasks := make([]Task, count)
+	for i := range tasks {
+		id := strconv.Itoa(i)
+		tasks[i] = Task{
+			ID:           id,
+			Description:  "Задача номер " + id,
+			Note:         "Заметка к задаче " + id,
+			Applications: []string{"VS Code", "git"},
+			CreatedAt:    testNow,
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewServer(DefaultConfig(), NewInMemoryStorage(tasks...)).WithLogger(logger).WithClock(NewFakeClock(testNow))
+}
+
+func benchServe(b *testing.B, s *Server, method, target, body string, want int) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, target, reader)
+	response := httptest.NewRecorder()
+	s.router.ServeHTTP(response, request)
+	if response.Code != want {
+		b.Fatalf("%s %s: status = %d, want %d", method, target, response.Code, want)
+	}
+}
+
+func BenchmarkGetTasks(b *testing.B) {
+	s := newBenchServer(b, benchTaskCount)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchServe(b, s, http.MethodGet, "/tasks", "", http.StatusOK)
+	}
+}
+
+func BenchmarkGetTasksSorted(b *testing.B) {
+	s := newBenchServer(b, benchTaskCount)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchServe(b, s, http.MethodGet, "/tasks?sort=-created_at,description", "", http.StatusOK)
+	}
+}
+
+func BenchmarkGetTask(b *testing.B) {
+	s := newBenchServer(b, benchTaskCount)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchServe(b, s, http.MethodGet, "/tasks/"+strconv.Itoa(i%benchTaskCount), "", http.StatusOK)
+	}
+}
+
+func BenchmarkPostTask(b *testing.B) {
+	s := newBenchServer(b, benchTaskCount)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := "new-" + strconv.Itoa(i)
+		body := `{"id":"` + id + `","description":"Новая задача ` + id + `","note":"","applications":[]}`
+		benchServe(b, s, http.MethodPost, "/tasks", body, http.StatusCreated)
+	}
+}
+
+func BenchmarkDeleteTask(b *testing.B) {
+	s := newBenchServer(b, benchTaskCount+b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchServe(b, s, http.MethodDelete, "/tasks/"+strconv.Itoa(i), "", http.StatusOK)
+	}
+}
+
+// autocompleteBenchTaskCount is the number of tasks
+// BenchmarkAutocomplete seeds: GET /tasks/autocomplete must answer
+// within 50ms over that many.
+const autocompleteBenchTaskCount = 100000
+
+func BenchmarkAutocomplete(b *testing.B) {
+	s := newBenchServer(b, autocompleteBenchTaskCount)
+	for _, bm := range []struct {
+		name, prefix string
+	}{
+		// Every task has the word "задача".
+		{name: "common word", prefix: "зад"},
+		{name: "rare word", prefix: "99999"},
+		{name: "no match", prefix: "отпуск"},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			target := "/tasks/autocomplete?q=" + url.QueryEscape(bm.prefix)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				benchServe(b, s, http.MethodGet, target, "", http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// BlocklistStore decides which client IPs are refused service.
+type BlocklistStore interface {
+	IsBlocked(ip string) bool
+}
+
+// FileBlocklist is a BlocklistStore holding the IPs and CIDR ranges,
+// such as 203.0.113.0/24, listed in a file, one per line, plus the IPs
+// added at runtime with Add. Blank lines and lines starting with # are
+// ignored. It is safe for concurrent use.
+type FileBlocklist struct {
+	path string
+
+	mu       sync.RWMutex
+	fromFile map[string]struct{}
+	networks []*net.IPNet
+	added    map[string]struct{}
+}
+
+// NewFileBlocklist creates an empty blocklist backed by the file at
+// path; call Load to read it. An empty path creates a blocklist
+// holding only the IPs added at runtime.
+func NewFileBlocklist(path string) *FileBlocklist {
+	return &FileBlocklist{
+		path:     path,
+		fromFile: make(map[string]struct{}),
+		added:    make(map[string]struct{}),
+	}
+}
+
+// IsBlocked reports whether ip is listed in the file, is in a range
+// listed there, or was added at runtime.
+func (blocklist *FileBlocklist) IsBlocked(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed != nil {
+		ip = parsed.String()
+	}
+
+	blocklist.mu.RLock()
+	defer blocklist.mu.RUnlock()
+
+	_, inFile := blocklist.fromFile[ip]
+	_, added := blocklist.added[ip]
+	if inFile || added {
+		return true
+	}
+	for _, network := range blocklist.networks {
+		if parsed != nil && network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add blocks ip until the process exits. Runtime additions survive
+// reloads of the file.
+func (blocklist *FileBlocklist) Add(ip string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	blocklist.mu.Lock()
+	defer blocklist.mu.Unlock()
+
+	blocklist.added[parsed.String()] = struct{}{}
+	return nil
+}
+
+// Load replaces the IPs and ranges read from the file with its current
+// contents. If the file is malformed, the previous ones are kept.
+// It does nothing for a blocklist without a file.
+func (blocklist *FileBlocklist) Load() error {
+	if blocklist.path == "" {
+		return nil
+	}
+
+	file, err := os.Open(blocklist.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fromFile := make(map[string]struct{})
+	var networks []*net.IPNet
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return fmt.Errorf("%s:%d: invalid CIDR range %q", blocklist.path, line, entry)
+			}
+			networks = append(networks, network)
+			continue
+		}
+		parsed := net.ParseIP(entry)
+		if parsed == nil {
+			return fmt.Errorf("%s:%d: invalid IP address %q", blocklist.path, line, entry)
+		}
+		fromFile[parsed.String()] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	blocklist.mu.Lock()
+	blocklist.fromFile = fromFile
+	blocklist.networks = networks
+	blocklist.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the file whenever it changes until ctx is cancelled.
+// The directory is watched rather than the file itself, so that the
+// blocklist keeps following the file when an editor replaces it. A
+// file that fails to load keeps the previous contents in effect.
+func (blocklist *FileBlocklist) Watch(ctx context.Context, logger *slog.Logger) error {
+	if blocklist.path == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watcher.Add(filepath.Dir(blocklist.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				if filepath.Clean(event.Name) != filepath.Clean(blocklist.path) || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				if err := blocklist.Load(); err != nil {
+					logger.Error("Не удалось перечитать список блокировок", "path", blocklist.path, "error", err)
+					continue
+				}
+				logger.Info("Список блокировок перечитан", "path", blocklist.path)
+			case err := <-watcher.Errors:
+				logger.Error("Ошибка наблюдения за списком блокировок", "path", blocklist.path, "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// clientIP returns the IP address the request was received from.
+func clientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// BlocklistMiddleware refuses requests from blocked IPs with 403 Forbidden.
+func BlocklistMiddleware(store BlocklistStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if store.IsBlocked(clientIP(request)) {
+				localizedError(writer, request, MsgIPBlocked, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// postBlocklist adds the IP given in the request body, as
+// {"ip":"203.0.113.7"}, to the blocklist. Upon success it responds
+// with a HTTP 201 Created status. If the body cannot be read or does
+// not hold a valid IP address, it responds with a HTTP 400 Bad Request
+// along with the error message.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to send the response to the client.
+//   - request: The http.Request object that contains the IP address in the body.
+func (s *Server) postBlocklist(writer http.ResponseWriter, request *http.Request) {
+	var entry struct {
+		IP string `json:"ip"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&entry); err != nil {
+		s.logger.Warn("Некорректное тело запроса блокировки", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.blocklist.Add(entry.IP); err != nil {
+		s.logger.Warn("Некорректный IP для блокировки", "ip", entry.IP, "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	s.logger.Info("IP добавлен в список блокировок", "ip", entry.IP)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBlocklist replaces the blocklist file at path with one holding
+// contents, as editors do, so that a watcher never reads it half
+// written.
+func writeBlocklist(t *testing.T, path, contents string) {
+	t.Helper()
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(temporary, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventually fails the test unless condition holds within 5 seconds.
+func eventually(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+	}
+}
+
+func TestFileBlocklistLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "# Сканеры\n203.0.113.7\n\n  2001:db8::0:1  \n198.51.100.0/24\n")
+	blocklist := NewFileBlocklist(path)
+	if err := blocklist.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "203.0.113.7", want: true},
+		{ip: "203.0.113.8", want: false},
+		{ip: "2001:db8::1", want: true},
+		{ip: "198.51.100.0", want: true},
+		{ip: "198.51.100.255", want: true},
+		{ip: "198.51.101.1", want: false},
+		{ip: "not an IP", want: false},
+	}
+	for _, tt := range tests {
+		if got := blocklist.IsBlocked(tt.ip); got != tt.want {
+			t.Errorf("IsBlocked(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	// A malformed file keeps the previous list in effect.
+	for _, contents := range []string{"192.0.2.1\nnot-an-ip\n", "192.0.2.0/33\n"} {
+		writeBlocklist(t, path, contents)
+		if err := blocklist.Load(); err == nil {
+			t.Errorf("Load(%q) succeeded, want an error", contents)
+		}
+		if !blocklist.IsBlocked("203.0.113.7") || !blocklist.IsBlocked("198.51.100.1") || blocklist.IsBlocked("192.0.2.1") {
+			t.Errorf("after Load(%q) the previous list is not in effect", contents)
+		}
+	}
+
+	if err := NewFileBlocklist(filepath.Join(t.TempDir(), "missing.txt")).Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() of a missing file error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestFileBlocklistWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "203.0.113.7\n")
+	blocklist := NewFileBlocklist(path)
+	if err := blocklist.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocklist.Add("192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := blocklist.Watch(ctx, slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatal(err)
+	}
+
+	writeBlocklist(t, path, "198.51.100.0/24\n")
+	eventually(t, func() bool { return blocklist.IsBlocked("198.51.100.20") }, "the new range never took effect")
+	if blocklist.IsBlocked("203.0.113.7") {
+		t.Error("203.0.113.7 is still blocked once removed from the file")
+	}
+	if !blocklist.IsBlocked("192.0.2.1") {
+		t.Error("the IP added at runtime was lost on reload")
+	}
+
+	// A malformed rewrite keeps the range; a valid one is taken again.
+	writeBlocklist(t, path, "198.51.101.0/24\nnot-an-ip\n")
+	time.Sleep(100 * time.Millisecond)
+	if !blocklist.IsBlocked("198.51.100.20") || blocklist.IsBlocked("198.51.101.1") {
+		t.Error("a malformed file replaced the previous list")
+	}
+	writeBlocklist(t, path, "198.51.101.0/24\n")
+	eventually(t, func() bool { return blocklist.IsBlocked("198.51.101.1") }, "the file was not reloaded after being fixed")
+}
+
+func TestPostBlocklist(t *testing.T) {
+	s := newAdminTestServer(t)
+	fromClient := func() *http.Request {
+		request := newRequest(http.MethodGet, "/tasks", "")
+		request.RemoteAddr = "192.0.2.1:54321"
+		return request
+	}
+	assertStatus(t, serveRequest(s, fromClient()), http.StatusOK)
+
+	assertStatus(t, serveAdmin(s, http.MethodPost, "/admin/blocklist", `{"ip":"192.0.2.1"}`), http.StatusCreated)
+	response := serveRequest(s, fromClient())
+	assertStatus(t, response, http.StatusForbidden)
+	if got, want := response.Body.String(), "Access from this IP address is blocked\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid IP", body: `{"ip":"192.0.2"}`, wantStatus: http.StatusBadRequest},
+		{name: "no IP", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", body: `{"ip":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, serveAdmin(s, http.MethodPost, "/admin/blocklist", tt.body), tt.wantStatus)
+		})
+	}
+}
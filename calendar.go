@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// calendarMonthLayout is the layout of the month query parameter of
+// GET /tasks/calendar.
+const calendarMonthLayout = "2006-01"
+
+// getTasksCalendar handles the HTTP request to retrieve the tasks due
+// in the month given by the month query parameter, as 2024-01, grouped
+// by the UTC day of their SLA deadline. The response maps 2024-01-15
+// style days to the tasks due that day, highest computed_priority first
+// and, among equal priorities, earliest deadline first; tasks without a
+// deadline are left out. Days without tasks are only listed,
+// with an empty array, when include_empty=true. A missing or malformed
+// month or include_empty is rejected with a HTTP 400 Bad Request. In
+// case of a storage error, it responds with the status chosen by
+// storageErrorStatus along with the error message.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the month and include_empty query parameters.
+func (s *Server) getTasksCalendar(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	month, err := time.Parse(calendarMonthLayout, query.Get("month"))
+	if err != nil {
+		s.logger.Warn("Некорректный месяц календаря", "month", query.Get("month"), "error", err)
+		http.Error(writer, "month must be a month like 2024-01", http.StatusBadRequest)
+		return
+	}
+	includeEmpty := false
+	if value := query.Get("include_empty"); value != "" {
+		if includeEmpty, err = strconv.ParseBool(value); err != nil {
+			s.logger.Warn("Некорректный параметр include_empty", "include_empty", value, "error", err)
+			http.Error(writer, "include_empty must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tasks, err := s.store.GetAll(request.Context(), nil)
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи для календаря", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	end := month.AddDate(0, 1, 0)
+	days := make(map[string][]Task)
+	if includeEmpty {
+		for day := month; day.Before(end); day = day.AddDate(0, 0, 1) {
+			days[day.Format(time.DateOnly)] = []Task{}
+		}
+	}
+	for _, task := range tasks {
+		deadline, ok := slaDeadline(task)
+		if !ok || deadline.Before(month) || !deadline.Before(end) {
+			continue
+		}
+		s.presentTask(&task)
+		day := deadline.UTC().Format(time.DateOnly)
+		days[day] = append(days[day], task)
+	}
+	for _, dayTasks := range days {
+		sort.Slice(dayTasks, func(i, j int) bool {
+			if dayTasks[i].ComputedPriority != dayTasks[j].ComputedPriority {
+				return dayTasks[i].ComputedPriority > dayTasks[j].ComputedPriority
+			}
+			first, _ := slaDeadline(dayTasks[i])
+			second, _ := slaDeadline(dayTasks[j])
+			if !first.Equal(second) {
+				return first.Before(second)
+			}
+			return dayTasks[i].ID < dayTasks[j].ID
+		})
+	}
+
+	s.writeJSON(writer, http.StatusOK, days)
+}
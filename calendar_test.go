@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetTasksCalendar(t *testing.T) {
+	s, _ := newTestServer(t,
+		// Due in an hour, with a priority of 0.504.
+		Task{ID: "b", Description: "Позвонить", CreatedAt: testNow.Add(-time.Hour), SLADeadlineMinutes: 120},
+		// Due an hour later with the same priority.
+		Task{ID: "a", Description: "Написать", CreatedAt: testNow.Add(-2 * time.Hour), SLADeadlineMinutes: 242},
+		// Due in two hours, with a priority of 0.683.
+		Task{ID: "c", Description: "Отправить", CreatedAt: testNow.Add(-4 * time.Hour), SLADeadlineMinutes: 360},
+		Task{ID: "next-month", Description: "Потом", CreatedAt: testNow.AddDate(0, 1, 0), SLADeadlineMinutes: 60},
+		Task{ID: "no-deadline", Description: "Когда-нибудь", CreatedAt: testNow},
+	)
+
+	response := serve(s, http.MethodGet, "/tasks/calendar?month=2024-01", "")
+	assertStatus(t, response, http.StatusOK)
+	var days map[string][]Task
+	decodeJSON(t, response.Body.Bytes(), &days)
+	if len(days) != 1 {
+		t.Fatalf("days = %v, want only 2024-01-15", days)
+	}
+	if got, want := taskIDs(days["2024-01-15"]), []string{"c", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks of 2024-01-15 = %q, want %q", got, want)
+	}
+
+	response = serve(s, http.MethodGet, "/tasks/calendar?month=2024-01&include_empty=true", "")
+	assertStatus(t, response, http.StatusOK)
+	days = nil
+	decodeJSON(t, response.Body.Bytes(), &days)
+	if len(days) != 31 || days["2024-01-01"] == nil || len(days["2024-01-01"]) != 0 {
+		t.Errorf("%d days, 2024-01-01 = %v, want 31 days with empty ones listed", len(days), days["2024-01-01"])
+	}
+}
+
+func TestGetTasksCalendarRejected(t *testing.T) {
+	for _, target := range []string{"/tasks/calendar", "/tasks/calendar?month=2024-13", "/tasks/calendar?month=2024-01&include_empty=sometimes"} {
+		s, _ := newTestServer(t)
+		assertStatus(t, serve(s, http.MethodGet, target, ""), http.StatusBadRequest)
+	}
+}
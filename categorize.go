@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+)
+
+// Categorizer suggests tags for a task from its description.
+type Categorizer interface {
+	Categorize(ctx context.Context, description string) []string
+}
+
+// KeywordCategorizer suggests the tag of every keyword the description
+// contains as whole words, ignoring case and accents. A keyword of
+// several words matches them in sequence.
+type KeywordCategorizer struct {
+	keywords []categoryKeyword
+}
+
+type categoryKeyword struct {
+	words []string
+	tag   string
+}
+
+// NewKeywordCategorizer creates a KeywordCategorizer from a keyword to
+// tag mapping. Keywords without words are ignored.
+func NewKeywordCategorizer(tags map[string]string) *KeywordCategorizer {
+	categorizer := &KeywordCategorizer{}
+	for keyword, tag := range tags {
+		if words := tokenize(foldText(keyword)); len(words) > 0 && tag != "" {
+			categorizer.keywords = append(categorizer.keywords, categoryKeyword{words: words, tag: tag})
+		}
+	}
+	return categorizer
+}
+
+// LoadCategoryKeywords reads the keyword to tag mapping of a
+// KeywordCategorizer from a JSON object file, such as
+// {"report": "work", "buy milk": "shopping"}. An empty path means no
+// keywords.
+func LoadCategoryKeywords(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tags map[string]string
+	if err = json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tags, nil
+}
+
+// Categorize returns the suggested tags, sorted and without
+// duplicates, or nil if no keyword matches.
+func (categorizer *KeywordCategorizer) Categorize(_ context.Context, description string) []string {
+	words := tokenize(foldText(description))
+	matched := make(map[string]bool)
+	for _, keyword := range categorizer.keywords {
+		if !matched[keyword.tag] && containsWords(words, keyword.words) {
+			matched[keyword.tag] = true
+		}
+	}
+
+	var tags []string
+	for tag := range matched {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// mergeTags returns the tags of both lists, sorted and without
+// duplicates, or nil if there are none.
+func mergeTags(first, second []string) []string {
+	var tags []string
+	for _, tag := range append(append([]string{}, first...), second...) {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// containsWords reports whether sequence occurs in words.
+func containsWords(words, sequence []string) bool {
+	for start := 0; start+len(sequence) <= len(words); start++ {
+		if slices.Equal(words[start:start+len(sequence)], sequence) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKeywordCategorizer(t *testing.T) {
+	categorizer := NewKeywordCategorizer(map[string]string{
+		"отчёт":         "работа",
+		"совещание":     "работа",
+		"купить молоко": "покупки",
+		"café":          "еда",
+		"":              "пусто",
+		"без тега":      "",
+	})
+
+	tests := []struct {
+		name        string
+		description string
+		want        []string
+	}{
+		{name: "keyword", description: "Подготовить отчёт", want: []string{"работа"}},
+		{name: "any case", description: "ОТЧЁТ за квартал", want: []string{"работа"}},
+		{name: "punctuation around the keyword", description: "Отчёт, срочно!", want: []string{"работа"}},
+		{name: "accents ignored", description: "Встреча в cafe", want: []string{"еда"}},
+		{name: "only whole words", description: "Отчёты за год", want: nil},
+		{name: "words in sequence", description: "Купить молоко и хлеб", want: []string{"покупки"}},
+		{name: "words out of sequence", description: "Молоко купить", want: nil},
+		{name: "words apart", description: "Купить свежее молоко", want: nil},
+		{name: "tag once, tags sorted", description: "Купить молоко к совещанию, отчёт и совещание", want: []string{"покупки", "работа"}},
+		{name: "keyword without a tag", description: "Задача без тега", want: nil},
+		{name: "no match", description: "Погулять", want: nil},
+		{name: "empty description", description: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categorizer.Categorize(context.Background(), tt.description); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Categorize(%q) = %q, want %q", tt.description, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCategoryKeywords(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tags, err := LoadCategoryKeywords(write("categories.json", `{"report": "work", "buy milk": "shopping"}`))
+	if want := map[string]string{"report": "work", "buy milk": "shopping"}; err != nil || !reflect.DeepEqual(tags, want) {
+		t.Errorf("LoadCategoryKeywords() = %v, %v, want %v", tags, err, want)
+	}
+	if tags, err := LoadCategoryKeywords(""); err != nil || tags != nil {
+		t.Errorf("LoadCategoryKeywords(\"\") = %v, %v, want no keywords", tags, err)
+	}
+	for _, path := range []string{write("list.json", `["report"]`), filepath.Join(dir, "missing.json")} {
+		if _, err := LoadCategoryKeywords(path); err == nil {
+			t.Errorf("LoadCategoryKeywords(%s) succeeded", path)
+		}
+	}
+}
+
+func TestPostTaskSuggestsTags(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CategoryKeywords = map[string]string{"отчёт": "работа"}
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage())
+
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", `{"id":"1","description":"Сдать отчёт","note":"","applications":[]}`), http.StatusCreated)
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", `{"id":"2","description":"Погулять","note":"","applications":[]}`), http.StatusCreated)
+
+	for id, want := range map[string][]string{"1": {"работа"}, "2": nil} {
+		response := serve(s, http.MethodGet, "/tasks/"+id, "")
+		assertStatus(t, response, http.StatusOK)
+		var task Task
+		decodeJSON(t, response.Body.Bytes(), &task)
+		if !reflect.DeepEqual(task.SuggestedTags, want) {
+			t.Errorf("suggested tags of task %s = %q, want %q", id, task.SuggestedTags, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeType classifies a change to the API.
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeChanged ChangeType = "changed"
+	ChangeRemoved ChangeType = "removed"
+)
+
+// Change is a single change to the API.
+type Change struct {
+	Type        ChangeType `yaml:"type" json:"type"`
+	Description string     `yaml:"description" json:"description"`
+}
+
+// ChangelogEntry lists the changes made in a version of the API.
+type ChangelogEntry struct {
+	Version string   `yaml:"version" json:"version"`
+	Date    string   `yaml:"date" json:"date"`
+	Changes []Change `yaml:"changes" json:"changes"`
+}
+
+//go:embed changelog.yaml
+var changelogYAML []byte
+
+// changelog holds the entries of changelog.yaml, newest version first.
+var changelog = mustParseChangelog(changelogYAML)
+
+// mustParseChangelog parses the changelog and panics if it is
+// malformed, so that a broken changelog never makes it past startup.
+func mustParseChangelog(data []byte) []ChangelogEntry {
+	var entries []ChangelogEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		panic(fmt.Sprintf("changelog: %v", err))
+	}
+	if len(entries) == 0 {
+		panic("changelog: no entries")
+	}
+	for _, entry := range entries {
+		for _, change := range entry.Changes {
+			switch change.Type {
+			case ChangeAdded, ChangeChanged, ChangeRemoved:
+			default:
+				panic(fmt.Sprintf("changelog: version %s: unknown change type %q", entry.Version, change.Type))
+			}
+		}
+	}
+	return entries
+}
+
+// getChangelog writes every version of the API with its changes,
+// newest first, in JSON format to the provided http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - req: The http.Request received from the client. This parameter is ignored in this function.
+func (s *Server) getChangelog(writer http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(writer, http.StatusOK, changelog)
+}
+
+// getLatestChangelog writes the newest version of the API with its
+// changes in JSON format to the provided http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - req: The http.Request received from the client. This parameter is ignored in this function.
+func (s *Server) getLatestChangelog(writer http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(writer, http.StatusOK, changelog[0])
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// versionNumbers parses a version such as 1.2.3.
+func versionNumbers(t *testing.T, version string) [3]int {
+	t.Helper()
+	var numbers [3]int
+	if _, err := fmt.Sscanf(version, "%d.%d.%d", &numbers[0], &numbers[1], &numbers[2]); err != nil {
+		t.Fatalf("version %q: %v", version, err)
+	}
+	return numbers
+}
+
+// versionLess reports whether version a is older than b.
+func versionLess(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func TestGetChangelog(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	response := serve(s, http.MethodGet, "/changelog", "")
+	assertStatus(t, response, http.StatusOK)
+	var entries []ChangelogEntry
+	decodeJSON(t, response.Body.Bytes(), &entries)
+	if !reflect.DeepEqual(entries, changelog) {
+		t.Fatalf("changelog = %+v, want the embedded entries %+v", entries, changelog)
+	}
+	for i, entry := range entries {
+		if entry.Date == "" || len(entry.Changes) == 0 {
+			t.Errorf("version %s has no date or no changes", entry.Version)
+		}
+		if i > 0 && !versionLess(versionNumbers(t, entry.Version), versionNumbers(t, entries[i-1].Version)) {
+			t.Errorf("version %s is listed after %s, want the newest first", entry.Version, entries[i-1].Version)
+		}
+	}
+
+	response = serve(s, http.MethodGet, "/changelog/latest", "")
+	assertStatus(t, response, http.StatusOK)
+	var latest ChangelogEntry
+	decodeJSON(t, response.Body.Bytes(), &latest)
+	if !reflect.DeepEqual(latest, entries[0]) {
+		t.Errorf("latest = %+v, want %+v", latest, entries[0])
+	}
+}
+
+func TestMustParseChangelog(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{name: "malformed", yaml: "- version: [\n"},
+		{name: "empty", yaml: ""},
+		{name: "unknown change type", yaml: "- version: \"1.0.0\"\n  changes:\n    - type: fixed\n      description: x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("mustParseChangelog() did not panic")
+				}
+			}()
+			mustParseChangelog([]byte(tt.yaml))
+		})
+	}
+}
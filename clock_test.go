@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFakeClockAdvance(t *testing.T) {
+	clock := NewFakeClock(testNow)
+	if got := clock.Now(); !got.Equal(testNow) {
+		t.Fatalf("Now() = %v, want %v", got, testNow)
+	}
+
+	clock.Advance(90 * time.Minute)
+	if want := testNow.Add(90 * time.Minute); !clock.Now().Equal(want) {
+		t.Errorf("Now() after Advance = %v, want %v", clock.Now(), want)
+	}
+}
+
+func TestFakeClockConcurrentUse(t *testing.T) {
+	clock := NewFakeClock(testNow)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			clock.Advance(time.Second)
+		}()
+		go func() {
+			defer wg.Done()
+			clock.Now()
+		}()
+	}
+	wg.Wait()
+
+	if want := testNow.Add(100 * time.Second); !clock.Now().Equal(want) {
+		t.Errorf("Now() = %v, want %v", clock.Now(), want)
+	}
+}
+
+func TestServerStampsTasksWithItsClock(t *testing.T) {
+	s, clock := newTestServer(t)
+	clock.Advance(time.Hour)
+
+	response := serve(s, http.MethodPost, "/tasks", `{"id":"1","description":"one","note":"","applications":[]}`)
+	assertStatus(t, response, http.StatusCreated)
+
+	task, err := s.store.Get(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := testNow.Add(time.Hour); !task.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", task.CreatedAt, want)
+	}
+}
+
+func TestRealClockMovesForward(t *testing.T) {
+	before := time.Now()
+	got := RealClock{}.Now()
+	if got.Before(before) {
+		t.Errorf("RealClock.Now() = %v, before %v", got, before)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the server settings.
+type Config struct {
+	// Addr is the TCP address the server listens on.
+	Addr string
+	// TaskQueueSize is the capacity of the queue newly created tasks
+	// are put on. Zero disables the queue.
+	TaskQueueSize int
+	// ExpirationInterval is how often tasks with an elapsed TTL are deleted.
+	ExpirationInterval time.Duration
+	// LogLevel and LogFormat configure the logger, see newLogger.
+	// ConfigFromEnv lower-cases LogFormat, so that it can be compared
+	// with "json" and "text" directly.
+	LogLevel  string
+	LogFormat string
+	// Debug enables checks that are too expensive for production,
+	// such as validating every response against its schema.
+	Debug bool
+	// BlocklistFile lists the client IPs and CIDR ranges to refuse, one
+	// per line. It is reloaded whenever it changes. Empty means no file.
+	BlocklistFile string
+	// CSRFProtection requires browser clients to echo the csrf_token
+	// cookie in the X-CSRF-Token header of unsafe requests. It is off
+	// by default because it would reject every non-browser client.
+	CSRFProtection bool
+	// GeoIPDB is the path of a MaxMind GeoLite2 Country or City
+	// database. When set, requests are tagged with the client's
+	// country code. Empty disables the lookup.
+	GeoIPDB string
+	// AdminSecret must be sent in the X-Admin-Secret header of every
+	// admin API request. Empty disables the admin API.
+	AdminSecret string
+	// AdminAddr is the TCP address the admin API listens on, kept
+	// apart from Addr so that it can be firewalled separately.
+	AdminAddr string
+	// FeatureFlags are the flags in effect at startup. They are
+	// reloaded from the environment and FeatureFlagsFile on SIGHUP.
+	FeatureFlags     FeatureFlags
+	FeatureFlagsFile string
+	// WrapResponses wraps successful JSON responses in an Envelope
+	// with the request ID and a timestamp. Off, they are bare JSON.
+	WrapResponses bool
+	// JobWorkers is how many background jobs, such as imports, run
+	// at once.
+	JobWorkers int
+	// SyncCursorSecret signs the cursors of GET /tasks/sync. Empty
+	// means a random secret, so cursors do not survive a restart.
+	SyncCursorSecret string
+	// CategoryKeywords maps keywords to the tags suggested for tasks
+	// whose description contains them, see KeywordCategorizer. They
+	// are loaded from CategoriesFile.
+	CategoryKeywords map[string]string
+	CategoriesFile   string
+	// SlackSigningSecret verifies the requests of the Slack slash
+	// command at /integrations/slack/command. Empty disables it.
+	SlackSigningSecret string
+	// Experiments are the A/B experiments requests are assigned to,
+	// see Experiments.
+	Experiments []Experiment
+	// StorageRetryAttempts is how many times a storage operation
+	// failing with a retryable error is tried, see RetryStorage, and
+	// StorageRetryBackoff the wait before the first retry. One, the
+	// default, means no retries.
+	StorageRetryAttempts int
+	StorageRetryBackoff  time.Duration
+}
+
+// DefaultConfig returns the settings used when nothing is overridden.
+func DefaultConfig() Config {
+	return Config{
+		Addr:                 ":8080",
+		AdminAddr:            ":8081",
+		FeatureFlags:         DefaultFeatureFlags(),
+		ExpirationInterval:   time.Minute,
+		JobWorkers:           2,
+		StorageRetryAttempts: 1,
+		StorageRetryBackoff:  50 * time.Millisecond,
+	}
+}
+
+// withDefaults returns cfg with the settings the server cannot run
+// without, the addresses, the expiration interval and the number of
+// job workers, taken from DefaultConfig where they are zero or
+// negative.
+func (cfg Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	if cfg.Addr == "" {
+		cfg.Addr = defaults.Addr
+	}
+	if cfg.AdminAddr == "" {
+		cfg.AdminAddr = defaults.AdminAddr
+	}
+	if cfg.ExpirationInterval <= 0 {
+		cfg.ExpirationInterval = defaults.ExpirationInterval
+	}
+	if cfg.JobWorkers < 1 {
+		cfg.JobWorkers = defaults.JobWorkers
+	}
+	return cfg
+}
+
+// ConfigFromEnv returns DefaultConfig overridden by the ADDR,
+// TASK_QUEUE_SIZE, LOG_LEVEL, LOG_FORMAT, DEBUG, BLOCKLIST_FILE,
+// CSRF_PROTECTION, GEOIP_DB, ADMIN_SECRET, ADMIN_ADDR,
+// FEATURE_FLAGS_FILE, WRAP_RESPONSES, JOB_WORKERS, SYNC_CURSOR_SECRET,
+// CATEGORIES_FILE, SLACK_SIGNING_SECRET, EXPERIMENTS,
+// STORAGE_RETRY_ATTEMPTS and STORAGE_RETRY_BACKOFF environment
+// variables, the feature flags loaded by LoadFeatureFlags and the
+// keywords loaded by LoadCategoryKeywords.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	if value := os.Getenv("ADDR"); value != "" {
+		cfg.Addr = value
+	}
+
+	if value := os.Getenv("TASK_QUEUE_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid TASK_QUEUE_SIZE: %w", err)
+		}
+		cfg.TaskQueueSize = size
+	}
+
+	cfg.LogLevel = os.Getenv("LOG_LEVEL")
+	cfg.LogFormat = strings.ToLower(os.Getenv("LOG_FORMAT"))
+	cfg.BlocklistFile = os.Getenv("BLOCKLIST_FILE")
+	cfg.GeoIPDB = os.Getenv("GEOIP_DB")
+	cfg.AdminSecret = os.Getenv("ADMIN_SECRET")
+	cfg.SyncCursorSecret = os.Getenv("SYNC_CURSOR_SECRET")
+	cfg.SlackSigningSecret = os.Getenv("SLACK_SIGNING_SECRET")
+	if value := os.Getenv("ADMIN_ADDR"); value != "" {
+		cfg.AdminAddr = value
+	}
+
+	cfg.FeatureFlagsFile = os.Getenv("FEATURE_FLAGS_FILE")
+	flags, err := LoadFeatureFlags(cfg.FeatureFlagsFile)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.FeatureFlags = flags
+
+	cfg.CategoriesFile = os.Getenv("CATEGORIES_FILE")
+	if cfg.CategoryKeywords, err = LoadCategoryKeywords(cfg.CategoriesFile); err != nil {
+		return Config{}, err
+	}
+
+	if value := os.Getenv("DEBUG"); value != "" {
+		debug, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DEBUG: %w", err)
+		}
+		cfg.Debug = debug
+	}
+
+	if value := os.Getenv("CSRF_PROTECTION"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CSRF_PROTECTION: %w", err)
+		}
+		cfg.CSRFProtection = enabled
+	}
+
+	if value := os.Getenv("WRAP_RESPONSES"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid WRAP_RESPONSES: %w", err)
+		}
+		cfg.WrapResponses = enabled
+	}
+
+	if value := os.Getenv("JOB_WORKERS"); value != "" {
+		workers, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid JOB_WORKERS: %w", err)
+		}
+		if workers < 1 {
+			return Config{}, fmt.Errorf("invalid JOB_WORKERS: %d, expected at least 1", workers)
+		}
+		cfg.JobWorkers = workers
+	}
+
+	if value := os.Getenv("STORAGE_RETRY_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid STORAGE_RETRY_ATTEMPTS: %w", err)
+		}
+		if attempts < 1 {
+			return Config{}, fmt.Errorf("invalid STORAGE_RETRY_ATTEMPTS: %d, expected at least 1", attempts)
+		}
+		cfg.StorageRetryAttempts = attempts
+	}
+
+	if value := os.Getenv("STORAGE_RETRY_BACKOFF"); value != "" {
+		backoff, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid STORAGE_RETRY_BACKOFF: %w", err)
+		}
+		cfg.StorageRetryBackoff = backoff
+	}
+
+	if cfg.Experiments, err = ParseExperiments(os.Getenv("EXPERIMENTS")); err != nil {
+		return Config{}, fmt.Errorf("invalid EXPERIMENTS: %w", err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigWithDefaults(t *testing.T) {
+	defaults := DefaultConfig()
+	got := Config{ExpirationInterval: -time.Second, JobWorkers: -1}.withDefaults()
+	if got.Addr != defaults.Addr || got.AdminAddr != defaults.AdminAddr {
+		t.Errorf("addresses = %q, %q, want %q, %q", got.Addr, got.AdminAddr, defaults.Addr, defaults.AdminAddr)
+	}
+	if got.ExpirationInterval != defaults.ExpirationInterval {
+		t.Errorf("ExpirationInterval = %v, want %v", got.ExpirationInterval, defaults.ExpirationInterval)
+	}
+	if got.JobWorkers != defaults.JobWorkers {
+		t.Errorf("JobWorkers = %d, want %d", got.JobWorkers, defaults.JobWorkers)
+	}
+
+	set := Config{Addr: ":9000", AdminAddr: ":9001", ExpirationInterval: time.Second, JobWorkers: 8}
+	if got := set.withDefaults(); got.Addr != set.Addr || got.AdminAddr != set.AdminAddr ||
+		got.ExpirationInterval != set.ExpirationInterval || got.JobWorkers != set.JobWorkers {
+		t.Errorf("withDefaults() = %+v, want the settings kept", got)
+	}
+}
+
+func TestNewServerAppliesDefaults(t *testing.T) {
+	s := NewServer(Config{}, NewInMemoryStorage())
+	if s.config.ExpirationInterval <= 0 || s.config.JobWorkers < 1 {
+		t.Errorf("ExpirationInterval = %v, JobWorkers = %d, want defaults", s.config.ExpirationInterval, s.config.JobWorkers)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+		check   func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != ":8080" || cfg.JobWorkers != 2 {
+					t.Errorf("Addr = %q, JobWorkers = %d", cfg.Addr, cfg.JobWorkers)
+				}
+			},
+		},
+		{
+			name: "overrides",
+			env:  map[string]string{"ADDR": ":9000", "JOB_WORKERS": "4", "DEBUG": "true"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != ":9000" || cfg.JobWorkers != 4 || !cfg.Debug {
+					t.Errorf("Addr = %q, JobWorkers = %d, Debug = %v", cfg.Addr, cfg.JobWorkers, cfg.Debug)
+				}
+			},
+		},
+		{
+			name: "log format in any case",
+			env:  map[string]string{"LOG_FORMAT": "JSON"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.LogFormat != "json" {
+					t.Errorf("LogFormat = %q, want json", cfg.LogFormat)
+				}
+			},
+		},
+		{name: "zero job workers", env: map[string]string{"JOB_WORKERS": "0"}, wantErr: true},
+		{name: "malformed queue size", env: map[string]string{"TASK_QUEUE_SIZE": "many"}, wantErr: true},
+		{name: "malformed debug", env: map[string]string{"DEBUG": "maybe"}, wantErr: true},
+		{
+			name: "experiments",
+			env:  map[string]string{"EXPERIMENTS": "layout=control,treatment"},
+			check: func(t *testing.T, cfg Config) {
+				if len(cfg.Experiments) != 1 || cfg.Experiments[0].Name != "layout" {
+					t.Errorf("Experiments = %+v", cfg.Experiments)
+				}
+			},
+		},
+		{name: "malformed experiments", env: map[string]string{"EXPERIMENTS": "layout=control"}, wantErr: true},
+		{
+			name: "storage retries",
+			env:  map[string]string{"STORAGE_RETRY_ATTEMPTS": "4", "STORAGE_RETRY_BACKOFF": "10ms"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.StorageRetryAttempts != 4 || cfg.StorageRetryBackoff != 10*time.Millisecond {
+					t.Errorf("StorageRetryAttempts = %d, StorageRetryBackoff = %v", cfg.StorageRetryAttempts, cfg.StorageRetryBackoff)
+				}
+			},
+		},
+		{name: "zero storage attempts", env: map[string]string{"STORAGE_RETRY_ATTEMPTS": "0"}, wantErr: true},
+		{name: "malformed storage backoff", env: map[string]string{"STORAGE_RETRY_BACKOFF": "soon"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := ConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
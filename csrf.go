@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// newCSRFToken returns a random, hex-encoded 256-bit token.
+func newCSRFToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// CSRFMiddleware implements double-submit cookie protection. Safe
+// requests (GET, HEAD, OPTIONS) receive a csrf_token cookie, readable
+// by scripts, if they do not carry one yet. Every other request must
+// echo that cookie's value in the X-CSRF-Token header, or it is
+// refused with 403 Forbidden.
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		cookie, err := request.Cookie(csrfCookieName)
+
+		switch request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if err != nil || cookie.Value == "" {
+				token, err := newCSRFToken()
+				if err != nil {
+					http.Error(writer, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				http.SetCookie(writer, &http.Cookie{
+					Name:     csrfCookieName,
+					Value:    token,
+					Path:     "/",
+					Secure:   request.TLS != nil,
+					SameSite: http.SameSiteStrictMode,
+				})
+			}
+		default:
+			header := request.Header.Get(csrfHeaderName)
+			if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+				localizedError(writer, request, MsgCSRFTokenInvalid, http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCursorSignerRoundTrip(t *testing.T) {
+	signer := NewCursorSigner("key")
+	for _, cursor := range []SyncCursor{
+		{Generation: 42},
+		{Generation: 7, AfterID: "task-3", Snapshot: true},
+		{Snapshot: true},
+	} {
+		decoded, err := signer.Decode(signer.Encode(cursor))
+		cursor.Version = syncCursorVersion
+		if err != nil || !reflect.DeepEqual(decoded, cursor) {
+			t.Errorf("Decode(Encode(%+v)) = %+v, %v", cursor, decoded, err)
+		}
+	}
+}
+
+func TestCursorSignerRejectsTampering(t *testing.T) {
+	signer := NewCursorSigner("key")
+	token := signer.Encode(SyncCursor{Generation: 42, AfterID: "task-3", Snapshot: true})
+
+	tokens := map[string]string{
+		"signed with another key":  NewCursorSigner("other key").Encode(SyncCursor{Generation: 42}),
+		"signed with a random key": NewCursorSigner("").Encode(SyncCursor{Generation: 42}),
+		"no signature":             token[:len(token)-44],
+		"not a token":              "cursor",
+		"empty":                    "",
+	}
+	for i := range token {
+		if token[i] == '.' {
+			continue
+		}
+		flipped := []byte(token)
+		flipped[i] ^= 1
+		tokens[fmt.Sprintf("byte %d flipped", i)] = string(flipped)
+	}
+	for name, tampered := range tokens {
+		if cursor, err := signer.Decode(tampered); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: Decode(%q) = %+v, %v, want ErrInvalidCursor", name, tampered, cursor, err)
+		}
+	}
+}
+
+// signedCursor signs a cursor payload of any JSON, as a server of
+// another version could have written it.
+func signedCursor(signer *CursorSigner, json string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(json))
+	return payload + "." + signer.sign(payload)
+}
+
+func TestCursorSignerDecodesForwardCompatibly(t *testing.T) {
+	signer := NewCursorSigner("key")
+
+	// Fields added later are ignored.
+	cursor, err := signer.Decode(signedCursor(signer, `{"v":1,"g":9,"after":"5","s":true,"shard":"eu"}`))
+	if want := (SyncCursor{Version: 1, Generation: 9, AfterID: "5", Snapshot: true}); err != nil || cursor != want {
+		t.Errorf("Decode() = %+v, %v, want %+v", cursor, err, want)
+	}
+
+	for _, json := range []string{`{"v":2,"g":9}`, `{"g":9}`, `{"v":1,"g":"nine"}`, `[1,9]`} {
+		if _, err := signer.Decode(signedCursor(signer, json)); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%s) error = %v, want ErrInvalidCursor", json, err)
+		}
+	}
+}
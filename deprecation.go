@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Deprecation describes the retirement of an endpoint.
+type Deprecation struct {
+	// DeprecationDate is when clients were told to stop using the endpoint.
+	DeprecationDate time.Time
+	// SunsetDate is when the endpoint stops responding. From then on
+	// it answers 410 Gone.
+	SunsetDate time.Time
+	// Link points to the documentation of the replacement. It may be empty.
+	Link string
+}
+
+// DeprecationPolicy maps "METHOD /route/pattern" keys, as matched by
+// chi, to the retirement of that endpoint.
+type DeprecationPolicy map[string]Deprecation
+
+// deprecationPolicy lists the retired endpoints. No endpoint has been
+// retired yet.
+var deprecationPolicy = DeprecationPolicy{}
+
+// deprecate adds the Deprecation and Sunset headers of RFC 8594 to the
+// responses of endpoints listed in s.deprecations, and refuses
+// requests to them with 410 Gone once their sunset date has passed.
+// It must be mounted as an inline middleware, so that the route
+// pattern is known.
+func (s *Server) deprecate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		pattern := chi.RouteContext(request.Context()).RoutePattern()
+		deprecation, ok := s.deprecations[request.Method+" "+pattern]
+		if !ok {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		header := writer.Header()
+		header.Set("Deprecation", deprecation.DeprecationDate.UTC().Format(http.TimeFormat))
+		header.Set("Sunset", deprecation.SunsetDate.UTC().Format(http.TimeFormat))
+		if deprecation.Link != "" {
+			header.Set("Link", fmt.Sprintf("<%s>; rel=\"sunset\"", deprecation.Link))
+		}
+
+		if !s.clock.Now().Before(deprecation.SunsetDate) {
+			s.logger.Warn("Запрос к выведенному из эксплуатации эндпоинту", "method", request.Method, "pattern", pattern)
+			localizedError(writer, request, MsgEndpointRetired, http.StatusGone)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDeprecate(t *testing.T) {
+	s, clock := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	deprecated := testNow.Add(-24 * time.Hour)
+	sunset := testNow.Add(time.Hour)
+	s.WithDeprecations(DeprecationPolicy{
+		"GET /tasks/{id}":    {DeprecationDate: deprecated, SunsetDate: sunset, Link: "https://example.com/docs/tasks-v2"},
+		"DELETE /tasks/{id}": {DeprecationDate: deprecated, SunsetDate: sunset},
+	})
+
+	response := serve(s, http.MethodGet, "/tasks/1", "")
+	assertStatus(t, response, http.StatusOK)
+	wantHeaders := map[string]string{
+		"Deprecation": "Sun, 14 Jan 2024 12:00:00 GMT",
+		"Sunset":      "Mon, 15 Jan 2024 13:00:00 GMT",
+		"Link":        `<https://example.com/docs/tasks-v2>; rel="sunset"`,
+	}
+	for name, want := range wantHeaders {
+		if got := response.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if link := serve(s, http.MethodDelete, "/tasks/2", "").Header().Get("Link"); link != "" {
+		t.Errorf("Link = %q for a deprecation without a link", link)
+	}
+
+	if response := serve(s, http.MethodGet, "/tasks", ""); response.Header().Get("Deprecation") != "" {
+		t.Error("GET /tasks is not deprecated, but carries a Deprecation header")
+	}
+
+	clock.Advance(time.Hour - time.Second)
+	assertStatus(t, serve(s, http.MethodGet, "/tasks/1", ""), http.StatusOK)
+
+	clock.Advance(time.Second)
+	response = serve(s, http.MethodGet, "/tasks/1", "")
+	assertStatus(t, response, http.StatusGone)
+	if response.Header().Get("Sunset") == "" {
+		t.Error("the 410 response has no Sunset header")
+	}
+	assertStatus(t, serve(s, http.MethodDelete, "/tasks/1", ""), http.StatusGone)
+	if _, err := s.store.Get(context.Background(), "1"); err != nil {
+		t.Errorf("a retired endpoint deleted the task: %v", err)
+	}
+	assertStatus(t, serve(s, http.MethodGet, "/tasks", ""), http.StatusOK)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Digest periods, as given by the period query parameter of GET
+// /tasks/digest.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// TaskDigest counts the tasks past their SLA deadline and the tasks
+// due later in the period, which ends at Until.
+type TaskDigest struct {
+	Period  string    `json:"period"`
+	Overdue int       `json:"overdue"`
+	Due     int       `json:"due"`
+	Until   time.Time `json:"until"`
+}
+
+// digestPeriodEnd returns the end of the UTC day, or of the UTC week
+// from Monday to Sunday, that now is in.
+func digestPeriodEnd(period string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case DigestDaily:
+		return day.AddDate(0, 0, 1), nil
+	case DigestWeekly:
+		daysSinceMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, 7-daysSinceMonday), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown period %q, expected %s or %s", period, DigestDaily, DigestWeekly)
+	}
+}
+
+// countTasks returns "1 task" or "n tasks".
+func countTasks(n int) string {
+	if n == 1 {
+		return "1 task"
+	}
+	return fmt.Sprintf("%d tasks", n)
+}
+
+// String returns the digest as a sentence, such as "You have 3 tasks
+// overdue and 1 task due today."
+func (digest TaskDigest) String() string {
+	when := "today"
+	if digest.Period == DigestWeekly {
+		when = "this week"
+	}
+	return fmt.Sprintf("You have %s overdue and %s due %s.", countTasks(digest.Overdue), countTasks(digest.Due), when)
+}
+
+// getTasksDigest handles the HTTP request to summarize the tasks for
+// the period given by the period query parameter, daily (the default)
+// or weekly: how many are past their SLA deadline, and how many are
+// due by the end of the UTC day or week. Tasks without a deadline are
+// not counted. Tasks have no completion state, so unlike a to-do
+// digest it cannot tell how many were completed. The summary is a
+// sentence in plain text, or the TaskDigest with format=json. An
+// unknown period or format is rejected with a HTTP 400 Bad Request; in
+// case of a storage error, it responds with the status chosen by
+// storageErrorStatus along with the error message.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the period and format query parameters.
+func (s *Server) getTasksDigest(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	period := DigestDaily
+	if value := query.Get("period"); value != "" {
+		period = value
+	}
+	now := s.clock.Now()
+	until, err := digestPeriodEnd(period, now)
+	if err != nil {
+		s.logger.Warn("Некорректный период сводки", "period", period)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "text" {
+		s.logger.Warn("Некорректный формат сводки", "format", format)
+		http.Error(writer, "format must be json or text", http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := s.store.GetAll(request.Context(), nil)
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи для сводки", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	digest := TaskDigest{Period: period, Until: until}
+	for _, task := range tasks {
+		deadline, ok := slaDeadline(task)
+		switch {
+		case !ok:
+		case !deadline.After(now):
+			digest.Overdue++
+		case deadline.Before(until):
+			digest.Due++
+		}
+	}
+
+	if format == "json" {
+		s.writeJSON(writer, http.StatusOK, digest)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	fmt.Fprintln(writer, digest)
+}
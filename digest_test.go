@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetTasksDigest(t *testing.T) {
+	// testNow is Monday 2024-01-15 12:00 UTC.
+	tasks := []Task{
+		{ID: "overdue", Description: "Просрочена", CreatedAt: testNow.Add(-2 * time.Hour), SLADeadlineMinutes: 60},
+		{ID: "due-now", Description: "Срок сейчас", CreatedAt: testNow.Add(-time.Hour), SLADeadlineMinutes: 60},
+		{ID: "today", Description: "Сегодня", CreatedAt: testNow, SLADeadlineMinutes: 60},
+		{ID: "thursday", Description: "В четверг", CreatedAt: testNow, SLADeadlineMinutes: 3 * 24 * 60},
+		{ID: "next-week", Description: "На следующей неделе", CreatedAt: testNow, SLADeadlineMinutes: 8 * 24 * 60},
+		{ID: "no-deadline", Description: "Когда-нибудь", CreatedAt: testNow},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantText string
+		want     TaskDigest
+	}{
+		{
+			name: "daily by default", query: "",
+			wantText: "You have 2 tasks overdue and 1 task due today.\n",
+		},
+		{
+			name: "weekly", query: "?period=weekly&format=text",
+			wantText: "You have 2 tasks overdue and 2 tasks due this week.\n",
+		},
+		{
+			name: "daily numbers", query: "?period=daily&format=json",
+			want: TaskDigest{Period: DigestDaily, Overdue: 2, Due: 1, Until: time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "weekly numbers", query: "?period=weekly&format=json",
+			want: TaskDigest{Period: DigestWeekly, Overdue: 2, Due: 2, Until: time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, tasks...)
+
+			response := serve(s, http.MethodGet, "/tasks/digest"+tt.query, "")
+			assertStatus(t, response, http.StatusOK)
+			if tt.wantText != "" {
+				if got := response.Body.String(); got != tt.wantText {
+					t.Errorf("digest = %q, want %q", got, tt.wantText)
+				}
+				return
+			}
+			var digest TaskDigest
+			decodeJSON(t, response.Body.Bytes(), &digest)
+			if digest.Period != tt.want.Period || digest.Overdue != tt.want.Overdue || digest.Due != tt.want.Due || !digest.Until.Equal(tt.want.Until) {
+				t.Errorf("digest = %+v, want %+v", digest, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTasksDigestEmpty(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	response := serve(s, http.MethodGet, "/tasks/digest", "")
+	assertStatus(t, response, http.StatusOK)
+	if got, want := response.Body.String(), "You have 0 tasks overdue and 0 tasks due today.\n"; got != want {
+		t.Errorf("digest = %q, want %q", got, want)
+	}
+}
+
+func TestDigestPeriodEnd(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		// Sunday is the last day of the week.
+		{now: time.Date(2024, 1, 21, 23, 59, 0, 0, time.UTC), want: time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC)},
+		{now: time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)},
+		// Weeks are UTC weeks.
+		{now: time.Date(2024, 1, 22, 1, 0, 0, 0, time.FixedZone("MSK", 3*60*60)), want: time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got, err := digestPeriodEnd(DigestWeekly, tt.now); err != nil || !got.Equal(tt.want) {
+			t.Errorf("digestPeriodEnd(weekly, %v) = %v, %v, want %v", tt.now, got, err, tt.want)
+		}
+	}
+}
+
+func TestGetTasksDigestRejected(t *testing.T) {
+	for _, query := range []string{"?period=monthly", "?period=Daily", "?format=xml"} {
+		s, _ := newTestServer(t)
+		assertStatus(t, serve(s, http.MethodGet, "/tasks/digest"+query, ""), http.StatusBadRequest)
+	}
+}
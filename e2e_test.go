@@ -0,0 +1,271 @@
+//go:build e2e
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The end-to-end tests build the server binary, start it with its
+// configuration in the environment, as in production, and drive it
+// over HTTP. Run them with
+//
+//	make test-e2e
+
+const (
+	e2eAdminSecret = "e2e-admin-secret"
+	e2eSlackSecret = "e2e-slack-secret"
+	// e2eStartTimeout is how long the server may take to answer.
+	e2eStartTimeout = 30 * time.Second
+)
+
+// e2eServer is a running server binary.
+type e2eServer struct {
+	api    string
+	admin  string
+	client *http.Client
+}
+
+// lockedBuffer collects the output of the server, which is written
+// while the test reads it.
+type lockedBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buffer *lockedBuffer) Write(p []byte) (int, error) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.buffer.Write(p)
+}
+
+func (buffer *lockedBuffer) String() string {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.buffer.String()
+}
+
+// freeAddr returns a local address no listener uses right now.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// startE2EServer builds the server and runs it on free ports until the
+// test ends. The server output is logged if the test fails.
+func startE2EServer(t *testing.T, env ...string) *e2eServer {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "server")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, output)
+	}
+
+	apiAddr, adminAddr := freeAddr(t), freeAddr(t)
+	cmd := exec.Command(binary)
+	cmd.Env = append(os.Environ(), append([]string{
+		"ADDR=" + apiAddr,
+		"ADMIN_ADDR=" + adminAddr,
+		"LOG_FORMAT=json",
+	}, env...)...)
+	output := &lockedBuffer{}
+	cmd.Stdout, cmd.Stderr = output, output
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		<-exited
+		if t.Failed() {
+			t.Logf("server output:\n%s", output)
+		}
+	})
+
+	server := &e2eServer{
+		api:    "http://" + apiAddr,
+		admin:  "http://" + adminAddr,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	deadline := time.Now().Add(e2eStartTimeout)
+	for {
+		select {
+		case err := <-exited:
+			t.Fatalf("the server exited: %v\n%s", err, output)
+		default:
+		}
+		if response, err := server.client.Get(server.api + "/version"); err == nil {
+			response.Body.Close()
+			return server
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the server did not answer within %v", e2eStartTimeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// do sends a request and returns the status and body of the response.
+// A non-empty body is sent as JSON unless header sets a Content-Type.
+func (server *e2eServer) do(t *testing.T, method, target, body string, header http.Header) (int, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	response, err := server.client.Do(request)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	defer response.Body.Close()
+	payload, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading the body: %v", method, target, err)
+	}
+	return response.StatusCode, payload
+}
+
+// expect is do that fails the test unless the response has status
+// want, and decodes a JSON body into value, if not nil.
+func (server *e2eServer) expect(t *testing.T, want int, method, target, body string, header http.Header, value interface{}) {
+	t.Helper()
+	status, payload := server.do(t, method, target, body, header)
+	if status != want {
+		t.Fatalf("%s %s: status = %d, want %d; body: %s", method, target, status, want, payload)
+	}
+	if value != nil {
+		if err := json.Unmarshal(payload, value); err != nil {
+			t.Fatalf("%s %s: invalid JSON %s: %v", method, target, payload, err)
+		}
+	}
+}
+
+// slackHeader signs a Slack slash command body with e2eSlackSecret.
+func slackHeader(body string) http.Header {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(e2eSlackSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	return http.Header{
+		"Content-Type":              {"application/x-www-form-urlencoded"},
+		"X-Slack-Request-Timestamp": {timestamp},
+		"X-Slack-Signature":         {"v0=" + hex.EncodeToString(mac.Sum(nil))},
+	}
+}
+
+func TestE2E(t *testing.T) {
+	server := startE2EServer(t, "ADMIN_SECRET="+e2eAdminSecret, "SLACK_SIGNING_SECRET="+e2eSlackSecret)
+	api, admin := server.api, server.admin
+	secret := http.Header{adminSecretHeader: {e2eAdminSecret}}
+
+	t.Run("seeded tasks", func(t *testing.T) {
+		var tasks map[string]Task
+		server.expect(t, http.StatusOK, http.MethodGet, api+"/tasks", "", nil, &tasks)
+		if len(tasks) != len(seedTasks) {
+			t.Errorf("got %d tasks, want the %d seeded", len(tasks), len(seedTasks))
+		}
+	})
+
+	t.Run("task lifecycle", func(t *testing.T) {
+		server.expect(t, http.StatusCreated, http.MethodPost, api+"/tasks",
+			`{"id":"e2e","description":"Проверить <b>сборку</b>","note":"","applications":["go"]}`, nil, nil)
+		var task Task
+		server.expect(t, http.StatusOK, http.MethodGet, api+"/tasks/e2e", "", nil, &task)
+		if task.Description != "Проверить сборку" || task.CreatedAt.IsZero() {
+			t.Errorf("task = %+v", task)
+		}
+		server.expect(t, http.StatusConflict, http.MethodPost, api+"/tasks",
+			`{"id":"e2e-2","description":"проверить СБОРКУ","note":"","applications":[]}`, nil, nil)
+		server.expect(t, http.StatusUnprocessableEntity, http.MethodPost, api+"/tasks", `{"id":"e2e-3"}`, nil, nil)
+		server.expect(t, http.StatusOK, http.MethodDelete, api+"/tasks/e2e", "", nil, nil)
+		server.expect(t, http.StatusBadRequest, http.MethodGet, api+"/tasks/e2e", "", nil, nil)
+	})
+
+	t.Run("import job", func(t *testing.T) {
+		var accepted struct {
+			JobID string `json:"job_id"`
+		}
+		server.expect(t, http.StatusAccepted, http.MethodPost, api+"/tasks/import",
+			`[{"id":"imported","description":"Импортированная задача","note":"","applications":[]},{"id":"broken"}]`, nil, &accepted)
+
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			var job struct {
+				Status string `json:"status"`
+			}
+			server.expect(t, http.StatusOK, http.MethodGet, api+"/jobs/"+accepted.JobID, "", nil, &job)
+			if job.Status == string(JobDone) {
+				break
+			}
+			if job.Status == string(JobFailed) || time.Now().After(deadline) {
+				t.Fatalf("import job status = %q", job.Status)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		server.expect(t, http.StatusOK, http.MethodGet, api+"/tasks/imported", "", nil, nil)
+		server.expect(t, http.StatusBadRequest, http.MethodGet, api+"/tasks/broken", "", nil, nil)
+	})
+
+	t.Run("slack command", func(t *testing.T) {
+		body := url.Values{"command": {"/task"}, "text": {"Созвониться со Slack #звонки"}, "user_name": {"e2e"}}.Encode()
+		server.expect(t, http.StatusUnauthorized, http.MethodPost, api+"/integrations/slack/command", body,
+			http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}, nil)
+
+		var reply SlackResponse
+		server.expect(t, http.StatusOK, http.MethodPost, api+"/integrations/slack/command", body, slackHeader(body), &reply)
+		if !strings.HasPrefix(reply.Text, "Created task ") || !strings.HasSuffix(reply.Text, ": Созвониться со Slack #звонки") {
+			t.Errorf("reply = %+v", reply)
+		}
+	})
+
+	t.Run("admin API", func(t *testing.T) {
+		server.expect(t, http.StatusNotFound, http.MethodGet, api+"/admin/tasks/count", "", secret, nil)
+		server.expect(t, http.StatusUnauthorized, http.MethodGet, admin+"/admin/tasks/count", "", nil, nil)
+
+		var count struct {
+			Count int `json:"count"`
+		}
+		server.expect(t, http.StatusOK, http.MethodGet, admin+"/admin/tasks/count", "", secret, &count)
+		// The seeded tasks, the imported one and the one from Slack.
+		if want := len(seedTasks) + 2; count.Count != want {
+			t.Errorf("count = %d, want %d", count.Count, want)
+		}
+	})
+
+	t.Run("blocklist", func(t *testing.T) {
+		server.expect(t, http.StatusCreated, http.MethodPost, admin+"/admin/blocklist", `{"ip":"127.0.0.1"}`, secret, nil)
+		server.expect(t, http.StatusForbidden, http.MethodGet, api+"/tasks", "", nil, nil)
+	})
+}
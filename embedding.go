@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Embedder turns text into a vector whose cosine similarity to the
+// vectors of other texts measures how alike they are. A client of a
+// hosted embedding model can replace HashingEmbedder through it.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// hashingDimensions is the length of the vectors of HashingEmbedder.
+const hashingDimensions = 256
+
+// HashingEmbedder embeds text without a model: every word is hashed
+// to one of hashingDimensions coordinates, with a sign taken from the
+// hash as well, and the vector is scaled to unit length. Texts sharing
+// words are similar; synonyms are not.
+type HashingEmbedder struct{}
+
+func (HashingEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	vector := make([]float32, hashingDimensions)
+	for _, word := range tokenize(text) {
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		sum := hash.Sum32()
+		if sum&(1<<31) != 0 {
+			vector[sum%hashingDimensions]--
+		} else {
+			vector[sum%hashingDimensions]++
+		}
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector, nil
+}
+
+// Embedding is the vector of a task along with the text it was
+// computed from, so that it can be recomputed once the text changes.
+type Embedding struct {
+	Text   string
+	Vector []float32
+}
+
+// Neighbour is a task whose embedding is close to the one searched for.
+type Neighbour struct {
+	ID         string
+	Similarity float64
+}
+
+// EmbeddingIndex keeps the embeddings of tasks and finds the nearest
+// ones. An approximate nearest neighbour index can replace
+// BruteForceIndex through it once scanning gets too slow.
+type EmbeddingIndex interface {
+	Put(id string, embedding Embedding)
+	Get(id string) (Embedding, bool)
+	Remove(id string)
+	// Nearest returns up to limit of the tasks for which keep is true,
+	// most similar to vector first, ties broken by ID.
+	Nearest(vector []float32, limit int, keep func(id string) bool) []Neighbour
+}
+
+// BruteForceIndex is an EmbeddingIndex comparing the vector searched
+// for with every stored one.
+type BruteForceIndex struct {
+	mu         sync.RWMutex
+	embeddings map[string]Embedding
+}
+
+// NewBruteForceIndex creates an empty BruteForceIndex.
+func NewBruteForceIndex() *BruteForceIndex {
+	return &BruteForceIndex{embeddings: make(map[string]Embedding)}
+}
+
+func (index *BruteForceIndex) Put(id string, embedding Embedding) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	index.embeddings[id] = embedding
+}
+
+func (index *BruteForceIndex) Get(id string) (Embedding, bool) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	embedding, ok := index.embeddings[id]
+	return embedding, ok
+}
+
+func (index *BruteForceIndex) Remove(id string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	delete(index.embeddings, id)
+}
+
+func (index *BruteForceIndex) Nearest(vector []float32, limit int, keep func(id string) bool) []Neighbour {
+	index.mu.RLock()
+	neighbours := make([]Neighbour, 0, len(index.embeddings))
+	for id, embedding := range index.embeddings {
+		if keep(id) {
+			neighbours = append(neighbours, Neighbour{ID: id, Similarity: cosineSimilarity(vector, embedding.Vector)})
+		}
+	}
+	index.mu.RUnlock()
+
+	sort.Slice(neighbours, func(i, j int) bool {
+		if neighbours[i].Similarity != neighbours[j].Similarity {
+			return neighbours[i].Similarity > neighbours[j].Similarity
+		}
+		return neighbours[i].ID < neighbours[j].ID
+	})
+	return neighbours[:min(limit, len(neighbours))]
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b,
+// or 0 if either is zero or their lengths differ.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// embeddingText is the text of a task that is embedded.
+func embeddingText(task Task) string {
+	return task.Description + "\n" + task.Note
+}
+
+// embed returns the embedding of the task, computing and storing it if
+// there is none yet or the task text changed since.
+func (s *Server) embed(ctx context.Context, task Task) ([]float32, error) {
+	text := embeddingText(task)
+	if embedding, ok := s.embeddings.Get(task.ID); ok && embedding.Text == text {
+		return embedding.Vector, nil
+	}
+
+	vector, err := s.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	s.embeddings.Put(task.ID, Embedding{Text: text, Vector: vector})
+	return vector, nil
+}
+
+// embedCreated computes the embedding of a task just created, so that
+// GET /tasks/similar finds it ready. The embedding is computed inline
+// rather than by a job, so that nothing is left behind for tasks that
+// are deleted soon after. Failing to is only logged, since
+// GET /tasks/similar embeds missing tasks itself.
+func (s *Server) embedCreated(ctx context.Context, task Task) {
+	if _, err := s.embed(ctx, task); err != nil {
+		s.logger.Warn("Не удалось векторизовать задачу", "id", task.ID, "error", err)
+	}
+}
+
+const (
+	// similarDefaultLimit and similarMaxLimit bound the number of
+	// tasks GET /tasks/similar/{id} returns.
+	similarDefaultLimit = 5
+	similarMaxLimit     = 50
+)
+
+// SimilarTask is a task found by GET /tasks/similar/{id}, with the
+// cosine similarity of its embedding to that of the task asked about.
+type SimilarTask struct {
+	Task
+	Similarity float64 `json:"similarity"`
+}
+
+// getSimilarTasks handles the HTTP request to find the tasks most
+// similar to the one with the ID given in the URL, at most limit (5 by
+// default, at most 50), most similar first. Similarity is the cosine
+// of the embeddings of the descriptions and notes; tasks not embedded
+// yet, see embedCreated, are embedded first. A malformed limit is
+// rejected with a HTTP 400 Bad Request, and an unknown task is
+// reported with the status chosen by storageErrorStatus.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the task ID and the limit query parameter.
+func (s *Server) getSimilarTasks(writer http.ResponseWriter, request *http.Request) {
+	limit := similarDefaultLimit
+	if value := request.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > similarMaxLimit {
+			s.logger.Warn("Некорректный лимит похожих задач", "limit", value)
+			http.Error(writer, "limit must be a number from 1 to "+strconv.Itoa(similarMaxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	taskID := chi.URLParam(request, "id")
+	tasks, err := s.store.GetAll(request.Context(), nil)
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	byID := make(map[string]Task, len(tasks))
+	var target []float32
+	for _, task := range tasks {
+		byID[task.ID] = task
+		vector, err := s.embed(request.Context(), task)
+		if err != nil {
+			s.logger.Error("Не удалось векторизовать задачу", "id", task.ID, "error", err)
+			http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if task.ID == taskID {
+			target = vector
+		}
+	}
+	if target == nil {
+		err = &StorageError{Op: "get", ID: taskID, Err: ErrNotFound}
+		s.logger.Warn("Задача не найдена", "id", taskID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	}
+
+	neighbours := s.embeddings.Nearest(target, limit, func(id string) bool {
+		_, exists := byID[id]
+		return exists && id != taskID
+	})
+	similar := make([]SimilarTask, 0, len(neighbours))
+	for _, neighbour := range neighbours {
+		task := byID[neighbour.ID]
+		s.presentTask(&task)
+		similar = append(similar, SimilarTask{Task: task, Similarity: math.Round(neighbour.Similarity*1000) / 1000})
+	}
+	s.writeJSON(writer, http.StatusOK, similar)
+}
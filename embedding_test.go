@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCreatedTasksAreEmbeddedInline(t *testing.T) {
+	s, _ := newTestServer(t)
+	startJobRunner(t, s)
+
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", `{"id":"posted","description":"Сварить кофе","note":"","applications":[]}`), http.StatusCreated)
+	importTasks(t, s, "/tasks/import", "application/json", `[{"id":"imported","description":"Купить чай","note":"","applications":[]}]`)
+
+	for id, text := range map[string]string{"posted": "Сварить кофе\n", "imported": "Купить чай\n"} {
+		if embedding, ok := s.embeddings.Get(id); !ok || embedding.Text != text {
+			t.Errorf("embedding of %s = %+v, %v, want one of %q", id, embedding, ok, text)
+		}
+	}
+	// Only the import is a job; embedding leaves none behind.
+	jobs, err := s.jobs.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Type != importJobType {
+		t.Errorf("jobs = %+v, want only the import", jobs)
+	}
+
+	assertStatus(t, serve(s, http.MethodDelete, "/tasks/posted", ""), http.StatusOK)
+	if _, ok := s.embeddings.Get("posted"); ok {
+		t.Error("the embedding of the deleted task is kept")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Envelope is the form successful JSON responses take when
+// Config.WrapResponses is set.
+type Envelope struct {
+	Data json.RawMessage `json:"data"`
+	Meta EnvelopeMeta    `json:"meta"`
+}
+
+// EnvelopeMeta describes the request a wrapped response answers.
+type EnvelopeMeta struct {
+	RequestID string `json:"request_id"`
+	Timestamp string `json:"timestamp"`
+}
+
+// bufferingWriter holds a response back from the client, so that it
+// can be rewritten before it is sent. A response that is flushed and
+// would not be rewritten is passed through from then on instead.
+type bufferingWriter struct {
+	next   http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+	// passed is set once the response is passed through to next.
+	passed bool
+}
+
+func (writer *bufferingWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *bufferingWriter) WriteHeader(status int) {
+	if writer.status == 0 {
+		writer.status = status
+	}
+}
+
+func (writer *bufferingWriter) Write(data []byte) (int, error) {
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	if writer.passed {
+		return writer.next.Write(data)
+	}
+	return writer.body.Write(data)
+}
+
+// Flush sends the response so far to the client, and the rest as it is
+// written, unless it is to be wrapped: an Envelope needs the whole body.
+func (writer *bufferingWriter) Flush() {
+	if !writer.passed {
+		if writer.status == 0 || wrappable(writer.status, writer.header) {
+			return
+		}
+		writer.next.WriteHeader(writer.status)
+		writer.next.Write(writer.body.Bytes())
+		writer.body.Reset()
+		writer.passed = true
+	}
+	flush(writer.next)
+}
+
+// wrappable reports whether wrapResponses wraps a response with the
+// given status and header, provided its body is valid JSON.
+func wrappable(status int, header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return status >= 200 && status < 300 && mediaType == "application/json"
+}
+
+// wrapResponses wraps every successful JSON response in an Envelope
+// carrying the request ID and the time of the response. Error
+// responses and responses in other formats are sent unchanged. It
+// must be mounted after middleware.RequestID.
+func (s *Server) wrapResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		buffer := &bufferingWriter{next: writer, header: writer.Header()}
+		next.ServeHTTP(buffer, request)
+		if buffer.passed {
+			return
+		}
+		if buffer.status == 0 {
+			buffer.status = http.StatusOK
+		}
+
+		body := buffer.body.Bytes()
+		if wrappable(buffer.status, buffer.header) && json.Valid(body) {
+			wrapped, err := json.Marshal(Envelope{
+				Data: json.RawMessage(body),
+				Meta: EnvelopeMeta{
+					RequestID: middleware.GetReqID(request.Context()),
+					Timestamp: s.clock.Now().UTC().Format(time.RFC3339),
+				},
+			})
+			if err != nil {
+				s.logger.Error("Не удалось обернуть ответ", "path", request.URL.Path, "error", err)
+			} else {
+				body = wrapped
+				buffer.header.Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+
+		writer.WriteHeader(buffer.status)
+		writer.Write(body)
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		wantStatus  int
+		wantWrapped bool
+	}{
+		{name: "task", method: http.MethodGet, target: "/tasks/1", wantStatus: http.StatusOK, wantWrapped: true},
+		{name: "task list", method: http.MethodGet, target: "/tasks?sort=id", wantStatus: http.StatusOK, wantWrapped: true},
+		{name: "not found", method: http.MethodGet, target: "/tasks/99", wantStatus: http.StatusBadRequest},
+		{name: "validation errors", method: http.MethodPost, target: "/tasks", body: `{"id":"3"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "markdown", method: http.MethodGet, target: "/tasks/export?format=md", wantStatus: http.StatusOK},
+	}
+	for _, wrap := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(tt.name+"/wrap="+strconv.FormatBool(wrap), func(t *testing.T) {
+				cfg := DefaultConfig()
+				cfg.WrapResponses = wrap
+				s, _ := newTestServerWithConfig(t, cfg, LoadFixtures(t))
+				response := serve(s, tt.method, tt.target, tt.body)
+				unwrapped, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+				bare := serve(unwrapped, tt.method, tt.target, tt.body)
+
+				assertStatus(t, response, tt.wantStatus)
+				if !wrap || !tt.wantWrapped {
+					if response.Body.String() != bare.Body.String() {
+						t.Errorf("body = %s, want it unchanged: %s", response.Body, bare.Body)
+					}
+					return
+				}
+
+				var envelope Envelope
+				decodeJSON(t, response.Body.Bytes(), &envelope)
+				if string(envelope.Data) != strings.TrimSpace(bare.Body.String()) {
+					t.Errorf("data = %s, want %s", envelope.Data, bare.Body)
+				}
+				if envelope.Meta.RequestID == "" || envelope.Meta.Timestamp != testNow.Format(time.RFC3339) {
+					t.Errorf("meta = %+v", envelope.Meta)
+				}
+				if length := response.Header().Get("Content-Length"); length != strconv.Itoa(response.Body.Len()) {
+					t.Errorf("Content-Length = %s for a %d byte body", length, response.Body.Len())
+				}
+				if !json.Valid(response.Body.Bytes()) {
+					t.Errorf("invalid JSON %s", response.Body)
+				}
+			})
+		}
+	}
+}
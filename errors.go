@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when no task has the requested ID.
+	ErrNotFound = errors.New("task not found")
+	// ErrAlreadyExists is returned when creating a task whose ID is taken.
+	ErrAlreadyExists = errors.New("task already exists")
+	// ErrDuplicateDescription is returned, as a
+	// *DuplicateDescriptionError, when creating a task whose
+	// description another task already has.
+	ErrDuplicateDescription = errors.New("task description is taken")
+	// ErrConflict is returned when a write loses a race against a
+	// concurrent change of the same task.
+	ErrConflict = errors.New("task was changed concurrently")
+	// ErrGenerationExpired is returned when the changes since a
+	// generation are asked for after they were dropped from the
+	// change history.
+	ErrGenerationExpired = errors.New("generation is no longer in the change history")
+	// ErrProjectNotFound is returned when no project has the requested ID.
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrProjectAlreadyExists is returned when creating a project whose
+	// ID is taken.
+	ErrProjectAlreadyExists = errors.New("project already exists")
+	// ErrSavedSearchNotFound is returned when no saved search has the
+	// requested ID.
+	ErrSavedSearchNotFound = errors.New("saved search not found")
+	// ErrUnavailable is wrapped by storage backends into failures that
+	// are expected to go away on their own, such as a database failing
+	// over. RetryStorage retries the operations failing with it.
+	ErrUnavailable = errors.New("storage is temporarily unavailable")
+)
+
+// DuplicateDescriptionError reports the task that already has the
+// description of a task being created. It matches
+// ErrDuplicateDescription.
+type DuplicateDescriptionError struct {
+	ExistingID string
+}
+
+func (err *DuplicateDescriptionError) Error() string {
+	return fmt.Sprintf("%v by task %s", ErrDuplicateDescription, err.ExistingID)
+}
+
+func (err *DuplicateDescriptionError) Is(target error) bool {
+	return target == ErrDuplicateDescription
+}
+
+// StorageError records the storage operation and the ID of the task,
+// or of the entity named by Entity, that failed, together with the
+// underlying error, which is usually one of the Err* sentinels or a
+// context error.
+type StorageError struct {
+	Op string
+	// Entity is the kind of record operated on. Empty means "task".
+	Entity string
+	ID     string
+	Err    error
+}
+
+func (err *StorageError) Error() string {
+	entity := err.Entity
+	if entity == "" {
+		entity = "task"
+	}
+	if err.ID == "" {
+		return fmt.Sprintf("%s %ss: %v", err.Op, entity, err.Err)
+	}
+	return fmt.Sprintf("%s %s %s: %v", err.Op, entity, err.ID, err.Err)
+}
+
+func (err *StorageError) Unwrap() error {
+	return err.Err
+}
+
+// storageErrorStatus returns the HTTP status a storage error is
+// reported with. Missing tasks are reported as 400 Bad Request, which
+// is what the API has always answered for unknown IDs; newer record
+// kinds use 404 Not Found.
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrProjectNotFound), errors.Is(err, ErrSavedSearchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAlreadyExists), errors.Is(err, ErrDuplicateDescription), errors.Is(err, ErrProjectAlreadyExists),
+		errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrGenerationExpired):
+		return http.StatusGone
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
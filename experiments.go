@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// userIDHeader names the user a request is made for. The API does not
+// authenticate users, so it only keeps a user in the same experiment
+// variants across requests.
+const userIDHeader = "X-User-ID"
+
+// Variant is one of the behaviors an A/B experiment compares.
+type Variant string
+
+// Experiment splits requests evenly between its variants. The first
+// variant is the control, the behavior of the API without the
+// experiment.
+type Experiment struct {
+	Name     string    `json:"name"`
+	Variants []Variant `json:"variants"`
+}
+
+// ParseExperiments parses experiments written as name=control,variant
+// and separated by semicolons, such as
+// "search-ranking=tfidf,bm25;export=v1,v2". Empty means none.
+func ParseExperiments(value string) ([]Experiment, error) {
+	var experiments []Experiment
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, list, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("expected name=control,variant, got %q", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("experiment %q is listed twice", name)
+		}
+		seen[name] = true
+
+		experiment := Experiment{Name: name}
+		variants := make(map[Variant]bool)
+		for _, variant := range strings.Split(list, ",") {
+			variant := Variant(strings.TrimSpace(variant))
+			if variant == "" || variants[variant] {
+				return nil, fmt.Errorf("experiment %q has an empty or repeated variant", name)
+			}
+			variants[variant] = true
+			experiment.Variants = append(experiment.Variants, variant)
+		}
+		if len(experiment.Variants) < 2 {
+			return nil, fmt.Errorf("experiment %q needs at least two variants", name)
+		}
+		experiments = append(experiments, experiment)
+	}
+	return experiments, nil
+}
+
+// assign returns the variant of the experiment for subject, picked by
+// the SHA-256 hash of the experiment name and subject. A subject thus
+// always gets the same variant of an experiment, and the variants it
+// gets in different experiments are independent, even for nearly
+// identical subjects such as sequential request IDs.
+func (experiment Experiment) assign(subject string) Variant {
+	sum := sha256.Sum256([]byte(experiment.Name + "\x00" + subject))
+	bucket := binary.BigEndian.Uint64(sum[:8]) % uint64(len(experiment.Variants))
+	return experiment.Variants[bucket]
+}
+
+// Experiments holds the running experiments and counts the requests
+// assigned to each of their variants.
+type Experiments struct {
+	mu          sync.Mutex
+	experiments map[string]Experiment
+	exposures   map[string]map[Variant]int
+}
+
+// NewExperiments creates Experiments running the given experiments.
+// Experiments with fewer than two variants compare nothing and are
+// skipped.
+func NewExperiments(experiments ...Experiment) *Experiments {
+	registry := &Experiments{
+		experiments: make(map[string]Experiment, len(experiments)),
+		exposures:   make(map[string]map[Variant]int, len(experiments)),
+	}
+	for _, experiment := range experiments {
+		if len(experiment.Variants) < 2 {
+			continue
+		}
+		registry.experiments[experiment.Name] = experiment
+		registry.exposures[experiment.Name] = make(map[Variant]int, len(experiment.Variants))
+	}
+	return registry
+}
+
+// expose assigns subject to a variant of the named experiment and
+// counts the exposure. It reports false if no such experiment runs.
+func (registry *Experiments) expose(name, subject string) (Variant, bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	experiment, ok := registry.experiments[name]
+	if !ok {
+		return "", false
+	}
+	variant := experiment.assign(subject)
+	registry.exposures[name][variant]++
+	return variant, true
+}
+
+// ExperimentStats reports how many requests were assigned to each
+// variant of an experiment.
+type ExperimentStats struct {
+	Experiment
+	Exposures map[Variant]int `json:"exposures"`
+}
+
+// Stats returns the exposures of every running experiment, ordered by
+// name.
+func (registry *Experiments) Stats() []ExperimentStats {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	stats := make([]ExperimentStats, 0, len(registry.experiments))
+	for name, experiment := range registry.experiments {
+		exposures := make(map[Variant]int, len(experiment.Variants))
+		for _, variant := range experiment.Variants {
+			exposures[variant] = registry.exposures[name][variant]
+		}
+		stats = append(stats, ExperimentStats{Experiment: experiment, Exposures: exposures})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// experimentSubject returns what a request is bucketed by: the user of
+// the X-User-ID header or, for anonymous requests, the request ID.
+func experimentSubject(request *http.Request) string {
+	if user := request.Header.Get(userIDHeader); user != "" {
+		return user
+	}
+	if id := middleware.GetReqID(request.Context()); id != "" {
+		return id
+	}
+	return clientIP(request)
+}
+
+// experimentVariant returns the variant of the named experiment the
+// request is assigned to, and logs and counts the exposure. Like
+// featureFlags, it lets a handler branch on the variant; if the
+// experiment is not running, it reports false and the handler should
+// keep its usual behavior.
+func (s *Server) experimentVariant(request *http.Request, name string) (Variant, bool) {
+	variant, ok := s.experiments.expose(name, experimentSubject(request))
+	if ok {
+		s.logger.Info("Запрос отнесён к варианту эксперимента", "experiment", name, "variant", variant,
+			"request_id", middleware.GetReqID(request.Context()))
+	}
+	return variant, ok
+}
+
+// ABRouter sends each request to the handler of the variant of the
+// named experiment it is assigned to. Requests go to control if the
+// experiment is not running or their variant has no handler.
+func (s *Server) ABRouter(experiment string, control http.Handler, variants map[Variant]http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		handler := control
+		if variant, ok := s.experimentVariant(request, experiment); ok {
+			if assigned, found := variants[variant]; found {
+				handler = assigned
+			}
+		}
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+// getAdminExperiments writes the running experiments with the number
+// of requests assigned to each variant in JSON format to the provided
+// http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - req: The http.Request received from the client. This parameter is ignored in this function.
+func (s *Server) getAdminExperiments(writer http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(writer, http.StatusOK, s.experiments.Stats())
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestParseExperiments(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []Experiment
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "ranking=tfidf,bm25", want: []Experiment{{Name: "ranking", Variants: []Variant{"tfidf", "bm25"}}}},
+		{
+			value: " ranking = tfidf , bm25 ; export=v1,v2,v3;",
+			want: []Experiment{
+				{Name: "ranking", Variants: []Variant{"tfidf", "bm25"}},
+				{Name: "export", Variants: []Variant{"v1", "v2", "v3"}},
+			},
+		},
+		{value: "ranking", wantErr: true},
+		{value: "=a,b", wantErr: true},
+		{value: "ranking=tfidf", wantErr: true},
+		{value: "ranking=tfidf,,bm25", wantErr: true},
+		{value: "ranking=a,a", wantErr: true},
+		{value: "ranking=a,b;ranking=c,d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseExperiments(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExperiments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExperiments() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newExperimentServer creates a server running a two-variant
+// experiment, and an ABRouter that answers with the variant name.
+func newExperimentServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.AdminSecret = "s3cret"
+	cfg.Experiments = []Experiment{{Name: "layout", Variants: []Variant{"control", "treatment"}}}
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage())
+
+	answer := func(variant string) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.Write([]byte(variant))
+		})
+	}
+	router := s.ABRouter("layout", answer("control"), map[Variant]http.Handler{"treatment": answer("treatment")})
+	return s, middleware.RequestID(router)
+}
+
+func TestABRouterSplitsRequests(t *testing.T) {
+	const requests = 1000
+	s, router := newExperimentServer(t)
+
+	// A user per request, so that the split does not depend on the
+	// random prefix of the request IDs.
+	served := make(map[string]int)
+	for i := 0; i < requests; i++ {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(userIDHeader, "user-"+strconv.Itoa(i))
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		served[response.Body.String()]++
+	}
+	t.Logf("split %v", served)
+
+	// Within 5 points of an even split.
+	for _, variant := range []string{"control", "treatment"} {
+		if count := served[variant]; count < 450 || count > 550 {
+			t.Errorf("%s served %d of %d requests, want about half; split %v", variant, count, requests, served)
+		}
+	}
+	stats := s.experiments.Stats()
+	if len(stats) != 1 || stats[0].Exposures["control"] != served["control"] || stats[0].Exposures["treatment"] != served["treatment"] {
+		t.Errorf("Stats() = %+v, want the served counts %v", stats, served)
+	}
+}
+
+func TestABRouterKeepsUsersInTheirVariant(t *testing.T) {
+	_, router := newExperimentServer(t)
+	variants := make(map[string]bool)
+	for user := 0; user < 20; user++ {
+		var first string
+		for i := 0; i < 5; i++ {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set(userIDHeader, "user-"+strconv.Itoa(user))
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+			if i == 0 {
+				first = response.Body.String()
+			} else if got := response.Body.String(); got != first {
+				t.Fatalf("user %d got %s after %s", user, got, first)
+			}
+		}
+		variants[first] = true
+	}
+	if len(variants) != 2 {
+		t.Errorf("20 users all got %v", variants)
+	}
+}
+
+func TestABRouterWithoutExperiment(t *testing.T) {
+	s, _ := newTestServer(t)
+	control := http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) { writer.Write([]byte("control")) })
+	router := s.ABRouter("layout", control, map[Variant]http.Handler{"treatment": http.NotFoundHandler()})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+	if response.Body.String() != "control" {
+		t.Errorf("body = %q, want the control", response.Body.String())
+	}
+	if variant, ok := s.experimentVariant(httptest.NewRequest(http.MethodGet, "/", nil), "layout"); ok {
+		t.Errorf("experimentVariant() = %q for an experiment that is not running", variant)
+	}
+}
+
+func TestGetAdminExperiments(t *testing.T) {
+	s, router := newExperimentServer(t)
+	for i := 0; i < 10; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	response := serveAdmin(s, http.MethodGet, "/admin/experiments", "")
+	assertStatus(t, response, http.StatusOK)
+	var stats []ExperimentStats
+	decodeJSON(t, response.Body.Bytes(), &stats)
+	if len(stats) != 1 || stats[0].Name != "layout" || stats[0].Exposures["control"]+stats[0].Exposures["treatment"] != 10 {
+		t.Errorf("experiments = %+v", stats)
+	}
+}
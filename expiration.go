@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// expireTasks deletes expired tasks every interval until ctx is
+// cancelled. Expiry is judged against the server clock rather than
+// the tick time. It is meant to be run in its own goroutine.
+func (s *Server) expireTasks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deleteExpiredTasks(ctx, s.clock.Now())
+		}
+	}
+}
+
+// deleteExpiredTasks removes every task whose TTL has elapsed at the
+// given moment. Tasks without a TTL never expire. The sweep stops
+// early, leaving the remaining tasks for the next tick, once ctx is done.
+func (s *Server) deleteExpiredTasks(ctx context.Context, now time.Time) {
+	tasks, err := s.store.GetAll(ctx, nil)
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи для проверки TTL", "error", err)
+		return
+	}
+
+	for _, task := range tasks {
+		if !isExpired(task, now) {
+			continue
+		}
+		if err := s.store.Delete(ctx, task.ID); errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			s.logger.Error("Не удалось удалить задачу с истёкшим TTL", "id", task.ID, "error", err)
+			return
+		}
+		s.forgetTask(task.ID)
+		s.logger.Info("Удалена задача с истёкшим TTL", "id", task.ID)
+	}
+}
+
+// isExpired reports whether the task's TTL has elapsed at the given moment.
+func isExpired(task Task, now time.Time) bool {
+	return task.TTL != nil && !now.Before(task.CreatedAt.Add(*task.TTL))
+}
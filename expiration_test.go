@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsExpired(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  *time.Duration
+		age  time.Duration
+		want bool
+	}{
+		{name: "no TTL", ttl: nil, age: 24 * time.Hour, want: false},
+		{name: "before TTL", ttl: ttl(time.Hour), age: 59 * time.Minute, want: false},
+		{name: "at TTL", ttl: ttl(time.Hour), age: time.Hour, want: true},
+		{name: "after TTL", ttl: ttl(time.Hour), age: 2 * time.Hour, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := Task{ID: "1", CreatedAt: testNow, TTL: tt.ttl}
+			if got := isExpired(task, testNow.Add(tt.age)); got != tt.want {
+				t.Errorf("isExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteExpiredTasks(t *testing.T) {
+	s, clock := newTestServer(t,
+		Task{ID: "short", Description: "short", CreatedAt: testNow, TTL: ttl(time.Minute)},
+		Task{ID: "long", Description: "long", CreatedAt: testNow, TTL: ttl(time.Hour)},
+		Task{ID: "forever", Description: "forever", CreatedAt: testNow},
+	)
+	ctx := context.Background()
+
+	clock.Advance(30 * time.Second)
+	s.deleteExpiredTasks(ctx, clock.Now())
+	assertTasks(t, s, "forever", "long", "short")
+
+	clock.Advance(time.Minute)
+	s.deleteExpiredTasks(ctx, clock.Now())
+	assertTasks(t, s, "forever", "long")
+
+	clock.Advance(24 * time.Hour)
+	s.deleteExpiredTasks(ctx, clock.Now())
+	assertTasks(t, s, "forever")
+}
+
+func TestDeleteExpiredTasksStopsWhenCancelled(t *testing.T) {
+	s, clock := newTestServer(t, Task{ID: "1", Description: "one", CreatedAt: testNow, TTL: ttl(time.Minute)})
+	clock.Advance(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.deleteExpiredTasks(ctx, clock.Now())
+	assertTasks(t, s, "1")
+}
+
+func TestPostTaskRejectsNonPositiveTTL(t *testing.T) {
+	for _, value := range []string{"0", "-1"} {
+		t.Run(value, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			response := serve(s, http.MethodPost, "/tasks",
+				`{"id":"1","description":"one","note":"","applications":[],"ttl":`+value+`}`)
+			assertStatus(t, response, http.StatusUnprocessableEntity)
+			if _, err := s.store.Get(context.Background(), "1"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("task was stored, Get error = %v", err)
+			}
+		})
+	}
+}
+
+// assertTasks fails the test unless the storage of s holds exactly the
+// tasks with the given IDs, in ascending order.
+func assertTasks(t *testing.T, s *Server, ids ...string) {
+	t.Helper()
+	_, tasks, err := s.store.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	got := taskIDs(tasks)
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(ids, ",") {
+		t.Errorf("stored tasks = %v, want %v", got, ids)
+	}
+}
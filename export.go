@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// markdownTemplate renders tasks as a Markdown checklist. Tasks have
+// no completion state yet, so every item is unchecked.
+var markdownTemplate = template.Must(template.New("tasks.md").Funcs(template.FuncMap{
+	"oneLine": func(text string) string { return strings.Join(strings.Fields(text), " ") },
+}).Parse(`# Tasks
+{{range .}}- [ ] {{oneLine .Description}}
+{{end}}`))
+
+// exportFlushInterval is how many records a streamed export writes
+// between flushes. They are read from the storage a page of as many at
+// a time.
+const exportFlushInterval = 100
+
+// exportTasks handles the HTTP request to export every task, in task
+// ID order, in the format given by the format query parameter:
+//   - md: a Markdown checklist with one item per task, sent as text/markdown.
+//   - json: a JSON array of tasks, as GET /tasks writes them.
+//   - csv: a CSV file with the columns POST /tasks/import accepts.
+//
+// JSON and CSV exports are streamed: the tasks are read from the
+// storage a page at a time, if it is a Pager, and flushed to the client
+// every exportFlushInterval tasks, so that large exports are not held
+// in memory as a whole. Any other format is rejected with a HTTP 400 Bad
+// Request. In case of a storage error, it responds with the status
+// chosen by storageErrorStatus along with the error message.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the format query parameter.
+func (s *Server) exportTasks(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	var stream func(writer http.ResponseWriter, page []Task, next func() ([]Task, error)) (int, error)
+	switch format {
+	case "md":
+		response, count, err := s.renderMarkdown(request.Context())
+		if err != nil {
+			s.logger.Error("Не удалось сформировать экспорт задач", "error", err)
+			http.Error(writer, err.Error(), storageErrorStatus(err))
+			return
+		}
+
+		writer.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		writer.WriteHeader(http.StatusOK)
+		writer.Write(response)
+		s.logger.Info("Задачи экспортированы", "format", format, "count", count)
+		return
+	case "json":
+		stream = s.streamJSON
+	case "csv":
+		stream = streamCSV
+	default:
+		s.logger.Warn("Неподдерживаемый формат экспорта", "format", format)
+		http.Error(writer, "Unsupported export format, expected format=md, json or csv", http.StatusBadRequest)
+		return
+	}
+
+	next := s.taskPages(request.Context(), exportFlushInterval)
+	page, err := next()
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи для экспорта", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	// Once streaming has begun the status is sent, so an error can only
+	// cut the export short.
+	count, err := stream(writer, page, next)
+	if err != nil {
+		s.logger.Error("Экспорт задач прерван", "format", format, "count", count, "error", err)
+		return
+	}
+	s.logger.Info("Задачи экспортированы", "format", format, "count", count)
+}
+
+// taskPages returns a function returning, on every call, the next size
+// tasks in task ID order, until it returns none. Storages that are not
+// a Pager are read whole with GetAll on the first call.
+func (s *Server) taskPages(ctx context.Context, size int) func() ([]Task, error) {
+	if pager, ok := s.store.(Pager); ok {
+		after := ""
+		return func() ([]Task, error) {
+			page, err := pager.Page(ctx, after, size)
+			if err == nil && len(page) > 0 {
+				after = page[len(page)-1].ID
+			}
+			return page, err
+		}
+	}
+
+	var tasks []Task
+	loaded := false
+	return func() ([]Task, error) {
+		if !loaded {
+			var err error
+			if tasks, err = s.store.GetAll(ctx, nil); err != nil {
+				return nil, err
+			}
+			sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+			loaded = true
+		}
+		page := tasks[:min(size, len(tasks))]
+		tasks = tasks[len(page):]
+		return page, nil
+	}
+}
+
+// flush sends what has been written so far to the client, if the
+// writer supports it.
+func flush(writer http.ResponseWriter) {
+	if flusher, ok := writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// streamJSON writes the tasks of page and of the pages next returns
+// as a JSON array, one element at a time, flushing after every page,
+// and returns how many it wrote.
+func (s *Server) streamJSON(writer http.ResponseWriter, page []Task, next func() ([]Task, error)) (int, error) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+
+	if _, err := io.WriteString(writer, "["); err != nil {
+		return 0, err
+	}
+	encoder := json.NewEncoder(writer)
+	count := 0
+	for len(page) > 0 {
+		for i := range page {
+			if count > 0 {
+				if _, err := io.WriteString(writer, ","); err != nil {
+					return count, err
+				}
+			}
+			s.presentTask(&page[i])
+			if err := encoder.Encode(page[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		flush(writer)
+
+		var err error
+		if page, err = next(); err != nil {
+			return count, err
+		}
+	}
+	_, err := io.WriteString(writer, "]\n")
+	return count, err
+}
+
+// streamCSV writes the tasks of page and of the pages next returns as
+// CSV rows, under a header row naming the columns, flushing after every
+// page, and returns how many it wrote. Applications are separated by
+// semicolons.
+func streamCSV(writer http.ResponseWriter, page []Task, next func() ([]Task, error)) (int, error) {
+	writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+
+	rows := csv.NewWriter(writer)
+	rows.Write([]string{"id", "description", "note", "applications", "project_id"})
+	count := 0
+	for len(page) > 0 {
+		for _, task := range page {
+			rows.Write([]string{task.ID, task.Description, task.Note, strings.Join(task.Applications, ";"), task.ProjectID})
+			count++
+		}
+		rows.Flush()
+		if err := rows.Error(); err != nil {
+			return count, err
+		}
+		flush(writer)
+
+		var err error
+		if page, err = next(); err != nil {
+			return count, err
+		}
+	}
+	rows.Flush()
+	return count, rows.Error()
+}
+
+// renderMarkdown renders every task, in task ID order, with
+// markdownTemplate and returns the result along with the number of
+// tasks.
+func (s *Server) renderMarkdown(ctx context.Context) ([]byte, int, error) {
+	tasks, err := s.store.GetAll(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+
+	var response bytes.Buffer
+	if err = markdownTemplate.Execute(&response, tasks); err != nil {
+		return nil, 0, err
+	}
+	return response.Bytes(), len(tasks), nil
+}
+
+// exportJobType is the type of the jobs exportWorker processes.
+const exportJobType = "export"
+
+// ExportResult is the result of an export job.
+type ExportResult struct {
+	Format  string `json:"format"`
+	Count   int    `json:"count"`
+	Content string `json:"content"`
+}
+
+// exportWorker renders the tasks in the background, for exports too
+// large to wait for. Its payload is {"format":"md"}, the only format.
+type exportWorker struct {
+	server *Server
+}
+
+func (worker exportWorker) Process(ctx context.Context, job Job) error {
+	var payload struct {
+		Format string `json:"format"`
+	}
+	if len(job.Payload) > 0 {
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return err
+		}
+	}
+	if payload.Format != "md" {
+		return fmt.Errorf("unsupported export format %q, expected md", payload.Format)
+	}
+
+	content, count, err := worker.server.renderMarkdown(ctx)
+	if err != nil {
+		return err
+	}
+	return worker.server.runner.Report(ctx, job.ID, 100, ExportResult{Format: payload.Format, Count: count, Content: string(content)})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// update rewrites the golden files with the current output:
+//
+//	go test -run TestExportMarkdown -update
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares got with the named file in testdata.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	response := serve(s, http.MethodGet, "/tasks/export?format=md", "")
+
+	assertStatus(t, response, http.StatusOK)
+	if got := response.Header().Get("Content-Type"); got != "text/markdown; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	assertGolden(t, "export.md", response.Body.Bytes())
+}
+
+func TestExportMarkdownEmpty(t *testing.T) {
+	s, _ := newTestServer(t)
+	response := serve(s, http.MethodGet, "/tasks/export?format=md", "")
+
+	assertStatus(t, response, http.StatusOK)
+	if got := response.Body.String(); got != "# Tasks\n" {
+		t.Errorf("body = %q, want only the heading", got)
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	s, _ := newTestServer(t)
+	assertStatus(t, serve(s, http.MethodGet, "/tasks/export?format=pdf", ""), http.StatusBadRequest)
+}
+
+// exportTestTasks returns count tasks, more than fit in one page.
+func exportTestTasks(count int) []Task {
+	tasks := make([]Task, count)
+	for i := range tasks {
+		id := fmt.Sprintf("%04d", i)
+		tasks[i] = Task{ID: id, Description: "Задача " + id, Applications: []string{"git"}, CreatedAt: testNow}
+	}
+	return tasks
+}
+
+// flushRecorder is a ResponseRecorder that keeps what had been written
+// at every Flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	chunks []string
+}
+
+func (recorder *flushRecorder) Flush() {
+	recorder.chunks = append(recorder.chunks, recorder.Body.String())
+	recorder.ResponseRecorder.Flush()
+}
+
+func TestExportStreamsInChunks(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		wrap   bool
+		// debug checks the responses against their schemas.
+		debug bool
+	}{
+		{format: "json"},
+		{format: "csv"},
+		// CSV is never wrapped, so it is passed through once flushed.
+		{format: "csv", wrap: true},
+		{format: "json", debug: true},
+	} {
+		t.Run(fmt.Sprintf("%s wrapped %v debug %v", tt.format, tt.wrap, tt.debug), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.WrapResponses = tt.wrap
+			cfg.Debug = tt.debug
+			s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage(exportTestTasks(250)...))
+			recorder := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+			s.router.ServeHTTP(recorder, newRequest(http.MethodGet, "/tasks/export?format="+tt.format, ""))
+			assertStatus(t, recorder.ResponseRecorder, http.StatusOK)
+
+			// One flush per page of 100 tasks.
+			if len(recorder.chunks) != 3 {
+				t.Fatalf("%d flushes, want 3", len(recorder.chunks))
+			}
+			for i, chunk := range recorder.chunks {
+				want := fmt.Sprintf("%04d", 100*(i+1)-1)
+				if i == 2 {
+					want = "0249"
+				}
+				if !strings.Contains(chunk, want) || strings.Contains(chunk, fmt.Sprintf("%04d", 100*(i+1))) {
+					t.Errorf("flush %d does not end with task %s", i+1, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExportJSONOverHTTP(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), NewInMemoryStorage(exportTestTasks(250)...))
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/tasks/export?format=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if !reflect.DeepEqual(response.TransferEncoding, []string{"chunked"}) {
+		t.Errorf("Transfer-Encoding = %q, want chunked", response.TransferEncoding)
+	}
+	var tasks []Task
+	if err := json.NewDecoder(response.Body).Decode(&tasks); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 250 || tasks[0].ID != "0000" || tasks[249].ID != "0249" {
+		t.Errorf("exported %d tasks, from %s to %s", len(tasks), tasks[0].ID, tasks[len(tasks)-1].ID)
+	}
+}
+
+func TestExportWrappedJSON(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.WrapResponses = true
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage(exportTestTasks(250)...))
+	response := serve(s, http.MethodGet, "/tasks/export?format=json", "")
+	assertStatus(t, response, http.StatusOK)
+
+	// An envelope needs the whole array, so the export is buffered.
+	var envelope struct {
+		Data []Task `json:"data"`
+	}
+	decodeJSON(t, response.Body.Bytes(), &envelope)
+	if len(envelope.Data) != 250 {
+		t.Errorf("%d tasks in the envelope, want 250", len(envelope.Data))
+	}
+}
+
+func TestExportWithoutPager(t *testing.T) {
+	tasks := exportTestTasks(150)
+	store := &MockStorage{GetAllFn: func(context.Context, Filter) ([]Task, error) { return tasks, nil }}
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), store)
+	response := serve(s, http.MethodGet, "/tasks/export?format=csv", "")
+	assertStatus(t, response, http.StatusOK)
+	if lines := strings.Count(response.Body.String(), "\n"); lines != 151 {
+		t.Errorf("%d CSV lines, want a header and 150 rows", lines)
+	}
+	if calls := len(store.CallsTo("GetAll")); calls != 1 {
+		t.Errorf("%d GetAll calls, want 1", calls)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// taskFieldIndex maps the JSON name of every Task field to its index
+// in the struct, as read from the json struct tags.
+var taskFieldIndex = func() map[string]int {
+	taskType := reflect.TypeOf(Task{})
+	index := make(map[string]int, taskType.NumField())
+	for i := 0; i < taskType.NumField(); i++ {
+		name, _, _ := strings.Cut(taskType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			index[name] = i
+		}
+	}
+	return index
+}()
+
+// ParseFields parses a comma-separated list of Task JSON field names,
+// as accepted by the fields query parameter. It rejects unknown names.
+func ParseFields(param string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if _, ok := taskFieldIndex[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// sparseTask serializes only the listed fields of a task.
+type sparseTask struct {
+	task   Task
+	fields []string
+}
+
+func (sparse sparseTask) MarshalJSON() ([]byte, error) {
+	value := reflect.ValueOf(sparse.task)
+	object := make(map[string]interface{}, len(sparse.fields))
+	for _, field := range sparse.fields {
+		object[field] = value.Field(taskFieldIndex[field]).Interface()
+	}
+	return json.Marshal(object)
+}
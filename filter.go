@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The filter query parameter of GET /tasks accepts a small boolean
+// expression language:
+//
+//	expr      = and_expr { "OR" and_expr }
+//	and_expr  = primary { "AND" primary }
+//	primary   = "(" expr ")" | condition
+//	condition = field ":" [ operator ] value
+//	operator  = "=" | ">" | ">=" | "<" | "<="
+//	value     = word | '"' { any character except '"' } '"'
+//
+// AND binds tighter than OR, keywords are case-insensitive. Supported
+// fields are id, description, note, application and project, which match when
+// the field (or any of the task's applications) contains the value,
+// ignoring case, and created_at, which is compared as a time. A
+// created_at value is either an RFC 3339 timestamp or a 2006-01-02
+// date; a date stands for the whole UTC day, so created_at:2024-01-15
+// matches any task created on that day and created_at:>2024-01-15
+// matches tasks created after it. Example:
+//
+//	(description:final OR note:"free day") AND created_at:>=2024-01-01
+
+// Filter decides whether a task belongs to a filtered result set.
+type Filter interface {
+	Match(task Task) bool
+}
+
+// FilterSyntaxError describes why a filter expression could not be
+// parsed. Position is the 1-based character offset of the offending
+// token in the expression.
+type FilterSyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (err *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("filter syntax error at position %d: %s", err.Position, err.Message)
+}
+
+type andFilter struct {
+	left, right Filter
+}
+
+func (filter andFilter) Match(task Task) bool {
+	return filter.left.Match(task) && filter.right.Match(task)
+}
+
+type orFilter struct {
+	left, right Filter
+}
+
+func (filter orFilter) Match(task Task) bool {
+	return filter.left.Match(task) || filter.right.Match(task)
+}
+
+// textFilter matches when one of the values extracted from the task
+// contains the wanted text, ignoring case.
+type textFilter struct {
+	values func(task Task) []string
+	text   string
+}
+
+func (filter textFilter) Match(task Task) bool {
+	for _, value := range filter.values(task) {
+		if strings.Contains(strings.ToLower(value), filter.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeFilter compares a task timestamp with the half-open interval
+// [from, to) denoted by the filter value.
+type timeFilter struct {
+	value    func(task Task) time.Time
+	operator string
+	from, to time.Time
+}
+
+func (filter timeFilter) Match(task Task) bool {
+	value := filter.value(task)
+	switch filter.operator {
+	case ">":
+		return !value.Before(filter.to)
+	case ">=":
+		return !value.Before(filter.from)
+	case "<":
+		return value.Before(filter.from)
+	case "<=":
+		return value.Before(filter.to)
+	default:
+		return !value.Before(filter.from) && value.Before(filter.to)
+	}
+}
+
+var textFilterFields = map[string]func(task Task) []string{
+	"id":          func(task Task) []string { return []string{task.ID} },
+	"description": func(task Task) []string { return []string{task.Description} },
+	"note":        func(task Task) []string { return []string{task.Note} },
+	"application": func(task Task) []string { return task.Applications },
+	"project":     func(task Task) []string { return []string{task.ProjectID} },
+}
+
+var timeFilterFields = map[string]func(task Task) time.Time{
+	"created_at": func(task Task) time.Time { return task.CreatedAt },
+}
+
+type filterTokenKind int
+
+const (
+	filterTokenEnd filterTokenKind = iota
+	filterTokenOpen
+	filterTokenClose
+	filterTokenAnd
+	filterTokenOr
+	filterTokenCondition
+)
+
+type filterToken struct {
+	kind     filterTokenKind
+	text     string
+	position int
+}
+
+// tokenizeFilter splits the expression into parentheses, keywords and
+// conditions. Spaces inside quoted condition values are preserved.
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '(':
+			tokens = append(tokens, filterToken{kind: filterTokenOpen, text: "(", position: i + 1})
+			i++
+		case runes[i] == ')':
+			tokens = append(tokens, filterToken{kind: filterTokenClose, text: ")", position: i + 1})
+			i++
+		default:
+			start := i
+			inQuotes := false
+			for i < len(runes) && (inQuotes || !(unicode.IsSpace(runes[i]) || runes[i] == '(' || runes[i] == ')')) {
+				if runes[i] == '"' {
+					inQuotes = !inQuotes
+				}
+				i++
+			}
+			if inQuotes {
+				return nil, &FilterSyntaxError{Position: start + 1, Message: "unterminated quoted value"}
+			}
+
+			token := filterToken{kind: filterTokenCondition, text: string(runes[start:i]), position: start + 1}
+			switch strings.ToUpper(token.text) {
+			case "AND":
+				token.kind = filterTokenAnd
+			case "OR":
+				token.kind = filterTokenOr
+			}
+			tokens = append(tokens, token)
+		}
+	}
+
+	return append(tokens, filterToken{kind: filterTokenEnd, position: len(runes) + 1}), nil
+}
+
+// filterParser is a recursive-descent parser over the token stream
+// produced by tokenizeFilter.
+type filterParser struct {
+	tokens []filterToken
+	next   int
+}
+
+// ParseFilter parses a filter expression into a Filter tree. On
+// failure it returns a *FilterSyntaxError.
+func ParseFilter(expression string) (Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &filterParser{tokens: tokens}
+	filter, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := parser.peek(); token.kind != filterTokenEnd {
+		return nil, &FilterSyntaxError{Position: token.position, Message: fmt.Sprintf("unexpected %q", token.text)}
+	}
+	return filter, nil
+}
+
+func (parser *filterParser) peek() filterToken {
+	return parser.tokens[parser.next]
+}
+
+func (parser *filterParser) advance() filterToken {
+	token := parser.tokens[parser.next]
+	if token.kind != filterTokenEnd {
+		parser.next++
+	}
+	return token
+}
+
+func (parser *filterParser) parseOr() (Filter, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.peek().kind == filterTokenOr {
+		parser.advance()
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (parser *filterParser) parseAnd() (Filter, error) {
+	left, err := parser.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.peek().kind == filterTokenAnd {
+		parser.advance()
+		right, err := parser.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (parser *filterParser) parsePrimary() (Filter, error) {
+	token := parser.advance()
+	switch token.kind {
+	case filterTokenOpen:
+		filter, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := parser.advance(); closing.kind != filterTokenClose {
+			return nil, &FilterSyntaxError{Position: closing.position, Message: "expected \")\""}
+		}
+		return filter, nil
+	case filterTokenCondition:
+		return parseCondition(token)
+	case filterTokenEnd:
+		return nil, &FilterSyntaxError{Position: token.position, Message: "unexpected end of expression"}
+	default:
+		return nil, &FilterSyntaxError{Position: token.position, Message: fmt.Sprintf("unexpected %q", token.text)}
+	}
+}
+
+// parseCondition turns a field:[operator]value token into a Filter.
+func parseCondition(token filterToken) (Filter, error) {
+	field, rest, found := strings.Cut(token.text, ":")
+	if !found {
+		return nil, &FilterSyntaxError{Position: token.position, Message: fmt.Sprintf("expected field:value, got %q", token.text)}
+	}
+
+	operator := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(rest, candidate) {
+			operator = candidate
+			rest = rest[len(candidate):]
+			break
+		}
+	}
+
+	value := rest
+	if strings.HasPrefix(value, `"`) {
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return nil, &FilterSyntaxError{Position: token.position, Message: "malformed quoted value"}
+		}
+		value = value[1 : len(value)-1]
+	}
+	if value == "" {
+		return nil, &FilterSyntaxError{Position: token.position, Message: fmt.Sprintf("missing value for field %q", field)}
+	}
+
+	field = strings.ToLower(field)
+	if values, ok := textFilterFields[field]; ok {
+		if operator != "" && operator != "=" {
+			return nil, &FilterSyntaxError{Position: token.position, Message: fmt.Sprintf("operator %q is not supported for field %q", operator, field)}
+		}
+		return textFilter{values: values, text: strings.ToLower(value)}, nil
+	}
+
+	if timestamp, ok := timeFilterFields[field]; ok {
+		if from, err := time.Parse(time.RFC3339, value); err == nil {
+			return timeFilter{value: timestamp, operator: operator, from: from, to: from.Add(time.Nanosecond)}, nil
+		}
+		if from, err := time.Parse(time.DateOnly, value); err == nil {
+			return timeFilter{value: timestamp, operator: operator, from: from, to: from.AddDate(0, 0, 1)}, nil
+		}
+		return nil, &FilterSyntaxError{Position: token.position, Message: fmt.Sprintf("invalid time %q for field %q", value, field)}
+	}
+
+	return nil, &FilterSyntaxError{Position: token.position, Message: fmt.Sprintf("unknown field %q", field)}
+}
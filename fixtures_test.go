@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixturesFile holds the tasks tests start from: plain tasks, Unicode
+// text, every SLA status at testNow and a task with a TTL.
+var fixturesFile = filepath.Join("testdata", "tasks.json")
+
+// fixtureTasks returns the tasks of fixturesFile. It reads the file on
+// every call, so no test sees the changes another made to the tasks.
+func fixtureTasks(t testing.TB) []Task {
+	t.Helper()
+	data, err := os.ReadFile(fixturesFile)
+	if err != nil {
+		t.Fatalf("reading the fixtures: %v", err)
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		t.Fatalf("decoding %s: %v", fixturesFile, err)
+	}
+	return tasks
+}
+
+// LoadFixtures returns a fresh InMemoryStorage holding the tasks of
+// fixturesFile.
+func LoadFixtures(t *testing.T) Storage {
+	t.Helper()
+	return NewInMemoryStorage(fixtureTasks(t)...)
+}
+
+// fixtureTask returns the fixture with the given ID.
+func fixtureTask(t testing.TB, id string) Task {
+	t.Helper()
+	for _, task := range fixtureTasks(t) {
+		if task.ID == id {
+			return task
+		}
+	}
+	t.Fatalf("no fixture has ID %q", id)
+	return Task{}
+}
+
+func TestFixtures(t *testing.T) {
+	tasks := fixtureTasks(t)
+	seen := make(map[string]bool, len(tasks))
+	statuses := make(map[string]bool)
+	for _, task := range tasks {
+		if task.ID == "" || task.Description == "" || task.Applications == nil || task.CreatedAt.IsZero() {
+			t.Errorf("fixture %+v misses a required field", task)
+		}
+		if seen[task.ID] {
+			t.Errorf("fixture ID %q is used twice", task.ID)
+		}
+		seen[task.ID] = true
+		if isExpired(task, testNow) {
+			t.Errorf("fixture %q has expired at testNow", task.ID)
+		}
+		statuses[slaStatus(task, testNow)] = true
+	}
+	for _, status := range []string{SLAOnTrack, SLAAtRisk, SLABreached} {
+		if !statuses[status] {
+			t.Errorf("no fixture is %s at testNow", status)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// FeatureFlags switches optional behavior on and off while the server
+// is running.
+type FeatureFlags struct {
+	// EnableHTMLSanitization strips HTML from task text fields on create.
+	EnableHTMLSanitization bool `json:"enable_html_sanitization"`
+	// EnableTaskQueue puts created tasks on the task queue, if one is configured.
+	EnableTaskQueue bool `json:"enable_task_queue"`
+	// EnableGeoIP tags requests with the client's country, if a GeoIP
+	// database is configured.
+	EnableGeoIP bool `json:"enable_geoip"`
+}
+
+// DefaultFeatureFlags returns the flags in effect when nothing
+// overrides them: all of them on.
+func DefaultFeatureFlags() FeatureFlags {
+	return FeatureFlags{
+		EnableHTMLSanitization: true,
+		EnableTaskQueue:        true,
+		EnableGeoIP:            true,
+	}
+}
+
+// featureFlagVariables maps the variable naming each flag to the flag.
+var featureFlagVariables = map[string]func(flags *FeatureFlags) *bool{
+	"FEATURE_HTML_SANITIZATION": func(flags *FeatureFlags) *bool { return &flags.EnableHTMLSanitization },
+	"FEATURE_TASK_QUEUE":        func(flags *FeatureFlags) *bool { return &flags.EnableTaskQueue },
+	"FEATURE_GEOIP":             func(flags *FeatureFlags) *bool { return &flags.EnableGeoIP },
+}
+
+// LoadFeatureFlags reads the flags from the FEATURE_* environment
+// variables. If path is not empty, the KEY=VALUE lines of that file
+// override the environment; blank lines and lines starting with #
+// are ignored. The file makes hot reloading useful, since the
+// environment of a running process cannot be changed from outside.
+func LoadFeatureFlags(path string) (FeatureFlags, error) {
+	values := make(map[string]string)
+	for name := range featureFlagVariables {
+		if value, ok := os.LookupEnv(name); ok {
+			values[name] = value
+		}
+	}
+
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return FeatureFlags{}, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			entry := strings.TrimSpace(scanner.Text())
+			if entry == "" || strings.HasPrefix(entry, "#") {
+				continue
+			}
+			name, value, found := strings.Cut(entry, "=")
+			if !found {
+				return FeatureFlags{}, fmt.Errorf("%s:%d: expected NAME=VALUE", path, line)
+			}
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		if err = scanner.Err(); err != nil {
+			return FeatureFlags{}, err
+		}
+	}
+
+	flags := DefaultFeatureFlags()
+	for name, value := range values {
+		flag, ok := featureFlagVariables[name]
+		if !ok {
+			return FeatureFlags{}, fmt.Errorf("unknown feature flag %s", name)
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return FeatureFlags{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		*flag(&flags) = enabled
+	}
+	return flags, nil
+}
+
+// featureFlags returns the flags currently in effect.
+func (s *Server) featureFlags() FeatureFlags {
+	return *s.flags.Load()
+}
+
+// reloadFeatureFlagsOnHangup reloads the feature flags whenever the
+// process receives SIGHUP, until ctx is cancelled. Flags that fail to
+// load leave the previous ones in effect.
+func (s *Server) reloadFeatureFlagsOnHangup(ctx context.Context) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			flags, err := LoadFeatureFlags(s.config.FeatureFlagsFile)
+			if err != nil {
+				s.logger.Error("Не удалось перечитать флаги функций", "error", err)
+				continue
+			}
+			s.flags.Store(&flags)
+			s.logger.Info("Флаги функций перечитаны", "flags", flags)
+		}
+	}
+}
+
+// getAdminFlags writes the feature flags currently in effect in JSON
+// format to the provided http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - req: The http.Request received from the client. This parameter is ignored in this function.
+func (s *Server) getAdminFlags(writer http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(writer, http.StatusOK, s.featureFlags())
+}
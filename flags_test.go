@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeFlagsFile writes a feature flags file in a temporary directory
+// and returns its path.
+func writeFlagsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "flags.env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		file    string
+		want    FeatureFlags
+		wantErr bool
+	}{
+		{name: "defaults", want: DefaultFeatureFlags()},
+		{
+			name: "environment", env: map[string]string{"FEATURE_TASK_QUEUE": "false", "FEATURE_GEOIP": "0"},
+			want: FeatureFlags{EnableHTMLSanitization: true},
+		},
+		{
+			name: "file overrides the environment", env: map[string]string{"FEATURE_TASK_QUEUE": "false"},
+			file: "# comment\n\nFEATURE_TASK_QUEUE = true\nFEATURE_HTML_SANITIZATION=false\n",
+			want: FeatureFlags{EnableTaskQueue: true, EnableGeoIP: true},
+		},
+		{name: "invalid value", env: map[string]string{"FEATURE_GEOIP": "maybe"}, wantErr: true},
+		{name: "unknown flag", file: "FEATURE_WEBHOOKS=true\n", wantErr: true},
+		{name: "malformed line", file: "FEATURE_GEOIP\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name := range featureFlagVariables {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			var path string
+			if tt.file != "" {
+				path = writeFlagsFile(t, tt.file)
+			}
+
+			flags, err := LoadFeatureFlags(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFeatureFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && flags != tt.want {
+				t.Errorf("LoadFeatureFlags() = %+v, want %+v", flags, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagGating(t *testing.T) {
+	body := `{"id":"new","description":"<b>Жирная</b> задача","note":"","applications":[]}`
+	tests := []struct {
+		name      string
+		flags     FeatureFlags
+		wantText  string
+		wantDepth int
+	}{
+		{name: "all on", flags: DefaultFeatureFlags(), wantText: "Жирная задача", wantDepth: 1},
+		{name: "sanitization off", flags: FeatureFlags{EnableTaskQueue: true}, wantText: "<b>Жирная</b> задача", wantDepth: 1},
+		{name: "task queue off", flags: FeatureFlags{EnableHTMLSanitization: true}, wantText: "Жирная задача", wantDepth: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.TaskQueueSize = 10
+			cfg.FeatureFlags = tt.flags
+			s, _ := newTestServerWithConfig(t, cfg, LoadFixtures(t))
+
+			assertStatus(t, serve(s, http.MethodPost, "/tasks", body), http.StatusCreated)
+			task, err := s.store.Get(context.Background(), "new")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if task.Description != tt.wantText {
+				t.Errorf("description = %q, want %q", task.Description, tt.wantText)
+			}
+			if depth := s.queue.Stats().Depth; depth != tt.wantDepth {
+				t.Errorf("queue depth = %d, want %d", depth, tt.wantDepth)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagsApplyWhileRunning(t *testing.T) {
+	s, _ := newTestServer(t)
+	flags := DefaultFeatureFlags()
+	flags.EnableHTMLSanitization = false
+	s.flags.Store(&flags)
+
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", `{"id":"1","description":"<i>x</i>","note":"","applications":[]}`), http.StatusCreated)
+	if task, _ := s.store.Get(context.Background(), "1"); task.Description != "<i>x</i>" {
+		t.Errorf("description = %q, want the HTML kept", task.Description)
+	}
+}
+
+func TestReloadFeatureFlagsOnHangup(t *testing.T) {
+	for name := range featureFlagVariables {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	path := writeFlagsFile(t, "FEATURE_GEOIP=true\n")
+	cfg := DefaultConfig()
+	cfg.FeatureFlagsFile = path
+	cfg.AdminSecret = "s3cret"
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage())
+
+	// Catch SIGHUP in the test too, so that a signal sent before
+	// reloadFeatureFlagsOnHangup is listening does not end the process.
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, syscall.SIGHUP)
+	defer signal.Stop(caught)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.reloadFeatureFlagsOnHangup(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if err := os.WriteFile(path, []byte("FEATURE_GEOIP=false\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Keep signalling until the flags change, since the first signals
+	// may arrive before reloadFeatureFlagsOnHangup listens.
+	deadline := time.Now().Add(5 * time.Second)
+	for s.featureFlags().EnableGeoIP {
+		if time.Now().After(deadline) {
+			t.Fatal("the flags were not reloaded on SIGHUP")
+		}
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	response := serveAdmin(s, http.MethodGet, "/admin/flags", "")
+	assertStatus(t, response, http.StatusOK)
+	var reported FeatureFlags
+	decodeJSON(t, response.Body.Bytes(), &reported)
+	if reported != s.featureFlags() || reported.EnableGeoIP {
+		t.Errorf("GET /admin/flags = %+v, want %+v", reported, s.featureFlags())
+	}
+}
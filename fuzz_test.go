@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// FuzzPostTask sends arbitrary bodies to POST /tasks. Whatever the body,
+// the handler must not panic or fail with a 5xx: a body that is not
+// JSON is a 400 Bad Request, one that does not match the task schema a
+// 422 Unprocessable Entity, and a task that clashes with a stored one a
+// 409 Conflict. Run it with
+//
+//	go test -run FuzzPostTask -fuzz FuzzPostTask
+func FuzzPostTask(f *testing.F) {
+	seeds := []string{
+		`{"id":"3","description":"Сдать задание","note":"","applications":["git"]}`,
+		`{"id":"4","description":"<b>Жирная</b> задача","note":"<script>x</script>","applications":[]}`,
+		`{"id":"5","description":"С TTL","note":"","applications":[],"ttl":"1h"}`,
+		`{"id":"1","description":"Занятый ID","note":"","applications":[]}`,
+		`{"id":"6","description":"Сделать финальное задание","note":"","applications":[]}`,
+		`{"id":null,"description":null,"note":null,"applications":null}`,
+		`{"id":3,"description":true,"note":[],"applications":"git"}`,
+		`{"id":"7","description":"x","note":"","applications":[[[[[[[[["deep"]]]]]]]]]}`,
+		`{"id":"8","description":"x","note":"","applications":[],"project_id":"missing"}`,
+		`{"id":"9","description":"x","note":"","applications":[],"ttl":"-1s"}`,
+		`{"id":"\u0000","description":"\ud800","note":"","applications":[]}`,
+		`{"id":"10","description":"x","note":"","applications":[],"id":"11"}`,
+		`{"id":`,
+		`[1, 2]`,
+		`"task"`,
+		`null`,
+		``,
+		`{}`,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, body string) {
+		s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+		response := serve(s, http.MethodPost, "/tasks", body)
+
+		switch response.Code {
+		case http.StatusCreated:
+			if !json.Valid([]byte(body)) {
+				t.Fatalf("created a task from invalid JSON %q", body)
+			}
+		case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusConflict:
+		default:
+			t.Fatalf("status = %d for body %q; response: %s", response.Code, body, response.Body.String())
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// CountryResolver looks up the country of an IP address, as
+// *geoip2.Reader does.
+type CountryResolver interface {
+	Country(ip net.IP) (*geoip2.Country, error)
+}
+
+type countryCodeKey struct{}
+
+// CountryCodeFromContext returns the ISO 3166-1 country code the
+// request was resolved to, or "" if it was not resolved.
+func CountryCodeFromContext(ctx context.Context) string {
+	code, _ := ctx.Value(countryCodeKey{}).(string)
+	return code
+}
+
+// geolocate resolves the client IP to a country with the GeoIP
+// database and attaches the ISO country code to the request context,
+// for collectAnalytics to log and count. Private and loopback
+// addresses are not looked up; they and the addresses the database
+// does not know are passed on without a code, as is everything while
+// the EnableGeoIP feature flag is off.
+func (s *Server) geolocate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ip := net.ParseIP(clientIP(request))
+		if ip == nil || ip.IsPrivate() || ip.IsLoopback() || s.geoip == nil || !s.featureFlags().EnableGeoIP {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		country, err := s.geoip.Country(ip)
+		if err != nil {
+			s.logger.Warn("Не удалось определить страну клиента", "ip", ip.String(), "error", err)
+			next.ServeHTTP(writer, request)
+			return
+		}
+		if country.Country.IsoCode == "" {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		next.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), countryCodeKey{}, country.Country.IsoCode)))
+	})
+}
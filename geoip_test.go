@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// fakeCountryResolver resolves the IPs in countries and fails for the
+// others, recording every lookup.
+type fakeCountryResolver struct {
+	countries map[string]string
+	lookups   []string
+}
+
+func (resolver *fakeCountryResolver) Country(ip net.IP) (*geoip2.Country, error) {
+	resolver.lookups = append(resolver.lookups, ip.String())
+	code, ok := resolver.countries[ip.String()]
+	if !ok {
+		return nil, errors.New("address not found")
+	}
+	country := &geoip2.Country{}
+	country.Country.IsoCode = code
+	return country, nil
+}
+
+// newGeoIPTestServer creates a test server geolocating with resolver,
+// which may be nil, and the EnableGeoIP feature flag as given.
+func newGeoIPTestServer(t *testing.T, resolver CountryResolver, enabled bool) *Server {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.GeoIPDB = "GeoLite2-Country.mmdb"
+	cfg.FeatureFlags.EnableGeoIP = enabled
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage())
+	if resolver != nil {
+		s.geoip = resolver
+	}
+	return s
+}
+
+func TestGeolocate(t *testing.T) {
+	tests := []struct {
+		name        string
+		remoteAddr  string
+		nilResolver bool
+		disabled    bool
+		wantCountry string
+		wantLookups []string
+	}{
+		{name: "resolved", remoteAddr: "203.0.113.7:1234", wantCountry: "RU", wantLookups: []string{"203.0.113.7"}},
+		{name: "unknown to the database", remoteAddr: "198.51.100.1:1234", wantLookups: []string{"198.51.100.1"}},
+		{name: "without a country", remoteAddr: "192.0.2.1:1234", wantLookups: []string{"192.0.2.1"}},
+		{name: "private", remoteAddr: "10.1.2.3:1234"},
+		{name: "private IPv6", remoteAddr: "[fd00::1]:1234"},
+		{name: "loopback", remoteAddr: "127.0.0.1:1234"},
+		{name: "not an IP", remoteAddr: "pipe"},
+		{name: "no database", remoteAddr: "203.0.113.7:1234", nilResolver: true},
+		{name: "flag off", remoteAddr: "203.0.113.7:1234", disabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeCountryResolver{countries: map[string]string{"203.0.113.7": "RU", "192.0.2.1": ""}}
+			var s *Server
+			if tt.nilResolver {
+				s = newGeoIPTestServer(t, nil, !tt.disabled)
+			} else {
+				s = newGeoIPTestServer(t, resolver, !tt.disabled)
+			}
+
+			var country string
+			handler := s.geolocate(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+				country = CountryCodeFromContext(request.Context())
+			}))
+			request := newRequest(http.MethodGet, "/tasks", "")
+			request.RemoteAddr = tt.remoteAddr
+			handler.ServeHTTP(nil, request)
+
+			if country != tt.wantCountry {
+				t.Errorf("country code = %q, want %q", country, tt.wantCountry)
+			}
+			if !reflect.DeepEqual(resolver.lookups, tt.wantLookups) {
+				t.Errorf("lookups = %q, want %q", resolver.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestGeolocatedAnalytics(t *testing.T) {
+	resolver := &fakeCountryResolver{countries: map[string]string{"203.0.113.7": "RU", "203.0.113.8": "DE"}}
+	s := newGeoIPTestServer(t, resolver, true)
+	for _, remoteAddr := range []string{"203.0.113.7:1", "203.0.113.7:2", "203.0.113.8:1", "10.0.0.1:1"} {
+		request := newRequest(http.MethodGet, "/tasks", "")
+		request.RemoteAddr = remoteAddr
+		assertStatus(t, serveRequest(s, request), http.StatusOK)
+	}
+
+	summaries := s.analytics.Summary(testNow.Add(-1))
+	if len(summaries) != 1 || summaries[0].Count != 4 {
+		t.Fatalf("summaries = %+v, want 4 requests to one route", summaries)
+	}
+	if want := map[string]int{"RU": 2, "DE": 1}; !reflect.DeepEqual(summaries[0].Countries, want) {
+		t.Errorf("countries = %v, want %v", summaries[0].Countries, want)
+	}
+}
@@ -1,5 +1,19 @@
 module github.com/Yandex-Practicum/go-rest-api-homework
 
-go 1.20
+go 1.21
 
-require github.com/go-chi/chi/v5 v5.0.10 // indirect
+require github.com/go-chi/chi/v5 v5.0.10
+
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/oschwald/geoip2-golang v1.9.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+	golang.org/x/net v0.21.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/oschwald/maxminddb-golang v1.11.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
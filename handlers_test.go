@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type handlerTest struct {
+	name            string
+	method          string
+	target          string
+	body            string
+	wantStatus      int
+	wantContentType string
+	// check, if set, inspects the response body and the storage.
+	check func(t *testing.T, s *Server, body []byte)
+}
+
+func runHandlerTests(t *testing.T, tests []handlerTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+			response := serve(s, tt.method, tt.target, tt.body)
+
+			assertStatus(t, response, tt.wantStatus)
+			if got := response.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantContentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if tt.check != nil {
+				tt.check(t, s, response.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestGetTasksHandler(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{
+			name: "all tasks keyed by ID", method: http.MethodGet, target: "/tasks",
+			wantStatus: http.StatusOK, wantContentType: "application/json",
+			check: func(t *testing.T, _ *Server, body []byte) {
+				var tasks map[string]Task
+				decodeJSON(t, body, &tasks)
+				if len(tasks) != len(fixtureTasks(t)) || tasks["unicode"].Description != "Ревью кода 👀 — café, naïve, 日本語" {
+					t.Errorf("tasks = %+v", tasks)
+				}
+			},
+		},
+		{
+			name: "filtered", method: http.MethodGet, target: "/tasks?filter=" + url.QueryEscape(`description:"Протестировать"`),
+			wantStatus: http.StatusOK, wantContentType: "application/json",
+			check: func(t *testing.T, _ *Server, body []byte) {
+				var tasks map[string]Task
+				decodeJSON(t, body, &tasks)
+				if _, ok := tasks["2"]; len(tasks) != 1 || !ok {
+					t.Errorf("tasks = %+v, want only task 2", tasks)
+				}
+			},
+		},
+		{
+			name: "sorted array", method: http.MethodGet, target: "/tasks?sort=-id",
+			wantStatus: http.StatusOK, wantContentType: "application/json",
+			check: func(t *testing.T, _ *Server, body []byte) {
+				var tasks []Task
+				decodeJSON(t, body, &tasks)
+				want := "unicode,on-track,expiring,breached,at-risk,2,1"
+				if got := strings.Join(taskIDs(tasks), ","); got != want {
+					t.Errorf("order = %s, want %s", got, want)
+				}
+			},
+		},
+		{
+			name: "sparse fields", method: http.MethodGet, target: "/tasks?fields=id,note",
+			wantStatus: http.StatusOK, wantContentType: "application/json",
+			check: func(t *testing.T, _ *Server, body []byte) {
+				var tasks map[string]map[string]interface{}
+				decodeJSON(t, body, &tasks)
+				if len(tasks["1"]) != 2 || tasks["1"]["note"] != "Ура!" {
+					t.Errorf("task 1 = %v, want only id and note", tasks["1"])
+				}
+			},
+		},
+		{name: "malformed filter", method: http.MethodGet, target: "/tasks?filter=(((", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+		{name: "unknown sort field", method: http.MethodGet, target: "/tasks?sort=priority", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+		{name: "unknown field", method: http.MethodGet, target: "/tasks?fields=status", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+		{name: "malformed pinned", method: http.MethodGet, target: "/tasks?pinned=maybe", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+		{name: "unknown SLA status", method: http.MethodGet, target: "/tasks?sla_status=late", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+	})
+}
+
+func TestCountTasksHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantCount  string
+	}{
+		{name: "all tasks", target: "/tasks", wantStatus: http.StatusOK, wantCount: "7"},
+		{name: "filtered", target: "/tasks?filter=" + url.QueryEscape(`description:"Протестировать"`), wantStatus: http.StatusOK, wantCount: "1"},
+		{name: "SLA status", target: "/tasks?sla_status=breached", wantStatus: http.StatusOK, wantCount: "1"},
+		{name: "pinned", target: "/tasks?pinned=true", wantStatus: http.StatusOK, wantCount: "1"},
+		{name: "unpinned", target: "/tasks?pinned=false", wantStatus: http.StatusOK, wantCount: "6"},
+		{name: "malformed filter", target: "/tasks?filter=(((", wantStatus: http.StatusBadRequest},
+		{name: "malformed pinned", target: "/tasks?pinned=maybe", wantStatus: http.StatusBadRequest},
+		{name: "unknown SLA status", target: "/tasks?sla_status=late", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+			assertStatus(t, serve(s, http.MethodPost, "/tasks/2/pin", `{}`), http.StatusOK)
+
+			response := serve(s, http.MethodHead, tt.target, "")
+			assertStatus(t, response, tt.wantStatus)
+			if got := response.Header().Get("X-Total-Count"); got != tt.wantCount {
+				t.Errorf("X-Total-Count = %q, want %q", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestGetTaskHandler(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{
+			name: "existing task", method: http.MethodGet, target: "/tasks/1",
+			wantStatus: http.StatusOK, wantContentType: "application/json",
+			check: func(t *testing.T, _ *Server, body []byte) {
+				var task Task
+				decodeJSON(t, body, &task)
+				if task.ID != "1" || task.Note != "Ура!" || len(task.Applications) != 2 {
+					t.Errorf("task = %+v", task)
+				}
+			},
+		},
+		{
+			name: "sparse fields", method: http.MethodGet, target: "/tasks/2?fields=description",
+			wantStatus: http.StatusOK, wantContentType: "application/json",
+			check: func(t *testing.T, _ *Server, body []byte) {
+				if got := strings.TrimSpace(string(body)); got != `{"description":"Протестировать задание"}` {
+					t.Errorf("body = %s", got)
+				}
+			},
+		},
+		{name: "unknown ID", method: http.MethodGet, target: "/tasks/99", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+		{name: "unknown field", method: http.MethodGet, target: "/tasks/1?fields=status", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+	})
+}
+
+func TestPostTaskHandler(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{
+			name: "new task", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":"3","description":"Сдать задание","note":"","applications":["git"]}`,
+			wantStatus: http.StatusCreated, wantContentType: "application/json",
+			check: func(t *testing.T, s *Server, _ []byte) {
+				task, err := s.store.Get(context.Background(), "3")
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				if task.Description != "Сдать задание" || !task.CreatedAt.Equal(testNow) {
+					t.Errorf("stored task = %+v", task)
+				}
+			},
+		},
+		{
+			name: "HTML is stripped", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":"3","description":"<b>Сдать</b> задание","note":"","applications":[]}`,
+			wantStatus: http.StatusCreated, wantContentType: "application/json",
+			check: func(t *testing.T, s *Server, _ []byte) {
+				task, _ := s.store.Get(context.Background(), "3")
+				if task.Description != "Сдать задание" {
+					t.Errorf("description = %q", task.Description)
+				}
+			},
+		},
+		{
+			name: "only a script", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":"3","description":"<script>alert(1)</script>","note":"","applications":[]}`,
+			wantStatus: http.StatusUnprocessableEntity, wantContentType: "text/plain",
+		},
+		{
+			name: "only HTML", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":"3","description":"<b></b>","note":"","applications":[]}`,
+			wantStatus: http.StatusUnprocessableEntity, wantContentType: "text/plain",
+		},
+		{
+			name: "duplicate description", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":"3","description":"сделать ФИНАЛЬНОЕ задание","note":"","applications":[]}`,
+			wantStatus: http.StatusConflict, wantContentType: "application/json",
+			check: func(t *testing.T, _ *Server, body []byte) {
+				var conflict map[string]string
+				decodeJSON(t, body, &conflict)
+				if conflict["existing_id"] != "1" {
+					t.Errorf("existing_id = %q, want 1", conflict["existing_id"])
+				}
+			},
+		},
+		{
+			name: "duplicate description forced", method: http.MethodPost, target: "/tasks?force=true",
+			body:       `{"id":"3","description":"Сделать финальное задание","note":"","applications":[]}`,
+			wantStatus: http.StatusCreated, wantContentType: "application/json",
+		},
+		{
+			name: "taken ID", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":"1","description":"Другое","note":"","applications":[]}`,
+			wantStatus: http.StatusConflict, wantContentType: "text/plain",
+		},
+		{name: "malformed JSON", method: http.MethodPost, target: "/tasks", body: `{"id":`, wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+		{name: "not an object", method: http.MethodPost, target: "/tasks", body: `[1, 2]`, wantStatus: http.StatusUnprocessableEntity, wantContentType: "application/json"},
+		{
+			name: "missing description", method: http.MethodPost, target: "/tasks", body: `{"id":"3"}`,
+			wantStatus: http.StatusUnprocessableEntity, wantContentType: "application/json",
+		},
+		{
+			name: "wrong type", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":3,"description":"Сдать задание","note":"","applications":[]}`,
+			wantStatus: http.StatusUnprocessableEntity, wantContentType: "application/json",
+		},
+		{
+			name: "malformed force", method: http.MethodPost, target: "/tasks?force=maybe",
+			body:       `{"id":"3","description":"Сдать задание","note":"","applications":[]}`,
+			wantStatus: http.StatusBadRequest, wantContentType: "text/plain",
+		},
+		{
+			name: "unknown project", method: http.MethodPost, target: "/tasks",
+			body:       `{"id":"3","description":"Сдать задание","note":"","applications":[],"project_id":"p1"}`,
+			wantStatus: http.StatusUnprocessableEntity, wantContentType: "text/plain",
+		},
+	})
+}
+
+func TestDeleteTaskHandler(t *testing.T) {
+	runHandlerTests(t, []handlerTest{
+		{
+			name: "existing task", method: http.MethodDelete, target: "/tasks/1",
+			wantStatus: http.StatusOK, wantContentType: "application/json",
+			check: func(t *testing.T, s *Server, _ []byte) {
+				if _, err := s.store.Get(context.Background(), "1"); !errors.Is(err, ErrNotFound) {
+					t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{name: "unknown ID", method: http.MethodDelete, target: "/tasks/99", wantStatus: http.StatusBadRequest, wantContentType: "text/plain"},
+	})
+}
+
+func TestPostTaskEnqueues(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TaskQueueSize = 1
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage())
+
+	for i, want := range []int{http.StatusCreated, http.StatusCreated} {
+		body := `{"id":"` + strconv.Itoa(i) + `","description":"task ` + strconv.Itoa(i) + `","note":"","applications":[]}`
+		assertStatus(t, serve(s, http.MethodPost, "/tasks", body), want)
+	}
+
+	var stats QueueStats
+	response := serve(s, http.MethodGet, "/queue/stats", "")
+	assertStatus(t, response, http.StatusOK)
+	decodeJSON(t, response.Body.Bytes(), &stats)
+	if stats.Depth != 1 || stats.Capacity != 1 {
+		t.Errorf("queue stats = %+v, want the first task queued and the second dropped", stats)
+	}
+}
+
+// failingStorage returns a MockStorage whose task reads and writes
+// fail with err.
+func failingStorage(err error) *MockStorage {
+	return &MockStorage{
+		GetAllFn:       func(context.Context, Filter) ([]Task, error) { return nil, err },
+		GetFn:          func(context.Context, string) (Task, error) { return Task{}, err },
+		CreateFn:       func(context.Context, Task) error { return err },
+		CreateUniqueFn: func(context.Context, Task) error { return err },
+		DeleteFn:       func(context.Context, string) error { return err },
+	}
+}
+
+func TestHandlersReportStorageErrors(t *testing.T) {
+	failure := &StorageError{Op: "access", Err: errors.New("disk on fire")}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{name: "list", method: http.MethodGet, target: "/tasks"},
+		{name: "get", method: http.MethodGet, target: "/tasks/1"},
+		{name: "create", method: http.MethodPost, target: "/tasks", body: `{"id":"3","description":"Сдать задание","note":"","applications":[]}`},
+		{name: "delete", method: http.MethodDelete, target: "/tasks/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServerWithConfig(t, DefaultConfig(), failingStorage(failure))
+			response := serve(s, tt.method, tt.target, tt.body)
+
+			assertStatus(t, response, http.StatusInternalServerError)
+			if !strings.Contains(response.Body.String(), "disk on fire") {
+				t.Errorf("body = %q, want the storage error", response.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlersCallStorage(t *testing.T) {
+	stored := fixtureTask(t, "1")
+	newStorage := func() *MockStorage {
+		return &MockStorage{
+			GetAllFn:       func(context.Context, Filter) ([]Task, error) { return []Task{stored}, nil },
+			GetFn:          func(context.Context, string) (Task, error) { return stored, nil },
+			CreateFn:       func(context.Context, Task) error { return nil },
+			CreateUniqueFn: func(context.Context, Task) error { return nil },
+			DeleteFn:       func(context.Context, string) error { return nil },
+		}
+	}
+	newTask := `{"id":"3","description":"Сдать задание","note":"","applications":["git"]}`
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		// check inspects the calls of wantMethod, which must be made
+		// exactly once.
+		wantMethod string
+		check      func(t *testing.T, args []interface{})
+	}{
+		{
+			name: "list without filter", method: http.MethodGet, target: "/tasks",
+			wantStatus: http.StatusOK, wantMethod: "GetAll",
+			check: func(t *testing.T, args []interface{}) {
+				if args[0] != nil {
+					t.Errorf("filter = %v, want nil", args[0])
+				}
+			},
+		},
+		{
+			name: "list with filter", method: http.MethodGet, target: "/tasks?filter=" + url.QueryEscape(`description:"сдать"`),
+			wantStatus: http.StatusOK, wantMethod: "GetAll",
+			check: func(t *testing.T, args []interface{}) {
+				filter, _ := args[0].(Filter)
+				if filter == nil || !filter.Match(Task{Description: "Сдать задание"}) || filter.Match(stored) {
+					t.Errorf("filter = %v does not match on the description", args[0])
+				}
+			},
+		},
+		{
+			name: "get", method: http.MethodGet, target: "/tasks/1",
+			wantStatus: http.StatusOK, wantMethod: "Get",
+			check: func(t *testing.T, args []interface{}) {
+				if args[0] != "1" {
+					t.Errorf("id = %v, want 1", args[0])
+				}
+			},
+		},
+		{
+			name: "create", method: http.MethodPost, target: "/tasks", body: newTask,
+			wantStatus: http.StatusCreated, wantMethod: "CreateUnique",
+			check: func(t *testing.T, args []interface{}) {
+				task := args[0].(Task)
+				if task.ID != "3" || task.Description != "Сдать задание" || !task.CreatedAt.Equal(testNow) {
+					t.Errorf("task = %+v", task)
+				}
+			},
+		},
+		{
+			name: "forced create", method: http.MethodPost, target: "/tasks?force=true", body: newTask,
+			wantStatus: http.StatusCreated, wantMethod: "Create",
+			check: func(t *testing.T, args []interface{}) {
+				if task := args[0].(Task); task.ID != "3" {
+					t.Errorf("task = %+v", task)
+				}
+			},
+		},
+		{
+			name: "delete", method: http.MethodDelete, target: "/tasks/1",
+			wantStatus: http.StatusOK, wantMethod: "Delete",
+			check: func(t *testing.T, args []interface{}) {
+				if args[0] != "1" {
+					t.Errorf("id = %v, want 1", args[0])
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newStorage()
+			s, _ := newTestServerWithConfig(t, DefaultConfig(), storage)
+			response := serve(s, tt.method, tt.target, tt.body)
+
+			assertStatus(t, response, tt.wantStatus)
+			calls := storage.CallsTo(tt.wantMethod)
+			if len(calls) != 1 {
+				t.Fatalf("%s called %d times, want once; calls: %v", tt.wantMethod, len(calls), storage.Calls())
+			}
+			tt.check(t, calls[0].Args)
+		})
+	}
+}
+
+// decodeJSON unmarshals body into value, failing the test if it is
+// not valid JSON.
+func decodeJSON(t *testing.T, body []byte, value interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, value); err != nil {
+		t.Fatalf("invalid JSON %s: %v", body, err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// MessageCode identifies an error message independently of its language.
+type MessageCode string
+
+const (
+	MsgTaskNotFound         MessageCode = "task_not_found"
+	MsgTaskAlreadyExists    MessageCode = "task_already_exists"
+	MsgTaskDescriptionEmpty MessageCode = "task_description_empty"
+	MsgAdminSecretInvalid   MessageCode = "admin_secret_invalid"
+	MsgIPBlocked            MessageCode = "ip_blocked"
+	MsgCSRFTokenInvalid     MessageCode = "csrf_token_invalid"
+	MsgEndpointRetired      MessageCode = "endpoint_retired"
+	MsgProjectNotFound      MessageCode = "project_not_found"
+	MsgProjectAlreadyExists MessageCode = "project_already_exists"
+	MsgTaskProjectMissing   MessageCode = "task_project_missing"
+	MsgTaskDuplicate        MessageCode = "task_duplicate"
+	MsgSavedSearchNotFound  MessageCode = "saved_search_not_found"
+	// The codes of the Idempotency-Key errors, see idempotent.
+	MsgIdempotencyKeyReused   MessageCode = "idempotency_key_reused"
+	MsgIdempotencyKeyInFlight MessageCode = "idempotency_key_in_flight"
+	// The code of the error of a task with reminders and no SLA
+	// deadline, see scheduleReminders.
+	MsgTaskReminderNoDeadline MessageCode = "task_reminder_no_deadline"
+)
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// defaultLanguage is used when the client accepts none of the
+// languages there are messages for.
+var defaultLanguage = language.English
+
+// Catalog holds the error messages of every supported language.
+type Catalog struct {
+	matcher  language.Matcher
+	tags     []language.Tag
+	messages []map[MessageCode]string
+}
+
+var catalog = mustLoadCatalog(localeFiles)
+
+// mustLoadCatalog reads every locales/<tag>.yaml file and panics,
+// so that startup fails, if one is malformed or lacks a message the
+// default language has.
+func mustLoadCatalog(files embed.FS) *Catalog {
+	names, err := files.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("locales: %v", err))
+	}
+
+	byTag := make(map[language.Tag]map[MessageCode]string)
+	for _, entry := range names {
+		tag, err := language.Parse(strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+		if err != nil {
+			panic(fmt.Sprintf("locales/%s: %v", entry.Name(), err))
+		}
+		data, err := files.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			panic(fmt.Sprintf("locales/%s: %v", entry.Name(), err))
+		}
+		messages := make(map[MessageCode]string)
+		if err = yaml.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("locales/%s: %v", entry.Name(), err))
+		}
+		byTag[tag] = messages
+	}
+
+	defaults, ok := byTag[defaultLanguage]
+	if !ok {
+		panic(fmt.Sprintf("locales: no messages for the default language %s", defaultLanguage))
+	}
+
+	// The matcher falls back to the first tag, so the default goes first.
+	result := &Catalog{
+		tags:     []language.Tag{defaultLanguage},
+		messages: []map[MessageCode]string{defaults},
+	}
+	for tag, messages := range byTag {
+		if tag == defaultLanguage {
+			continue
+		}
+		for code := range defaults {
+			if _, ok := messages[code]; !ok {
+				panic(fmt.Sprintf("locales: %s lacks message %s", tag, code))
+			}
+		}
+		result.tags = append(result.tags, tag)
+		result.messages = append(result.messages, messages)
+	}
+	result.matcher = language.NewMatcher(result.tags)
+	return result
+}
+
+// Message returns the message for code in the language the
+// Accept-Language header prefers, along with that language.
+func (c *Catalog) Message(acceptLanguage string, code MessageCode) (string, language.Tag) {
+	_, index := language.MatchStrings(c.matcher, acceptLanguage)
+	if message, ok := c.messages[index][code]; ok {
+		return message, c.tags[index]
+	}
+	return string(code), c.tags[index]
+}
+
+// localizedError replies to the request with the message for code, in
+// the language the client prefers, and the given HTTP status.
+func localizedError(writer http.ResponseWriter, request *http.Request, code MessageCode, status int) {
+	message, tag := catalog.Message(request.Header.Get("Accept-Language"), code)
+	writer.Header().Set("Content-Language", tag.String())
+	http.Error(writer, message, status)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCatalogMessage(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		wantLanguage   string
+	}{
+		{name: "no header", acceptLanguage: "", wantLanguage: "en"},
+		{name: "english", acceptLanguage: "en-US", wantLanguage: "en"},
+		{name: "russian", acceptLanguage: "ru", wantLanguage: "ru"},
+		{name: "regional russian", acceptLanguage: "ru-RU", wantLanguage: "ru"},
+		{name: "russian as a fallback", acceptLanguage: "fr, ru;q=0.5", wantLanguage: "ru"},
+		{name: "english preferred", acceptLanguage: "en;q=0.9, ru;q=0.1", wantLanguage: "en"},
+		{name: "unsupported", acceptLanguage: "fr-FR, de;q=0.8", wantLanguage: "en"},
+		{name: "malformed", acceptLanguage: ";;q=x", wantLanguage: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, tag := catalog.Message(tt.acceptLanguage, MsgTaskNotFound)
+			if base, _ := tag.Base(); base.String() != tt.wantLanguage {
+				t.Errorf("language = %s, want %s", tag, tt.wantLanguage)
+			}
+			want := map[string]string{"en": "Task with given ID was not found", "ru": "Задача с указанным ID не найдена"}[tt.wantLanguage]
+			if message != want {
+				t.Errorf("message = %q, want %q", message, want)
+			}
+		})
+	}
+}
+
+func TestLocalesHaveTheSameCodes(t *testing.T) {
+	read := func(name string) map[MessageCode]string {
+		t.Helper()
+		data, err := localeFiles.ReadFile("locales/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages := make(map[MessageCode]string)
+		if err = yaml.Unmarshal(data, &messages); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return messages
+	}
+	english, russian := read("en.yaml"), read("ru.yaml")
+
+	for code := range english {
+		if russian[code] == "" {
+			t.Errorf("ru.yaml lacks %s", code)
+		}
+	}
+	for code := range russian {
+		if english[code] == "" {
+			t.Errorf("en.yaml lacks %s, which ru.yaml has", code)
+		}
+	}
+	for _, code := range []MessageCode{
+		MsgTaskNotFound, MsgTaskAlreadyExists, MsgTaskDescriptionEmpty, MsgAdminSecretInvalid,
+		MsgIPBlocked, MsgCSRFTokenInvalid, MsgEndpointRetired, MsgProjectNotFound,
+		MsgProjectAlreadyExists, MsgTaskProjectMissing, MsgTaskDuplicate, MsgSavedSearchNotFound,
+		MsgIdempotencyKeyReused, MsgIdempotencyKeyInFlight, MsgTaskReminderNoDeadline,
+	} {
+		if english[code] == "" {
+			t.Errorf("en.yaml lacks %s", code)
+		}
+	}
+}
+
+func TestLocalizedError(t *testing.T) {
+	s, _ := newTestServer(t, fixtureTasks(t)...)
+
+	request := newRequest(http.MethodGet, "/tasks/99", "")
+	request.Header.Set("Accept-Language", "ru-RU, en;q=0.5")
+	response := serveRequest(s, request)
+	assertStatus(t, response, http.StatusBadRequest)
+	if got := response.Header().Get("Content-Language"); got != "ru" {
+		t.Errorf("Content-Language = %q, want ru", got)
+	}
+	if got, want := response.Body.String(), "Задача с указанным ID не найдена\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	response = serve(s, http.MethodGet, "/tasks/99", "")
+	if got := response.Header().Get("Content-Language"); got != "en" {
+		t.Errorf("Content-Language without Accept-Language = %q, want en", got)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyKeyTTL is how long the response to a keyed request
+	// is replayed for.
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencySweepInterval is how often expired keys are dropped.
+	idempotencySweepInterval = 10 * time.Minute
+)
+
+// CachedResponse is a response kept to be replayed to a retried request.
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyEntry is what an IdempotencyStore keeps for a key: the
+// SHA-256 fingerprint of the body of the first request made with it
+// and, once that request has been handled, its response.
+type IdempotencyEntry struct {
+	Fingerprint string
+	// Response is nil while the first request is in flight.
+	Response  *CachedResponse
+	ExpiresAt time.Time
+}
+
+// IdempotencyStore keeps the responses to requests made with an
+// Idempotency-Key header. Keys are reserved before their request is
+// handled, so that concurrent retries are not handled twice.
+type IdempotencyStore interface {
+	// Reserve claims key for a request with the given fingerprint
+	// until now plus idempotencyKeyTTL, and reports true, if the key
+	// is unknown or has expired. Otherwise it returns the entry of
+	// the key and false.
+	Reserve(ctx context.Context, key, fingerprint string, now time.Time) (IdempotencyEntry, bool, error)
+	// Complete stores the response to the request key was reserved
+	// for.
+	Complete(ctx context.Context, key string, response CachedResponse) error
+	// Release forgets a reserved key, so that the request can be
+	// retried.
+	Release(ctx context.Context, key string) error
+	// Sweep forgets the keys expired by now and returns how many
+	// there were.
+	Sweep(ctx context.Context, now time.Time) (int, error)
+	// Flush forgets every stored response and returns how many there
+	// were. Keys whose request is in flight stay reserved.
+	Flush(ctx context.Context) (int, error)
+}
+
+// InMemoryIdempotencyStore is an IdempotencyStore kept in a map.
+type InMemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]IdempotencyEntry
+}
+
+// NewInMemoryIdempotencyStore creates an empty InMemoryIdempotencyStore.
+func NewInMemoryIdempotencyStore() *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{entries: make(map[string]IdempotencyEntry)}
+}
+
+func (store *InMemoryIdempotencyStore) Reserve(_ context.Context, key, fingerprint string, now time.Time) (IdempotencyEntry, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if entry, ok := store.entries[key]; ok && now.Before(entry.ExpiresAt) {
+		return entry, false, nil
+	}
+	entry := IdempotencyEntry{Fingerprint: fingerprint, ExpiresAt: now.Add(idempotencyKeyTTL)}
+	store.entries[key] = entry
+	return entry, true, nil
+}
+
+func (store *InMemoryIdempotencyStore) Complete(_ context.Context, key string, response CachedResponse) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if entry, ok := store.entries[key]; ok {
+		entry.Response = &response
+		store.entries[key] = entry
+	}
+	return nil
+}
+
+func (store *InMemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.entries, key)
+	return nil
+}
+
+func (store *InMemoryIdempotencyStore) Sweep(_ context.Context, now time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	swept := 0
+	for key, entry := range store.entries {
+		if !now.Before(entry.ExpiresAt) {
+			delete(store.entries, key)
+			swept++
+		}
+	}
+	return swept, nil
+}
+
+func (store *InMemoryIdempotencyStore) Flush(context.Context) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	flushed := 0
+	for key, entry := range store.entries {
+		if entry.Response != nil {
+			delete(store.entries, key)
+			flushed++
+		}
+	}
+	return flushed, nil
+}
+
+// sweepIdempotencyKeys drops the expired Idempotency-Key entries every
+// interval, until ctx is cancelled.
+func (s *Server) sweepIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			swept, err := s.idempotency.Sweep(ctx, s.clock.Now())
+			if err != nil {
+				s.logger.Error("Не удалось удалить устаревшие ключи идемпотентности", "error", err)
+			} else if swept > 0 {
+				s.logger.Debug("Удалены устаревшие ключи идемпотентности", "count", swept)
+			}
+		}
+	}
+}
+
+// idempotent replays the response to an earlier request with the same
+// Idempotency-Key header instead of handling the request again, for
+// idempotencyKeyTTL after that request. Replayed responses carry an
+// Idempotent-Replayed header. The key is reserved before the request is
+// handled: a retry made while the first request is in flight gets a
+// HTTP 409 Conflict, and a request whose body differs from the first
+// one's a HTTP 422 Unprocessable Entity. Server errors are not kept,
+// so that such requests can be retried. Requests without the header
+// are handled as usual.
+func (s *Server) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		key := request.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(writer, request)
+			return
+		}
+		key = request.Method + " " + request.URL.Path + " " + key
+
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			s.logger.Warn("Не удалось прочитать тело запроса", "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		entry, reserved, err := s.idempotency.Reserve(request.Context(), key, fingerprint, s.clock.Now())
+		if err != nil {
+			s.logger.Error("Не удалось зарезервировать ключ идемпотентности", "error", err)
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			s.replay(writer, request, entry, fingerprint)
+			return
+		}
+
+		completed := false
+		defer func() {
+			// A failed or panicking request frees the key for a retry.
+			if !completed {
+				if err := s.idempotency.Release(request.Context(), key); err != nil {
+					s.logger.Error("Не удалось освободить ключ идемпотентности", "error", err)
+				}
+			}
+		}()
+
+		recorder := &recordingWriter{ResponseWriter: writer}
+		next.ServeHTTP(recorder, request)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		if recorder.status >= 500 {
+			return
+		}
+
+		err = s.idempotency.Complete(request.Context(), key, CachedResponse{
+			Status: recorder.status,
+			Header: writer.Header().Clone(),
+			Body:   recorder.body.Bytes(),
+		})
+		if err != nil {
+			s.logger.Error("Не удалось сохранить ответ", "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+// replay answers a request whose Idempotency-Key is already known with
+// the stored response, if the first request has been handled and had
+// the same body.
+func (s *Server) replay(writer http.ResponseWriter, request *http.Request, entry IdempotencyEntry, fingerprint string) {
+	idempotencyKey := request.Header.Get(idempotencyKeyHeader)
+	if entry.Fingerprint != fingerprint {
+		s.logger.Warn("Ключ идемпотентности использован с другим телом запроса", "idempotency_key", idempotencyKey)
+		localizedError(writer, request, MsgIdempotencyKeyReused, http.StatusUnprocessableEntity)
+		return
+	}
+	if entry.Response == nil {
+		s.logger.Warn("Запрос с ключом идемпотентности ещё выполняется", "idempotency_key", idempotencyKey)
+		localizedError(writer, request, MsgIdempotencyKeyInFlight, http.StatusConflict)
+		return
+	}
+
+	s.logger.Info("Повторён сохранённый ответ", "idempotency_key", idempotencyKey)
+	for name, values := range entry.Response.Header {
+		writer.Header()[name] = values
+	}
+	writer.Header().Set("Idempotent-Replayed", "true")
+	writer.WriteHeader(entry.Response.Status)
+	writer.Write(entry.Response.Body)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// postWithKey posts body to /tasks with the given Idempotency-Key.
+func postWithKey(s *Server, key, body string) *httptest.ResponseRecorder {
+	request := newRequest(http.MethodPost, "/tasks", body)
+	request.Header.Set(idempotencyKeyHeader, key)
+	return serveRequest(s, request)
+}
+
+const (
+	idempotentTask      = `{"id":"new","description":"Новая задача","note":"","applications":[]}`
+	otherIdempotentTask = `{"id":"other","description":"Другая задача","note":"","applications":[]}`
+)
+
+func TestIdempotentReplay(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	first := postWithKey(s, "k1", idempotentTask)
+	assertStatus(t, first, http.StatusCreated)
+
+	replayed := postWithKey(s, "k1", idempotentTask)
+	assertStatus(t, replayed, http.StatusCreated)
+	if replayed.Header().Get("Idempotent-Replayed") != "true" || replayed.Body.String() != first.Body.String() {
+		t.Errorf("retry got %s %q, want the first response replayed", replayed.Header(), replayed.Body)
+	}
+
+	// Without the key, or with another one, the request is handled.
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", idempotentTask), http.StatusConflict)
+	assertStatus(t, postWithKey(s, "k2", idempotentTask), http.StatusConflict)
+}
+
+func TestIdempotencyKeyReusedWithAnotherBody(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	assertStatus(t, postWithKey(s, "k1", idempotentTask), http.StatusCreated)
+
+	response := postWithKey(s, "k1", otherIdempotentTask)
+	assertStatus(t, response, http.StatusUnprocessableEntity)
+	if _, err := s.store.Get(context.Background(), "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("the second body was handled: %v", err)
+	}
+}
+
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	sum := sha256.Sum256([]byte(idempotentTask))
+	_, reserved, err := s.idempotency.Reserve(context.Background(), "POST /tasks k1", hex.EncodeToString(sum[:]), testNow)
+	if err != nil || !reserved {
+		t.Fatalf("Reserve() = %v, %v", reserved, err)
+	}
+
+	assertStatus(t, postWithKey(s, "k1", idempotentTask), http.StatusConflict)
+	if _, err := s.store.Get(context.Background(), "new"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a request in flight was handled again: %v", err)
+	}
+}
+
+func TestIdempotentConcurrentRetries(t *testing.T) {
+	var mu sync.Mutex
+	creates := 0
+	storage := &MockStorage{
+		CreateUniqueFn: func(context.Context, Task) error {
+			mu.Lock()
+			creates++
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+	}
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), storage)
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- postWithKey(s, "k1", idempotentTask).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	if creates != 1 {
+		t.Errorf("the task was created %d times, want once", creates)
+	}
+	for status := range statuses {
+		if status != http.StatusCreated && status != http.StatusConflict {
+			t.Errorf("status = %d, want 201 or 409", status)
+		}
+	}
+}
+
+func TestIdempotencyKeyReleasedOnServerError(t *testing.T) {
+	failing := true
+	storage := &MockStorage{
+		CreateUniqueFn: func(context.Context, Task) error {
+			if failing {
+				return &StorageError{Op: "create", Err: errors.New("disk on fire")}
+			}
+			return nil
+		},
+	}
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), storage)
+
+	assertStatus(t, postWithKey(s, "k1", idempotentTask), http.StatusInternalServerError)
+	failing = false
+	response := postWithKey(s, "k1", idempotentTask)
+	assertStatus(t, response, http.StatusCreated)
+	if response.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("the retry of a failed request was replayed")
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	s, clock := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	assertStatus(t, postWithKey(s, "k1", idempotentTask), http.StatusCreated)
+
+	clock.Advance(idempotencyKeyTTL - time.Second)
+	assertStatus(t, postWithKey(s, "k1", idempotentTask), http.StatusCreated)
+
+	clock.Advance(time.Second)
+	assertStatus(t, postWithKey(s, "k1", idempotentTask), http.StatusConflict)
+}
+
+func TestInMemoryIdempotencyStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdempotencyStore()
+	store.Reserve(ctx, "old", "a", testNow)
+	store.Reserve(ctx, "new", "b", testNow.Add(time.Hour))
+	store.Complete(ctx, "new", CachedResponse{Status: http.StatusCreated})
+
+	swept, err := store.Sweep(ctx, testNow.Add(idempotencyKeyTTL))
+	if err != nil || swept != 1 {
+		t.Fatalf("Sweep() = %d, %v, want 1", swept, err)
+	}
+	if _, reserved, _ := store.Reserve(ctx, "new", "b", testNow.Add(idempotencyKeyTTL)); reserved {
+		t.Error("Sweep() dropped a key that has not expired")
+	}
+	if _, reserved, _ := store.Reserve(ctx, "old", "a", testNow.Add(idempotencyKeyTTL)); !reserved {
+		t.Error("the swept key is still reserved")
+	}
+}
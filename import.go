@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// importMaxBytes bounds the size of an uploaded import.
+const importMaxBytes = 32 << 20
+
+// csvImportColumns are the columns a CSV import may have. Applications
+// are separated by semicolons.
+var csvImportColumns = map[string]bool{
+	"id":           true,
+	"description":  true,
+	"note":         true,
+	"applications": true,
+	"project_id":   true,
+}
+
+// importFormats maps the values of the format query parameter of
+// POST /tasks/import to the media type the upload is parsed as.
+var importFormats = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv",
+	"md":   "text/markdown",
+}
+
+// parseImport splits an import into records, each decoded as a generic
+// JSON value. JSON imports are an array of tasks; CSV imports have a
+// header row naming csvImportColumns; Markdown imports are checklists,
+// see parseMarkdownImport.
+func parseImport(contentType string, body []byte) ([]interface{}, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json", "":
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var records []interface{}
+		if err := decoder.Decode(&records); err != nil {
+			return nil, fmt.Errorf("expected a JSON array of tasks: %w", err)
+		}
+		return records, nil
+	case "text/csv":
+		reader := csv.NewReader(bytes.NewReader(body))
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("expected a CSV header row: %w", err)
+		}
+		for _, column := range header {
+			if !csvImportColumns[column] {
+				return nil, fmt.Errorf("unknown CSV column %q", column)
+			}
+		}
+
+		var records []interface{}
+		for {
+			row, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			} else if err != nil {
+				return nil, err
+			}
+			record := make(map[string]interface{}, len(header))
+			for i, column := range header {
+				if column == "applications" {
+					applications := []interface{}{}
+					for _, application := range strings.Split(row[i], ";") {
+						if application = strings.TrimSpace(application); application != "" {
+							applications = append(applications, application)
+						}
+					}
+					record[column] = applications
+				} else {
+					record[column] = row[i]
+				}
+			}
+			records = append(records, record)
+		}
+	case "text/markdown":
+		return parseMarkdownImport(body)
+	default:
+		return nil, fmt.Errorf("unsupported import type %q, expected application/json, text/csv or text/markdown", mediaType)
+	}
+}
+
+// markdownItem matches a checklist item, such as "- [x] Report",
+// capturing its indentation and text.
+var markdownItem = regexp.MustCompile(`^(\s*)[-*+] \[[ xX]\] (.*)$`)
+
+// parseMarkdownImport turns a Markdown checklist, such as GET
+// /tasks/export?format=md writes, into records. Every top-level item
+// is a task under a generated ID. Tasks have neither a completion
+// state nor sub-tasks, so checked and unchecked items are imported
+// alike, and nested items are appended to the note of the task they
+// are nested under, one "- " line each. The text of a heading,
+// lowercased, is suggested as a tag of the items under it, up to the
+// next heading. Blank lines are skipped; any other line is rejected.
+func parseMarkdownImport(body []byte) ([]interface{}, error) {
+	var records []interface{}
+	var current map[string]interface{}
+	var tag string
+	for number, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			continue
+		}
+		if heading, isHeading := strings.CutPrefix(line, "#"); isHeading {
+			tag = strings.ToLower(strings.TrimSpace(strings.TrimLeft(heading, "#")))
+			continue
+		}
+		match := markdownItem.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected a checklist item such as \"- [ ] Task\"", number+1)
+		}
+
+		text := strings.TrimSpace(match[2])
+		if match[1] != "" {
+			if current == nil {
+				return nil, fmt.Errorf("line %d: nested item without a task to belong to", number+1)
+			}
+			note := current["note"].(string)
+			if note != "" {
+				note += "\n"
+			}
+			current["note"] = note + "- " + text
+			continue
+		}
+
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		current = map[string]interface{}{
+			"id":           id,
+			"description":  text,
+			"note":         "",
+			"applications": []interface{}{},
+		}
+		if tag != "" {
+			current["suggested_tags"] = []interface{}{tag}
+		}
+		records = append(records, current)
+	}
+	return records, nil
+}
+
+// importTask stores one imported task, applying the rules of postTask
+// without the force query parameter: a task whose description another
+// task has is refused with a *DuplicateDescriptionError. The tags the
+// categorizer suggests are added to the suggested tags the task comes
+// with, such as the #tags of a Slack command.
+func (s *Server) importTask(ctx context.Context, task Task) error {
+	if s.featureFlags().EnableHTMLSanitization {
+		sanitizeTask(&task)
+		if strings.TrimSpace(task.Description) == "" {
+			return errors.New("description is empty once HTML is removed")
+		}
+	}
+	if task.ProjectID != "" {
+		if _, err := s.projects.Get(ctx, task.ProjectID); err != nil {
+			return err
+		}
+	}
+
+	task.CreatedAt = s.clock.Now()
+	task.SuggestedTags = mergeTags(task.SuggestedTags, s.categorizer.Categorize(ctx, task.Description))
+	if err := s.store.CreateUnique(ctx, task); err != nil {
+		return err
+	}
+	if s.queue != nil && s.featureFlags().EnableTaskQueue {
+		if err := s.queue.Enqueue(ctx, task); err != nil {
+			s.logger.Error("Не удалось поставить задачу в очередь", "id", task.ID, "error", err)
+		}
+	}
+	s.embedCreated(ctx, task)
+	return nil
+}
+
+// importJobType is the type of the jobs postImportTasks submits.
+const importJobType = "import"
+
+// ImportResult is the result of an import job.
+type ImportResult struct {
+	Created int      `json:"created"`
+	Errors  []string `json:"errors"`
+}
+
+// importWorker imports the records in the payload of an import job, a
+// JSON array of tasks, one by one, reporting the progress and the
+// failed records.
+type importWorker struct {
+	server *Server
+}
+
+func (worker importWorker) Process(ctx context.Context, job Job) error {
+	decoder := json.NewDecoder(bytes.NewReader(job.Payload))
+	decoder.UseNumber()
+	var records []interface{}
+	if err := decoder.Decode(&records); err != nil {
+		return fmt.Errorf("expected a JSON array of tasks: %w", err)
+	}
+
+	s := worker.server
+	schema := s.schemas.lookup(http.MethodPost, "/tasks")
+	result := ImportResult{Errors: []string{}}
+	for i, record := range records {
+		if err := s.importRecord(ctx, schema, record); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+		} else {
+			result.Created++
+		}
+		if err := s.runner.Report(ctx, job.ID, (i+1)*100/len(records), result); err != nil {
+			s.logger.Error("Не удалось обновить задание импорта", "id", job.ID, "error", err)
+		}
+	}
+	if len(records) == 0 {
+		return s.runner.Report(ctx, job.ID, 100, result)
+	}
+
+	s.logger.Info("Импорт задач завершён", "id", job.ID, "created", result.Created, "failed", len(result.Errors))
+	return nil
+}
+
+// importRecord checks a record against the schema of POST /tasks and
+// imports it.
+func (s *Server) importRecord(ctx context.Context, schema *jsonschema.Schema, record interface{}) error {
+	if err := schema.Validate(record); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			var messages []string
+			for _, fieldErr := range fieldErrors(validationErr) {
+				if fieldErr.Field == "" {
+					messages = append(messages, fieldErr.Message)
+				} else {
+					messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+				}
+			}
+			return errors.New(strings.Join(messages, "; "))
+		}
+		return err
+	}
+
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	var task Task
+	if err = json.Unmarshal(encoded, &task); err != nil {
+		return err
+	}
+	return s.importTask(ctx, task)
+}
+
+// postImportTasks handles the HTTP request to import many tasks at
+// once, from a JSON array of tasks or, with Content-Type text/csv, a
+// CSV file or, with Content-Type text/markdown, a Markdown checklist.
+// The format query parameter, json, csv or md, overrides the
+// Content-Type; any other value is rejected with a HTTP 400 Bad
+// Request. The upload is checked and split into records right away;
+// a malformed one is rejected with a HTTP 400 Bad Request. The records
+// are then imported by an import job, and the response is a HTTP 202
+// Accepted with the ID of the job, whose progress and ImportResult
+// GET /jobs/{id} reports. A full job queue is reported with a HTTP 503
+// Service Unavailable.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, with the tasks in the body.
+func (s *Server) postImportTasks(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, importMaxBytes))
+	if err != nil {
+		s.logger.Warn("Не удалось прочитать файл импорта", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := request.Header.Get("Content-Type")
+	if format := request.URL.Query().Get("format"); format != "" {
+		var ok bool
+		if contentType, ok = importFormats[format]; !ok {
+			s.logger.Warn("Неподдерживаемый формат импорта", "format", format)
+			http.Error(writer, "Unsupported import format, expected format=json, csv or md", http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, err := parseImport(contentType, body)
+	if err != nil {
+		s.logger.Warn("Некорректный файл импорта", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := json.Marshal(records)
+	if err != nil {
+		s.logger.Error("Не удалось создать задание импорта", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	job, err := s.runner.Submit(request.Context(), importJobType, payload)
+	if err != nil {
+		s.reportSubmitError(writer, err)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusAccepted, map[string]string{"job_id": job.ID})
+	s.logger.Info("Запущен импорт задач", "id", job.ID, "records", len(records))
+}
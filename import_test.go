@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startJobRunner runs the job workers of s until the test ends.
+func startJobRunner(t *testing.T, s *Server) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.runner.Run(ctx, 1)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitForJob waits for the job to finish and returns it.
+func waitForJob(t *testing.T, s *Server, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := s.jobs.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("getting job %s: %v", id, err)
+		}
+		if job.Status == JobDone || job.Status == JobFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is still %s", id, job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// importTasks posts an import and waits for its job to finish.
+func importTasks(t *testing.T, s *Server, target, contentType, body string) Job {
+	t.Helper()
+	request := newRequest(http.MethodPost, target, body)
+	request.Header.Set("Content-Type", contentType)
+	response := serveRequest(s, request)
+	assertStatus(t, response, http.StatusAccepted)
+
+	var accepted map[string]string
+	decodeJSON(t, response.Body.Bytes(), &accepted)
+	return waitForJob(t, s, accepted["job_id"])
+}
+
+func TestParseMarkdownImport(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []map[string]interface{}
+		wantErr  bool
+	}{
+		{name: "empty", markdown: ""},
+		{name: "only a heading", markdown: "# Tasks\n"},
+		{
+			name:     "checked and unchecked",
+			markdown: "# Tasks\n- [ ] Сделать задание\n- [x] Сдать задание\r\n* [X] Отдохнуть \n",
+			want: []map[string]interface{}{
+				{"description": "Сделать задание", "note": "", "suggested_tags": "tasks"},
+				{"description": "Сдать задание", "note": "", "suggested_tags": "tasks"},
+				{"description": "Отдохнуть", "note": "", "suggested_tags": "tasks"},
+			},
+		},
+		{
+			name:     "nested items",
+			markdown: "- [ ] Релиз\n  - [x] Тесты\n    - [ ] Ревью\n\n- [ ] Отпуск\n",
+			want: []map[string]interface{}{
+				{"description": "Релиз", "note": "- Тесты\n- Ревью"},
+				{"description": "Отпуск", "note": ""},
+			},
+		},
+		{
+			name:     "headings as tags",
+			markdown: "- [ ] Отдохнуть\n# Работа\n- [ ] Релиз\n  - [ ] Тесты\n- [ ] Отчёт\n## Учёба  \n- [x] Лекция\n#\n- [ ] Прогулка\n",
+			want: []map[string]interface{}{
+				{"description": "Отдохнуть", "note": ""},
+				{"description": "Релиз", "note": "- Тесты", "suggested_tags": "работа"},
+				{"description": "Отчёт", "note": "", "suggested_tags": "работа"},
+				{"description": "Лекция", "note": "", "suggested_tags": "учёба"},
+				{"description": "Прогулка", "note": ""},
+			},
+		},
+		{name: "plain text", markdown: "- [ ] Релиз\nне пункт списка\n", wantErr: true},
+		{name: "list item without a box", markdown: "- Релиз\n", wantErr: true},
+		{name: "nested item first", markdown: "  - [ ] Тесты\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := parseMarkdownImport([]byte(tt.markdown))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMarkdownImport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(records) != len(tt.want) {
+				t.Fatalf("got %d records, want %d: %v", len(records), len(tt.want), records)
+			}
+			ids := make(map[interface{}]bool)
+			for i, record := range records {
+				fields := record.(map[string]interface{})
+				if fields["description"] != tt.want[i]["description"] || fields["note"] != tt.want[i]["note"] {
+					t.Errorf("record %d = %v, want %v", i, fields, tt.want[i])
+				}
+				var tags []interface{}
+				if tag, ok := tt.want[i]["suggested_tags"]; ok {
+					tags = []interface{}{tag}
+				}
+				if got, _ := fields["suggested_tags"].([]interface{}); !reflect.DeepEqual(got, tags) {
+					t.Errorf("record %d suggested_tags = %v, want %v", i, got, tags)
+				}
+				if id, _ := fields["id"].(string); id == "" || ids[id] {
+					t.Errorf("record %d has a missing or repeated ID %v", i, fields["id"])
+				}
+				ids[fields["id"]] = true
+			}
+		})
+	}
+}
+
+func TestImportMarkdownRoundTrip(t *testing.T) {
+	source, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	exported := serve(source, http.MethodGet, "/tasks/export?format=md", "")
+	assertStatus(t, exported, http.StatusOK)
+
+	target, _ := newTestServer(t)
+	startJobRunner(t, target)
+	job := importTasks(t, target, "/tasks/import?format=md", "text/plain", exported.Body.String())
+	if job.Status != JobDone || string(job.Result) != `{"created":7,"errors":[]}` {
+		t.Fatalf("import job = %s, %s", job.Status, job.Result)
+	}
+
+	descriptions := func(s *Server) []string {
+		tasks, err := s.store.GetAll(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var descriptions []string
+		for _, task := range tasks {
+			descriptions = append(descriptions, task.Description)
+		}
+		sort.Strings(descriptions)
+		return descriptions
+	}
+	if got, want := descriptions(target), descriptions(source); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported %q, want %q", got, want)
+	}
+	// The "# Tasks" heading of the export is suggested as a tag.
+	imported, err := target.store.GetAll(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range imported {
+		if !slices.Contains(task.SuggestedTags, "tasks") {
+			t.Errorf("task %q suggested_tags = %q, want tasks among them", task.Description, task.SuggestedTags)
+		}
+	}
+
+	// Exported again, the checklist has the same items, in the order
+	// of the new IDs.
+	reexported := serve(target, http.MethodGet, "/tasks/export?format=md", "")
+	lines := func(markdown string) []string {
+		lines := strings.Split(strings.TrimSpace(markdown), "\n")
+		sort.Strings(lines)
+		return lines
+	}
+	if got, want := lines(reexported.Body.String()), lines(exported.Body.String()); !reflect.DeepEqual(got, want) {
+		t.Errorf("re-exported %q, want %q", got, want)
+	}
+}
+
+func TestImportFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "JSON", target: "/tasks/import", contentType: "application/json", body: `[{"id":"a","description":"A","note":"","applications":[]}]`, wantStatus: http.StatusAccepted},
+		{name: "CSV", target: "/tasks/import", contentType: "text/csv", body: "id,description\na,A\n", wantStatus: http.StatusAccepted},
+		{name: "Markdown by Content-Type", target: "/tasks/import", contentType: "text/markdown", body: "- [ ] A\n", wantStatus: http.StatusAccepted},
+		{name: "CSV by format", target: "/tasks/import?format=csv", contentType: "text/plain", body: "id,description\na,A\n", wantStatus: http.StatusAccepted},
+		{name: "unknown format", target: "/tasks/import?format=xml", contentType: "text/markdown", body: "- [ ] A\n", wantStatus: http.StatusBadRequest},
+		{name: "unknown Content-Type", target: "/tasks/import", contentType: "text/plain", body: "- [ ] A\n", wantStatus: http.StatusBadRequest},
+		{name: "malformed Markdown", target: "/tasks/import?format=md", contentType: "text/plain", body: "A\n", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			request := newRequest(http.MethodPost, tt.target, tt.body)
+			request.Header.Set("Content-Type", tt.contentType)
+			assertStatus(t, serveRequest(s, request), tt.wantStatus)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+//go:build integration
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// The integration tests run the whole middleware stack of the main
+// and admin routers behind a real HTTP listener. Run them with
+//
+//	go test -tags integration ./...
+
+// integrationCall is one request of an integration scenario and what
+// its response must look like.
+type integrationCall struct {
+	name       string
+	method     string
+	path       string
+	body       string
+	header     http.Header
+	wantStatus int
+	wantHeader map[string]string
+	// wantJSON, if set, is compared with the decoded response body.
+	wantJSON func(t *testing.T, body interface{})
+}
+
+func runIntegration(t *testing.T, server *httptest.Server, client *http.Client, calls []integrationCall) {
+	t.Helper()
+	for _, call := range calls {
+		var body io.Reader
+		if call.body != "" {
+			body = strings.NewReader(call.body)
+		}
+		request, err := http.NewRequest(call.method, server.URL+call.path, body)
+		if err != nil {
+			t.Fatalf("%s: %v", call.name, err)
+		}
+		if call.body != "" {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		for key, values := range call.header {
+			request.Header[key] = values
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("%s: %v", call.name, err)
+		}
+		payload, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: reading the body: %v", call.name, err)
+		}
+
+		if response.StatusCode != call.wantStatus {
+			t.Fatalf("%s: status = %d, want %d; body: %s", call.name, response.StatusCode, call.wantStatus, payload)
+		}
+		for key, want := range call.wantHeader {
+			if got := response.Header.Get(key); !strings.HasPrefix(got, want) {
+				t.Errorf("%s: %s = %q, want %q", call.name, key, got, want)
+			}
+		}
+		if call.wantJSON != nil {
+			var decoded interface{}
+			if err := json.Unmarshal(payload, &decoded); err != nil {
+				t.Fatalf("%s: invalid JSON %s: %v", call.name, payload, err)
+			}
+			call.wantJSON(t, decoded)
+		}
+	}
+}
+
+// securityHeaders are set by SecurityHeadersMiddleware on every
+// response, errors included.
+var securityHeaders = map[string]string{
+	"X-Content-Type-Options":  "nosniff",
+	"X-Frame-Options":         "DENY",
+	"Content-Security-Policy": "default-src 'none'",
+}
+
+func withHeaders(headers map[string]string, extra ...string) map[string]string {
+	merged := make(map[string]string, len(headers)+len(extra)/2)
+	for key, value := range headers {
+		merged[key] = value
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		merged[extra[i]] = extra[i+1]
+	}
+	return merged
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestIntegrationTaskLifecycle(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	runIntegration(t, server, server.Client(), []integrationCall{
+		{
+			name: "create", method: http.MethodPost, path: "/tasks",
+			body:       `{"id":"3","description":"Сдать <i>задание</i>","note":"","applications":["git"]}`,
+			wantStatus: http.StatusCreated, wantHeader: securityHeaders,
+		},
+		{
+			name: "get", method: http.MethodGet, path: "/tasks/3",
+			wantStatus: http.StatusOK, wantHeader: withHeaders(securityHeaders, "Content-Type", "application/json"),
+			wantJSON: func(t *testing.T, body interface{}) {
+				task := body.(map[string]interface{})
+				if task["description"] != "Сдать задание" || task["word_count"] != 2.0 {
+					t.Errorf("task = %v", task)
+				}
+			},
+		},
+		{
+			name: "count", method: http.MethodHead, path: "/tasks",
+			wantStatus: http.StatusOK, wantHeader: map[string]string{"X-Total-Count": "8"},
+		},
+		{
+			name: "sorted list", method: http.MethodGet, path: "/tasks?sort=-id&fields=id",
+			wantStatus: http.StatusOK,
+			wantJSON: func(t *testing.T, body interface{}) {
+				tasks := body.([]interface{})
+				if len(tasks) != 8 || tasks[0].(map[string]interface{})["id"] != "unicode" {
+					t.Errorf("tasks = %v", tasks)
+				}
+			},
+		},
+		{
+			name: "invalid body", method: http.MethodPost, path: "/tasks", body: `{"id":"4"}`,
+			wantStatus: http.StatusUnprocessableEntity, wantHeader: securityHeaders,
+		},
+		{
+			name: "localized error", method: http.MethodGet, path: "/tasks/99",
+			header:     http.Header{"Accept-Language": {"ru"}},
+			wantStatus: http.StatusBadRequest, wantHeader: withHeaders(securityHeaders, "Content-Language", "ru"),
+		},
+		{name: "delete", method: http.MethodDelete, path: "/tasks/3", wantStatus: http.StatusOK},
+		{name: "deleted", method: http.MethodGet, path: "/tasks/3", wantStatus: http.StatusBadRequest},
+	})
+}
+
+func TestIntegrationWrappedResponses(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.WrapResponses = true
+	s, _ := newTestServerWithConfig(t, cfg, LoadFixtures(t))
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	runIntegration(t, server, server.Client(), []integrationCall{
+		{
+			name: "wrapped", method: http.MethodGet, path: "/tasks/1",
+			wantStatus: http.StatusOK,
+			wantJSON: func(t *testing.T, body interface{}) {
+				envelope := body.(map[string]interface{})
+				meta, _ := envelope["meta"].(map[string]interface{})
+				data, _ := envelope["data"].(map[string]interface{})
+				if data["id"] != "1" || meta["request_id"] == "" || meta["timestamp"] == "" {
+					t.Errorf("envelope = %v", envelope)
+				}
+			},
+		},
+		{name: "errors bare", method: http.MethodGet, path: "/tasks/99", wantStatus: http.StatusBadRequest, wantHeader: map[string]string{"Content-Type": "text/plain"}},
+	})
+}
+
+func TestIntegrationCSRF(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CSRFProtection = true
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage())
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := server.Client()
+	client.Jar = jar
+
+	body := `{"id":"1","description":"one","note":"","applications":[]}`
+	runIntegration(t, server, client, []integrationCall{
+		{name: "token issued", method: http.MethodGet, path: "/tasks", wantStatus: http.StatusOK},
+		{name: "without token", method: http.MethodPost, path: "/tasks", body: body, wantStatus: http.StatusForbidden},
+	})
+
+	var token string
+	for _, cookie := range jar.Cookies(mustParseURL(t, server.URL)) {
+		if cookie.Name == csrfCookieName {
+			token = cookie.Value
+		}
+	}
+	if token == "" {
+		t.Fatal("no csrf_token cookie was set")
+	}
+	runIntegration(t, server, client, []integrationCall{
+		{
+			name: "with token", method: http.MethodPost, path: "/tasks", body: body,
+			header: http.Header{csrfHeaderName: {token}}, wantStatus: http.StatusCreated,
+		},
+	})
+}
+
+func TestIntegrationAdminAPI(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminSecret = "s3cret"
+	s, _ := newTestServerWithConfig(t, cfg, LoadFixtures(t))
+	admin := httptest.NewServer(s.adminRouter)
+	defer admin.Close()
+	api := httptest.NewServer(s.router)
+	defer api.Close()
+
+	secret := http.Header{adminSecretHeader: {"s3cret"}}
+	runIntegration(t, api, api.Client(), []integrationCall{
+		{name: "admin routes stay off the main router", method: http.MethodGet, path: "/admin/tasks/count", header: secret, wantStatus: http.StatusNotFound},
+	})
+	runIntegration(t, admin, admin.Client(), []integrationCall{
+		{name: "no secret", method: http.MethodGet, path: "/admin/tasks/count", wantStatus: http.StatusUnauthorized, wantHeader: securityHeaders},
+		{
+			name: "wrong secret", method: http.MethodGet, path: "/admin/tasks/count",
+			header: http.Header{adminSecretHeader: {"guess"}}, wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "task count", method: http.MethodGet, path: "/admin/tasks/count", header: secret,
+			wantStatus: http.StatusOK,
+			wantJSON: func(t *testing.T, body interface{}) {
+				if count := body.(map[string]interface{})["count"]; count != 7.0 {
+					t.Errorf("count = %v, want 7", count)
+				}
+			},
+		},
+		{name: "block the client", method: http.MethodPost, path: "/admin/blocklist", body: `{"ip":"127.0.0.1"}`, header: secret, wantStatus: http.StatusCreated},
+	})
+	runIntegration(t, api, api.Client(), []integrationCall{
+		{name: "blocked client", method: http.MethodGet, path: "/tasks", wantStatus: http.StatusForbidden, wantHeader: securityHeaders},
+	})
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	// ErrJobNotFound is returned when no job has the requested ID.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrUnknownJobType is returned when submitting a job no worker is
+	// registered for.
+	ErrUnknownJobType = errors.New("unknown job type")
+	// ErrJobQueueFull is returned when submitting a job while the
+	// queue of the JobRunner is full.
+	ErrJobQueueFull = errors.New("job queue is full")
+)
+
+// JobStatus is the state of a background job.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// Job is a unit of background work, processed by the JobWorker
+// registered for its Type.
+type Job struct {
+	ID   string `json:"job_id"`
+	Type string `json:"type"`
+	// Payload is the input of the worker. It is left out of responses,
+	// since an import carries every record in it, and dropped once the
+	// job has finished.
+	Payload json.RawMessage `json:"-"`
+	Status  JobStatus       `json:"status"`
+	// Progress is the share of the work done so far, in percent.
+	Progress int `json:"progress"`
+	// Result is set by the worker, and its shape depends on Type.
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// JobWorker processes the jobs of one type. A job whose Process
+// returns an error is marked failed with that error.
+type JobWorker interface {
+	Process(ctx context.Context, job Job) error
+}
+
+// JobStore keeps jobs. Get returns ErrJobNotFound for unknown IDs.
+type JobStore interface {
+	Create(ctx context.Context, job Job) error
+	Update(ctx context.Context, job Job) error
+	Get(ctx context.Context, id string) (Job, error)
+	GetAll(ctx context.Context) ([]Job, error)
+	// Delete forgets a job. Unknown IDs are ignored.
+	Delete(ctx context.Context, id string) error
+}
+
+// InMemoryJobStore is a JobStore backed by a map guarded by a mutex.
+type InMemoryJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+// NewInMemoryJobStore creates an empty InMemoryJobStore.
+func NewInMemoryJobStore() *InMemoryJobStore {
+	return &InMemoryJobStore{jobs: make(map[string]Job)}
+}
+
+func (store *InMemoryJobStore) Create(_ context.Context, job Job) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.jobs[job.ID] = job
+	return nil
+}
+
+func (store *InMemoryJobStore) Update(_ context.Context, job Job) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.jobs[job.ID]; !ok {
+		return ErrJobNotFound
+	}
+	store.jobs[job.ID] = job
+	return nil
+}
+
+func (store *InMemoryJobStore) Get(_ context.Context, id string) (Job, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	job, ok := store.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (store *InMemoryJobStore) GetAll(_ context.Context) ([]Job, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	jobs := make([]Job, 0, len(store.jobs))
+	for _, job := range store.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (store *InMemoryJobStore) Delete(_ context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.jobs, id)
+	return nil
+}
+
+// newID returns a random, hex-encoded 64-bit ID.
+func newID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+const (
+	// jobQueueCapacity is how many jobs may wait for a worker.
+	jobQueueCapacity = 1024
+	// jobTTL is how long a finished job is kept for its status to be
+	// read.
+	jobTTL = 24 * time.Hour
+	// jobSweepInterval is how often finished jobs older than jobTTL
+	// are dropped.
+	jobSweepInterval = 10 * time.Minute
+)
+
+// JobRunner queues submitted jobs and processes them on a pool of
+// goroutines with the worker registered for their type.
+type JobRunner struct {
+	store   JobStore
+	workers map[string]JobWorker
+	queue   chan string
+	clock   Clock
+	logger  *slog.Logger
+}
+
+// NewJobRunner creates a JobRunner keeping its jobs in store. Workers
+// must be registered before Run is called.
+func NewJobRunner(store JobStore, clock Clock, logger *slog.Logger) *JobRunner {
+	return &JobRunner{
+		store:   store,
+		workers: make(map[string]JobWorker),
+		queue:   make(chan string, jobQueueCapacity),
+		clock:   clock,
+		logger:  logger,
+	}
+}
+
+// Register makes worker process the jobs of the given type.
+func (runner *JobRunner) Register(jobType string, worker JobWorker) {
+	runner.workers[jobType] = worker
+}
+
+// Submit queues a job of the given type and returns it. It returns
+// ErrUnknownJobType if no worker processes that type, and
+// ErrJobQueueFull if too many jobs are waiting.
+func (runner *JobRunner) Submit(ctx context.Context, jobType string, payload json.RawMessage) (Job, error) {
+	job, err := runner.create(ctx, jobType, payload)
+	if err != nil {
+		return Job{}, err
+	}
+
+	select {
+	case runner.queue <- job.ID:
+		return job, nil
+	default:
+		completed := runner.clock.Now()
+		job.Status = JobFailed
+		job.Error = ErrJobQueueFull.Error()
+		job.Payload = nil
+		job.CompletedAt = &completed
+		runner.store.Update(ctx, job)
+		return Job{}, ErrJobQueueFull
+	}
+}
+
+// Do processes a job of the given type in the calling goroutine, rather
+// than queueing it, and returns it finished. The job is kept like a
+// submitted one. It returns ErrUnknownJobType if no worker processes
+// that type; a failure of the worker is reported by the job.
+func (runner *JobRunner) Do(ctx context.Context, jobType string, payload json.RawMessage) (Job, error) {
+	job, err := runner.create(ctx, jobType, payload)
+	if err != nil {
+		return Job{}, err
+	}
+	runner.process(ctx, job.ID)
+	return runner.store.Get(ctx, job.ID)
+}
+
+// create stores a new queued job of the given type.
+func (runner *JobRunner) create(ctx context.Context, jobType string, payload json.RawMessage) (Job, error) {
+	if _, ok := runner.workers[jobType]; !ok {
+		return Job{}, fmt.Errorf("%w %q", ErrUnknownJobType, jobType)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{ID: id, Type: jobType, Payload: payload, Status: JobQueued, CreatedAt: runner.clock.Now()}
+	if err = runner.store.Create(ctx, job); err != nil {
+		return Job{}, err
+	}
+	return job, nil
+}
+
+// Report records the progress, and optionally the result so far, of a
+// running job. Workers call it while processing.
+func (runner *JobRunner) Report(ctx context.Context, id string, progress int, result interface{}) error {
+	job, err := runner.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	job.Progress = progress
+	if result != nil {
+		if job.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+	return runner.store.Update(ctx, job)
+}
+
+// Run processes queued jobs on parallelism goroutines until ctx is
+// cancelled.
+func (runner *JobRunner) Run(ctx context.Context, parallelism int) {
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-runner.queue:
+					runner.process(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (runner *JobRunner) process(ctx context.Context, id string) {
+	job, err := runner.store.Get(ctx, id)
+	if err != nil {
+		runner.logger.Error("Не удалось получить задание", "id", id, "error", err)
+		return
+	}
+
+	started := runner.clock.Now()
+	job.Status = JobRunning
+	job.StartedAt = &started
+	if err = runner.store.Update(ctx, job); err != nil {
+		runner.logger.Error("Не удалось обновить задание", "id", id, "error", err)
+		return
+	}
+
+	processErr := runner.workers[job.Type].Process(ctx, job)
+
+	// The worker may have reported progress and a result meanwhile.
+	if job, err = runner.store.Get(ctx, id); err != nil {
+		runner.logger.Error("Не удалось получить задание", "id", id, "error", err)
+		return
+	}
+	completed := runner.clock.Now()
+	job.CompletedAt = &completed
+	job.Payload = nil
+	if processErr != nil {
+		job.Status = JobFailed
+		job.Error = processErr.Error()
+		runner.logger.Error("Задание завершилось ошибкой", "id", id, "type", job.Type, "error", processErr)
+	} else {
+		job.Status = JobDone
+		job.Progress = 100
+		runner.logger.Info("Задание выполнено", "id", id, "type", job.Type)
+	}
+	if err = runner.store.Update(ctx, job); err != nil {
+		runner.logger.Error("Не удалось обновить задание", "id", id, "error", err)
+	}
+}
+
+// Sweep drops the jobs that finished jobTTL or longer before now and
+// returns how many there were. Queued and running jobs are kept.
+func (runner *JobRunner) Sweep(ctx context.Context, now time.Time) (int, error) {
+	jobs, err := runner.store.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	swept := 0
+	for _, job := range jobs {
+		if job.CompletedAt == nil || now.Sub(*job.CompletedAt) < jobTTL {
+			continue
+		}
+		if err = runner.store.Delete(ctx, job.ID); err != nil {
+			return swept, err
+		}
+		swept++
+	}
+	return swept, nil
+}
+
+// sweepJobs drops the finished jobs older than jobTTL every interval,
+// until ctx is cancelled.
+func (s *Server) sweepJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			swept, err := s.runner.Sweep(ctx, s.clock.Now())
+			if err != nil {
+				s.logger.Error("Не удалось удалить устаревшие задания", "error", err)
+			} else if swept > 0 {
+				s.logger.Debug("Удалены устаревшие задания", "count", swept)
+			}
+		}
+	}
+}
+
+// ImportJobStatus is how GET /jobs and GET /jobs/{id} describe an
+// import job: its status, progress and ImportResult so far.
+type ImportJobStatus struct {
+	ID       string    `json:"job_id"`
+	Status   JobStatus `json:"status"`
+	Progress int       `json:"progress"`
+	// Errors describes every record that could not be imported, and
+	// why the job failed, if it did.
+	Errors    []string  `json:"errors"`
+	Created   int       `json:"created"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// importJobStatus describes an import job for GET /jobs.
+func importJobStatus(job Job) interface{} {
+	var result ImportResult
+	if len(job.Result) > 0 {
+		// The result is written by importWorker, so it decodes.
+		json.Unmarshal(job.Result, &result)
+	}
+	status := ImportJobStatus{
+		ID:        job.ID,
+		Status:    job.Status,
+		Progress:  job.Progress,
+		Errors:    append([]string{}, result.Errors...),
+		Created:   result.Created,
+		CreatedAt: job.CreatedAt,
+	}
+	if job.Error != "" {
+		status.Errors = append(status.Errors, job.Error)
+	}
+	return status
+}
+
+// adminJobView describes any job for the admin API: the Job itself.
+func adminJobView(job Job) interface{} {
+	return job
+}
+
+// writeJobs writes the jobs matching keep, newest first, each as view
+// describes it.
+func (s *Server) writeJobs(writer http.ResponseWriter, request *http.Request, keep func(job Job) bool, view func(job Job) interface{}) {
+	jobs, err := s.sortedJobs(request.Context(), keep)
+	if err != nil {
+		s.logger.Error("Не удалось получить список заданий", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	views := make([]interface{}, len(jobs))
+	for i, job := range jobs {
+		views[i] = view(job)
+	}
+	s.writeJSON(writer, http.StatusOK, views)
+}
+
+// sortedJobs returns the jobs matching keep, newest first.
+func (s *Server) sortedJobs(ctx context.Context, keep func(job Job) bool) ([]Job, error) {
+	all, err := s.jobs.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0, len(all))
+	for _, job := range all {
+		if keep(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs, nil
+}
+
+// writeJob writes the job with the ID given in the URL, as view
+// describes it, if keep accepts it, and responds with a HTTP 404 Not
+// Found otherwise.
+func (s *Server) writeJob(writer http.ResponseWriter, request *http.Request, keep func(job Job) bool, view func(job Job) interface{}) {
+	jobID := chi.URLParam(request, "id")
+	job, err := s.jobs.Get(request.Context(), jobID)
+	if errors.Is(err, ErrJobNotFound) || err == nil && !keep(job) {
+		s.logger.Warn("Задание не найдено", "id", jobID)
+		http.Error(writer, ErrJobNotFound.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось получить задание", "id", jobID, "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, view(job))
+}
+
+func isImportJob(job Job) bool {
+	return job.Type == importJobType
+}
+
+func isAnyJob(Job) bool {
+	return true
+}
+
+// getJobs writes every import job, newest first, in JSON format to the
+// provided http.ResponseWriter, each as an ImportJobStatus. Other jobs
+// are only listed by the admin API.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client.
+func (s *Server) getJobs(writer http.ResponseWriter, request *http.Request) {
+	s.writeJobs(writer, request, isImportJob, importJobStatus)
+}
+
+// getJob writes the import job with the ID given in the URL in JSON
+// format to the provided http.ResponseWriter, as an ImportJobStatus, or
+// responds with a HTTP 404 Not Found if there is no such job.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the job ID.
+func (s *Server) getJob(writer http.ResponseWriter, request *http.Request) {
+	s.writeJob(writer, request, isImportJob, importJobStatus)
+}
+
+// getAdminJobs writes every job, of any type, newest first, in JSON
+// format to the provided http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client.
+func (s *Server) getAdminJobs(writer http.ResponseWriter, request *http.Request) {
+	s.writeJobs(writer, request, isAnyJob, adminJobView)
+}
+
+// getAdminJob writes the job with the ID given in the URL in JSON
+// format to the provided http.ResponseWriter, or responds with a HTTP
+// 404 Not Found if there is no such job.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the job ID.
+func (s *Server) getAdminJob(writer http.ResponseWriter, request *http.Request) {
+	s.writeJob(writer, request, isAnyJob, adminJobView)
+}
+
+// postAdminJob submits the job described by the body, {"type":...,
+// "payload":...}, and responds with a HTTP 202 Accepted and the queued
+// job. An unknown type is rejected with a HTTP 422 Unprocessable
+// Entity, and a full queue with a HTTP 503 Service Unavailable.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, with the job in the body.
+func (s *Server) postAdminJob(writer http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело задания", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := s.runner.Submit(request.Context(), body.Type, body.Payload)
+	if err != nil {
+		s.reportSubmitError(writer, err)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusAccepted, job)
+	s.logger.Info("Поставлено задание", "id", job.ID, "type", job.Type)
+}
+
+// reportSubmitError answers a request whose job could not be submitted.
+func (s *Server) reportSubmitError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownJobType):
+		s.logger.Warn("Неизвестный тип задания", "error", err)
+		http.Error(writer, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrJobQueueFull):
+		s.logger.Warn("Очередь заданий переполнена")
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+	default:
+		s.logger.Error("Не удалось поставить задание", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+	}
+}
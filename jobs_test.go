@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetJobReportsImportStatus(t *testing.T) {
+	s, _ := newTestServer(t)
+	startJobRunner(t, s)
+	job := importTasks(t, s, "/tasks/import", "application/json",
+		`[{"id":"1","description":"Импортированная задача","note":"","applications":[]},{"id":"2"}]`)
+
+	response := serve(s, http.MethodGet, "/jobs/"+job.ID, "")
+	assertStatus(t, response, http.StatusOK)
+	var fields map[string]json.RawMessage
+	decodeJSON(t, response.Body.Bytes(), &fields)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if want := []string{"created", "created_at", "errors", "job_id", "progress", "status"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("fields = %q, want %q", names, want)
+	}
+
+	var status ImportJobStatus
+	decodeJSON(t, response.Body.Bytes(), &status)
+	if status.ID != job.ID || status.Status != JobDone || status.Progress != 100 || status.Created != 1 || len(status.Errors) != 1 {
+		t.Errorf("GET /jobs/%s = %+v", job.ID, status)
+	}
+
+	response = serve(s, http.MethodGet, "/jobs", "")
+	assertStatus(t, response, http.StatusOK)
+	var listed []ImportJobStatus
+	decodeJSON(t, response.Body.Bytes(), &listed)
+	if len(listed) != 1 || !reflect.DeepEqual(listed[0], status) {
+		t.Errorf("GET /jobs = %+v, want [%+v]", listed, status)
+	}
+}
+
+func TestImportJobStatusOfFailedJob(t *testing.T) {
+	status := importJobStatus(Job{ID: "1", Type: importJobType, Status: JobFailed, Error: "job queue is full", CreatedAt: testNow})
+	want := ImportJobStatus{ID: "1", Status: JobFailed, Errors: []string{"job queue is full"}, CreatedAt: testNow}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("importJobStatus() = %+v, want %+v", status, want)
+	}
+}
+
+func TestFinishedJobDropsPayload(t *testing.T) {
+	s, _ := newTestServer(t)
+	startJobRunner(t, s)
+	job := importTasks(t, s, "/tasks/import", "application/json", `[{"id":"1","description":"Задача","note":"","applications":[]}]`)
+	if job.Payload != nil {
+		t.Errorf("payload of a finished job = %s, want none", job.Payload)
+	}
+}
+
+func TestJobRunnerSweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryJobStore()
+	finished := testNow.Add(-time.Hour)
+	for _, job := range []Job{
+		{ID: "done", Status: JobDone, CompletedAt: &finished},
+		{ID: "failed", Status: JobFailed, CompletedAt: &finished},
+		{ID: "queued", Status: JobQueued},
+		{ID: "running", Status: JobRunning},
+	} {
+		if err := store.Create(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runner := NewJobRunner(store, NewFakeClock(testNow), nil)
+
+	if swept, err := runner.Sweep(ctx, finished.Add(jobTTL-time.Second)); err != nil || swept != 0 {
+		t.Fatalf("Sweep() before the TTL = %d, %v, want 0", swept, err)
+	}
+	if swept, err := runner.Sweep(ctx, finished.Add(jobTTL)); err != nil || swept != 2 {
+		t.Fatalf("Sweep() after the TTL = %d, %v, want 2", swept, err)
+	}
+	jobs, err := store.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	sort.Strings(ids)
+	if want := []string{"queued", "running"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("jobs left = %q, want %q", ids, want)
+	}
+}
+
+// webhookReceiver records the requests a webhook delivery makes and
+// answers them with status and answer.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	status   int
+	answer   string
+}
+
+func (receiver *webhookReceiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	receiver.mu.Lock()
+	receiver.requests = append(receiver.requests, request)
+	receiver.bodies = append(receiver.bodies, string(body))
+	receiver.mu.Unlock()
+	writer.WriteHeader(receiver.status)
+	io.WriteString(writer, receiver.answer)
+}
+
+// submitAdminJob posts a job to the admin API and waits for it to finish.
+func submitAdminJob(t *testing.T, s *Server, body string) Job {
+	t.Helper()
+	response := serveAdmin(s, http.MethodPost, "/admin/jobs", body)
+	assertStatus(t, response, http.StatusAccepted)
+	var job Job
+	decodeJSON(t, response.Body.Bytes(), &job)
+	return waitForJob(t, s, job.ID)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+	s := newAdminTestServer(t)
+	startJobRunner(t, s)
+
+	job := submitAdminJob(t, s, `{"type":"webhook-delivery","payload":{"url":"`+target.URL+`/hook","body":{"event":"task.created","id":"1"}}}`)
+	var result WebhookResult
+	if job.Status != JobDone || json.Unmarshal(job.Result, &result) != nil {
+		t.Fatalf("webhook job = %s, %s, error %q", job.Status, job.Result, job.Error)
+	}
+	if result.StatusCode != http.StatusNoContent || result.URL != target.URL+"/hook" || string(result.Payload) != `{"event":"task.created","id":"1"}` {
+		t.Errorf("webhook result = %+v", result)
+	}
+	if len(receiver.requests) != 1 {
+		t.Fatalf("%d deliveries, want 1", len(receiver.requests))
+	}
+	request := receiver.requests[0]
+	if request.Method != http.MethodPost || request.URL.Path != "/hook" || request.Header.Get("Content-Type") != "application/json" || request.Header.Get("X-Webhook-ID") != job.ID {
+		t.Errorf("delivery = %s %s, headers %v", request.Method, request.URL, request.Header)
+	}
+	if want := `{"event":"task.created","id":"1"}`; receiver.bodies[0] != want {
+		t.Errorf("delivered body = %s, want %s", receiver.bodies[0], want)
+	}
+}
+
+func TestWebhookDeliveryFails(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "receiver error", payload: `{"url":"` + target.URL + `","body":{}}`},
+		{name: "not an http URL", payload: `{"url":"file:///etc/passwd","body":{}}`},
+		{name: "no body", payload: `{"url":"` + target.URL + `"}`},
+		{name: "malformed payload", payload: `["` + target.URL + `"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAdminTestServer(t)
+			startJobRunner(t, s)
+			job := submitAdminJob(t, s, `{"type":"webhook-delivery","payload":`+tt.payload+`}`)
+			if job.Status != JobFailed || job.Error == "" {
+				t.Errorf("webhook job = %s, error %q, want failed", job.Status, job.Error)
+			}
+		})
+	}
+	if got := len(receiver.requests); got != 1 {
+		t.Errorf("%d deliveries, want 1", got)
+	}
+}
+
+func TestPostAdminWebhookTest(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusAccepted, answer: "принято"}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+	s := newAdminTestServer(t)
+
+	response := serveAdmin(s, http.MethodPost, "/admin/webhooks/test", `{"event_type":"task.updated","task_id":"1","url":"`+target.URL+`"}`)
+	assertStatus(t, response, http.StatusOK)
+	var delivery WebhookDelivery
+	decodeJSON(t, response.Body.Bytes(), &delivery)
+	if delivery.Status != JobDone || delivery.StatusCode != http.StatusAccepted || delivery.ResponseBody != "принято" || delivery.URL != target.URL {
+		t.Errorf("delivery = %+v", delivery)
+	}
+
+	var event WebhookEvent
+	decodeJSON(t, []byte(receiver.bodies[0]), &event)
+	if event.Event != "task.updated" || event.Task.ID != "1" || event.Task.Description != fixtureTask(t, "1").Description {
+		t.Errorf("delivered event = %+v", event)
+	}
+	if !reflect.DeepEqual(delivery.Payload, json.RawMessage(receiver.bodies[0])) {
+		t.Errorf("payload = %s, want the delivered %s", delivery.Payload, receiver.bodies[0])
+	}
+}
+
+func TestPostAdminWebhookTestFailures(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusInternalServerError, answer: "сломано"}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name           string
+		body           string
+		wantStatus     int
+		wantStatusCode int
+		wantAnswer     string
+	}{
+		{name: "receiver error", body: `{"event_type":"task.created","task_id":"1","url":"` + target.URL + `"}`, wantStatus: http.StatusOK, wantStatusCode: http.StatusInternalServerError, wantAnswer: "сломано"},
+		{name: "receiver unreachable", body: `{"event_type":"task.created","task_id":"1","url":"` + unreachable.URL + `"}`, wantStatus: http.StatusOK},
+		{name: "unknown task", body: `{"event_type":"task.created","task_id":"99","url":"` + target.URL + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown event", body: `{"event_type":"task.renamed","task_id":"1","url":"` + target.URL + `"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "no URL", body: `{"event_type":"task.created","task_id":"1"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", body: `{"event_type":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAdminTestServer(t)
+			response := serveAdmin(s, http.MethodPost, "/admin/webhooks/test", tt.body)
+			assertStatus(t, response, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var delivery WebhookDelivery
+			decodeJSON(t, response.Body.Bytes(), &delivery)
+			if delivery.Status != JobFailed || delivery.Error == "" || delivery.StatusCode != tt.wantStatusCode || delivery.ResponseBody != tt.wantAnswer {
+				t.Errorf("delivery = %+v, want failed with status code %d", delivery, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestGetAdminWebhookDeliveries(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+	s, clock := newTestServerWithConfig(t, Config{AdminSecret: "s3cret"}, LoadFixtures(t))
+	startJobRunner(t, s)
+
+	// An import job, which is no delivery.
+	importTasks(t, s, "/tasks/import", "application/json", `[{"id":"imported","description":"Импорт","note":"","applications":[]}]`)
+	var ids []string
+	for _, event := range []string{"task.created", "task.updated", "task.deleted"} {
+		clock.Advance(time.Second)
+		response := serveAdmin(s, http.MethodPost, "/admin/webhooks/test", `{"event_type":"`+event+`","task_id":"1","url":"`+target.URL+`"}`)
+		assertStatus(t, response, http.StatusOK)
+		var delivery WebhookDelivery
+		decodeJSON(t, response.Body.Bytes(), &delivery)
+		ids = append(ids, delivery.ID)
+	}
+
+	deliveries := func(target string) []string {
+		response := serveAdmin(s, http.MethodGet, target, "")
+		assertStatus(t, response, http.StatusOK)
+		var deliveries []WebhookDelivery
+		decodeJSON(t, response.Body.Bytes(), &deliveries)
+		ids := make([]string, len(deliveries))
+		for i, delivery := range deliveries {
+			if delivery.StatusCode != http.StatusOK || len(delivery.Payload) == 0 {
+				t.Errorf("delivery = %+v", delivery)
+			}
+			ids[i] = delivery.ID
+		}
+		return ids
+	}
+	if got, want := deliveries("/admin/webhooks/deliveries"), []string{ids[2], ids[1], ids[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("deliveries = %q, want %q, newest first", got, want)
+	}
+	if got, want := deliveries("/admin/webhooks/deliveries?limit=2"), []string{ids[2], ids[1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("deliveries with limit=2 = %q, want %q", got, want)
+	}
+	for _, limit := range []string{"0", "101", "many"} {
+		assertStatus(t, serveAdmin(s, http.MethodGet, "/admin/webhooks/deliveries?limit="+limit, ""), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// mergedNoteSeparator separates the notes of merged tasks.
+const mergedNoteSeparator = "\n---\n"
+
+// mergeRequest is the body of POST /tasks/merge.
+type mergeRequest struct {
+	KeepID    string `json:"keep_id"`
+	DiscardID string `json:"discard_id"`
+}
+
+// mergeTasks folds discard into keep: the notes are joined with
+// mergedNoteSeparator, the applications of discard that keep lacks are
+// appended, and every other field keep leaves empty is taken from
+// discard. The ID and creation time of keep are kept.
+func mergeTasks(keep, discard Task) Task {
+	merged := keep
+
+	if merged.Description == "" {
+		merged.Description = discard.Description
+	}
+	switch {
+	case merged.Note == "":
+		merged.Note = discard.Note
+	case discard.Note != "":
+		merged.Note += mergedNoteSeparator + discard.Note
+	}
+
+	seen := make(map[string]bool, len(keep.Applications))
+	merged.Applications = append([]string(nil), keep.Applications...)
+	for _, application := range keep.Applications {
+		seen[application] = true
+	}
+	for _, application := range discard.Applications {
+		if !seen[application] {
+			seen[application] = true
+			merged.Applications = append(merged.Applications, application)
+		}
+	}
+
+	if merged.TTL == nil {
+		merged.TTL = discard.TTL
+	}
+	if merged.SLADeadlineMinutes == 0 {
+		merged.SLADeadlineMinutes = discard.SLADeadlineMinutes
+	}
+	if merged.ProjectID == "" {
+		merged.ProjectID = discard.ProjectID
+	}
+	return merged
+}
+
+// postMergeTasks handles the HTTP request to merge the task named by
+// discard_id in the body into the one named by keep_id (see
+// mergeTasks), delete the former, with its pin, embedding and SLA
+// breach, and write the merged task. If both IDs are the same, it
+// responds with a HTTP 400 Bad Request. If either task does not exist,
+// it responds with a HTTP 404 Not Found: the tasks are named in the
+// body, not the path, so the request itself is well-formed.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, with the IDs in the body.
+func (s *Server) postMergeTasks(writer http.ResponseWriter, request *http.Request) {
+	var body mergeRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело запроса объединения", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.KeepID == body.DiscardID {
+		s.logger.Warn("Задачу нельзя объединить саму с собой", "id", body.KeepID)
+		http.Error(writer, "keep_id and discard_id must differ", http.StatusBadRequest)
+		return
+	}
+
+	tasks := make([]Task, 2)
+	for i, id := range []string{body.KeepID, body.DiscardID} {
+		task, err := s.store.Get(request.Context(), id)
+		if errors.Is(err, ErrNotFound) {
+			s.logger.Warn("Задача для объединения не найдена", "id", id)
+			localizedError(writer, request, MsgTaskNotFound, http.StatusNotFound)
+			return
+		} else if err != nil {
+			s.logger.Error("Не удалось получить задачу", "id", id, "error", err)
+			http.Error(writer, err.Error(), storageErrorStatus(err))
+			return
+		}
+		tasks[i] = task
+	}
+
+	merged := mergeTasks(tasks[0], tasks[1])
+	if err := scheduleReminders(&merged); err != nil {
+		s.logger.Error("Не удалось запланировать напоминания", "id", merged.ID, "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := s.store.Update(request.Context(), merged); err != nil {
+		s.logger.Error("Не удалось сохранить объединённую задачу", "id", merged.ID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	if err := s.store.Delete(request.Context(), body.DiscardID); err != nil && !errors.Is(err, ErrNotFound) {
+		s.logger.Error("Не удалось удалить объединённую задачу", "id", body.DiscardID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	s.forgetTask(body.DiscardID)
+
+	s.presentTask(&merged)
+	s.writeJSON(writer, http.StatusOK, merged)
+	s.logger.Info("Задачи объединены", "keep_id", body.KeepID, "discard_id", body.DiscardID)
+}
+
+// threeWayMergeRequest is the body of POST /tasks/{id}/merge: the
+// version both sides started from and the two edited versions.
+type threeWayMergeRequest struct {
+	Base   Task `json:"base"`
+	Theirs Task `json:"theirs"`
+	Mine   Task `json:"mine"`
+}
+
+// MergeConflict is a field both sides changed, differently.
+type MergeConflict struct {
+	Field  string      `json:"field"`
+	Base   interface{} `json:"base"`
+	Theirs interface{} `json:"theirs"`
+	Mine   interface{} `json:"mine"`
+}
+
+// ThreeWayMerge is the result of POST /tasks/{id}/merge. Conflicting
+// fields keep their base value in Merged.
+type ThreeWayMerge struct {
+	Merged    Task            `json:"merged"`
+	Conflicts []MergeConflict `json:"conflicts"`
+}
+
+// mergeField reads and writes one client-editable field of a task.
+type mergeField struct {
+	name string
+	get  func(task Task) interface{}
+	set  func(task *Task, from Task)
+}
+
+var mergeFields = []mergeField{
+	{"description", func(task Task) interface{} { return task.Description }, func(task *Task, from Task) { task.Description = from.Description }},
+	{"note", func(task Task) interface{} { return task.Note }, func(task *Task, from Task) { task.Note = from.Note }},
+	{"applications", func(task Task) interface{} {
+		if task.Applications == nil {
+			return []string{}
+		}
+		return task.Applications
+	}, func(task *Task, from Task) { task.Applications = from.Applications }},
+	{"ttl", func(task Task) interface{} { return task.TTL }, func(task *Task, from Task) { task.TTL = from.TTL }},
+	{"sla_deadline_minutes", func(task Task) interface{} { return task.SLADeadlineMinutes }, func(task *Task, from Task) { task.SLADeadlineMinutes = from.SLADeadlineMinutes }},
+	{"project_id", func(task Task) interface{} { return task.ProjectID }, func(task *Task, from Task) { task.ProjectID = from.ProjectID }},
+}
+
+// threeWayMerge merges theirs and mine field by field: a field only
+// one side changed from base takes that side's value, and a field both
+// changed the same way takes it too. A field both changed differently
+// keeps the base value and is reported as a conflict.
+func threeWayMerge(base, theirs, mine Task) ThreeWayMerge {
+	result := ThreeWayMerge{Merged: base, Conflicts: []MergeConflict{}}
+	for _, field := range mergeFields {
+		baseValue, theirValue, myValue := field.get(base), field.get(theirs), field.get(mine)
+		switch {
+		case reflect.DeepEqual(baseValue, theirValue), reflect.DeepEqual(theirValue, myValue):
+			field.set(&result.Merged, mine)
+		case reflect.DeepEqual(baseValue, myValue):
+			field.set(&result.Merged, theirs)
+		default:
+			result.Conflicts = append(result.Conflicts, MergeConflict{Field: field.name, Base: baseValue, Theirs: theirValue, Mine: myValue})
+		}
+	}
+	return result
+}
+
+// postThreeWayMerge handles the HTTP request to merge two concurrent
+// edits of the task with the ID given in the URL, from the base,
+// theirs and mine versions in the body (see threeWayMerge). The ID
+// and creation time of the merged task are those of the stored task,
+// which is left unchanged. It responds with the ThreeWayMerge, with a
+// HTTP 200 OK if every field merged cleanly and a HTTP 409 Conflict
+// otherwise. If the task does not exist, it responds with the status
+// chosen by storageErrorStatus.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the task ID and the three versions.
+func (s *Server) postThreeWayMerge(writer http.ResponseWriter, request *http.Request) {
+	var body threeWayMergeRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело запроса слияния", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskID := chi.URLParam(request, "id")
+	task, err := s.store.Get(request.Context(), taskID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("Задача для слияния не найдена", "id", taskID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось получить задачу", "id", taskID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	result := threeWayMerge(body.Base, body.Theirs, body.Mine)
+	result.Merged.ID = task.ID
+	result.Merged.CreatedAt = task.CreatedAt
+	s.presentTask(&result.Merged)
+
+	status := http.StatusOK
+	if len(result.Conflicts) > 0 {
+		status = http.StatusConflict
+	}
+	s.writeJSON(writer, status, result)
+	s.logger.Info("Выполнено трёхстороннее слияние задачи", "id", taskID, "conflicts", len(result.Conflicts))
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeTasks(t *testing.T) {
+	hour := time.Hour
+	keep := Task{ID: "keep", Description: "Сдать отчёт", Note: "черновик готов", Applications: []string{"Word", "git"}, CreatedAt: testNow}
+	discard := Task{ID: "discard", Description: "Отчёт", Note: "приложить таблицы", Applications: []string{"git", "Excel"},
+		CreatedAt: testNow.Add(-time.Hour), TTL: &hour, SLADeadlineMinutes: 90, ProjectID: "q1"}
+
+	merged := mergeTasks(keep, discard)
+	want := Task{ID: "keep", Description: "Сдать отчёт", Note: "черновик готов" + mergedNoteSeparator + "приложить таблицы",
+		Applications: []string{"Word", "git", "Excel"}, CreatedAt: testNow, TTL: &hour, SLADeadlineMinutes: 90, ProjectID: "q1"}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("mergeTasks() = %+v, want %+v", merged, want)
+	}
+
+	// Without a note of its own, keep takes that of discard as it is.
+	keep.Note = ""
+	if merged := mergeTasks(keep, discard); merged.Note != discard.Note {
+		t.Errorf("note = %q, want %q", merged.Note, discard.Note)
+	}
+}
+
+func TestPostMergeTasks(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "merged", body: `{"keep_id":"1","discard_id":"2"}`, wantStatus: http.StatusOK},
+		{name: "unknown keep_id", body: `{"keep_id":"99","discard_id":"2"}`, wantStatus: http.StatusNotFound},
+		{name: "unknown discard_id", body: `{"keep_id":"1","discard_id":"99"}`, wantStatus: http.StatusNotFound},
+		{name: "same task", body: `{"keep_id":"1","discard_id":"1"}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed body", body: `{"keep_id":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+			response := serve(s, http.MethodPost, "/tasks/merge", tt.body)
+			assertStatus(t, response, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				// Nothing was merged away.
+				for _, id := range []string{"1", "2"} {
+					if _, err := s.store.Get(context.Background(), id); err != nil {
+						t.Errorf("task %s: %v", id, err)
+					}
+				}
+				return
+			}
+
+			var merged Task
+			decodeJSON(t, response.Body.Bytes(), &merged)
+			if want := []string{"VS Code", "git", "Postman"}; merged.ID != "1" || !reflect.DeepEqual(merged.Applications, want) {
+				t.Errorf("merged = %+v, want task 1 with applications %q", merged, want)
+			}
+			assertStatus(t, serve(s, http.MethodGet, "/tasks/2", ""), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestPostMergeTasksForgetsDiscardedTask(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	ctx := context.Background()
+
+	discard := fixtureTask(t, "breached")
+	if _, err := s.embed(ctx, discard); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, serve(s, http.MethodPost, "/tasks/breached/pin", `{}`), http.StatusOK)
+	assertStatus(t, serve(s, http.MethodGet, "/tasks/breached", ""), http.StatusOK)
+	if _, ok := s.breaches.Load("breached"); !ok {
+		t.Fatal("the breach of the fixture was not reported")
+	}
+
+	assertStatus(t, serve(s, http.MethodPost, "/tasks/merge", `{"keep_id":"1","discard_id":"breached"}`), http.StatusOK)
+
+	if _, ok := s.embeddings.Get("breached"); ok {
+		t.Error("the embedding of the discarded task is kept")
+	}
+	if _, ok := s.pins.Order("breached"); ok {
+		t.Error("the discarded task is still pinned")
+	}
+	if _, ok := s.breaches.Load("breached"); ok {
+		t.Error("the SLA breach of the discarded task is remembered")
+	}
+}
+
+func TestThreeWayMerge(t *testing.T) {
+	hour, twoHours := time.Hour, 2*time.Hour
+	base := Task{ID: "1", Description: "Отчёт", Note: "черновик", Applications: []string{"Word"}, TTL: &hour, SLADeadlineMinutes: 60}
+	edit := func(change func(task *Task)) Task {
+		task := base
+		task.Applications = append([]string(nil), base.Applications...)
+		change(&task)
+		return task
+	}
+
+	tests := []struct {
+		name          string
+		theirs, mine  Task
+		want          Task
+		wantConflicts []string
+	}{
+		{name: "neither changed", theirs: base, mine: base, want: base},
+		{
+			name:   "only theirs changed",
+			theirs: edit(func(task *Task) { task.Note = "готово" }), mine: base,
+			want: edit(func(task *Task) { task.Note = "готово" }),
+		},
+		{
+			name:   "only mine changed",
+			theirs: base, mine: edit(func(task *Task) { task.Description = "Квартальный отчёт" }),
+			want: edit(func(task *Task) { task.Description = "Квартальный отчёт" }),
+		},
+		{
+			name:   "both changed the same way",
+			theirs: edit(func(task *Task) { task.SLADeadlineMinutes = 90 }), mine: edit(func(task *Task) { task.SLADeadlineMinutes = 90 }),
+			want: edit(func(task *Task) { task.SLADeadlineMinutes = 90 }),
+		},
+		{
+			name:   "different fields changed",
+			theirs: edit(func(task *Task) { task.Note = "готово" }), mine: edit(func(task *Task) { task.ProjectID = "q1" }),
+			want: edit(func(task *Task) { task.Note = "готово"; task.ProjectID = "q1" }),
+		},
+		{
+			name:   "both changed differently",
+			theirs: edit(func(task *Task) { task.Note = "готово"; task.TTL = &twoHours }), mine: edit(func(task *Task) { task.Note = "отменено"; task.TTL = nil }),
+			want: base, wantConflicts: []string{"note", "ttl"},
+		},
+		{
+			name:   "applications reordered on one side",
+			theirs: base, mine: edit(func(task *Task) { task.Applications = []string{"git", "Word"} }),
+			want: edit(func(task *Task) { task.Applications = []string{"git", "Word"} }),
+		},
+		{
+			name:   "applications changed differently",
+			theirs: edit(func(task *Task) { task.Applications = append(task.Applications, "git") }), mine: edit(func(task *Task) { task.Applications = nil }),
+			want: base, wantConflicts: []string{"applications"},
+		},
+		{
+			name:   "equal TTLs at different addresses",
+			theirs: edit(func(task *Task) { ttl := time.Hour; task.TTL = &ttl }), mine: edit(func(task *Task) { task.Note = "готово" }),
+			want: edit(func(task *Task) { task.Note = "готово" }),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := threeWayMerge(base, tt.theirs, tt.mine)
+			if !reflect.DeepEqual(result.Merged, tt.want) {
+				t.Errorf("merged = %+v, want %+v", result.Merged, tt.want)
+			}
+			fields := []string{}
+			for _, conflict := range result.Conflicts {
+				fields = append(fields, conflict.Field)
+			}
+			if tt.wantConflicts == nil {
+				tt.wantConflicts = []string{}
+			}
+			if !reflect.DeepEqual(fields, tt.wantConflicts) {
+				t.Errorf("conflicts = %q, want %q", fields, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestThreeWayMergeConflictValues(t *testing.T) {
+	base := Task{Note: "черновик"}
+	result := threeWayMerge(base, Task{Note: "готово"}, Task{Note: "отменено"})
+	want := []MergeConflict{{Field: "note", Base: "черновик", Theirs: "готово", Mine: "отменено"}}
+	if !reflect.DeepEqual(result.Conflicts, want) {
+		t.Errorf("conflicts = %+v, want %+v", result.Conflicts, want)
+	}
+}
+
+func TestPostThreeWayMerge(t *testing.T) {
+	const base = `{"description":"Сделать финальное задание","note":"Ура!","applications":["VS Code","git"]}`
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+		wantNote   string
+	}{
+		{
+			name: "clean", target: "/tasks/1/merge",
+			body:       `{"base":` + base + `,"theirs":` + base + `,"mine":{"description":"Сделать финальное задание","note":"Готово","applications":["VS Code","git"]}}`,
+			wantStatus: http.StatusOK, wantNote: "Готово",
+		},
+		{
+			name: "conflict", target: "/tasks/1/merge",
+			body:       `{"base":` + base + `,"theirs":{"description":"Сделать финальное задание","note":"Завтра"},"mine":{"description":"Сделать финальное задание","note":"Готово"}}`,
+			wantStatus: http.StatusConflict, wantNote: "Ура!",
+		},
+		{name: "unknown task", target: "/tasks/99/merge", body: `{"base":{},"theirs":{},"mine":{}}`, wantStatus: http.StatusBadRequest},
+		{name: "missing version", target: "/tasks/1/merge", body: `{"base":{},"theirs":{}}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", target: "/tasks/1/merge", body: `{"base":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+			response := serve(s, http.MethodPost, tt.target, tt.body)
+			assertStatus(t, response, tt.wantStatus)
+			if tt.wantNote == "" {
+				return
+			}
+			var result ThreeWayMerge
+			decodeJSON(t, response.Body.Bytes(), &result)
+			stored := fixtureTask(t, "1")
+			if result.Merged.Note != tt.wantNote || result.Merged.ID != "1" || !result.Merged.CreatedAt.Equal(stored.CreatedAt) {
+				t.Errorf("merged = %+v, want note %q with the ID and creation time of task 1", result.Merged, tt.wantNote)
+			}
+			// The merge is only computed; the stored task is unchanged.
+			if task, err := s.store.Get(context.Background(), "1"); err != nil || task.Note != stored.Note {
+				t.Errorf("stored task = %+v, %v, want it unchanged", task, err)
+			}
+		})
+	}
+}
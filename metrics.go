@@ -0,0 +1,35 @@
+package main
+
+import "strings"
+
+// wordsPerMinute is the reading speed reading times are estimated with.
+const wordsPerMinute = 200
+
+// ComputeMetrics sets the word count of the task's description and
+// note, and the time it takes to read them in seconds, rounded up.
+// Words are runs of non-whitespace characters. The metrics are derived
+// when a task is written to a client; stored values are ignored.
+func ComputeMetrics(task *Task) {
+	words := len(strings.Fields(task.Description)) + len(strings.Fields(task.Note))
+	task.WordCount = words
+	task.ReadingTimeSeconds = (words*60 + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// presentTask sets the fields of a task that are computed when it is
+// written to a client: the metrics, the SLA status, the computed
+// priority and the pin order.
+func (s *Server) presentTask(task *Task) {
+	ComputeMetrics(task)
+
+	now := s.clock.Now()
+	task.SLAStatus = slaStatus(*task, now)
+	task.ComputedPriority = computedPriority(*task, now)
+	if task.SLAStatus == SLABreached {
+		s.reportBreach(*task)
+	}
+
+	task.PinOrder = nil
+	if order, pinned := s.pins.Order(task.ID); pinned {
+		task.PinOrder = &order
+	}
+}
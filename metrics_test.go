@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestComputeMetrics(t *testing.T) {
+	tests := []struct {
+		name              string
+		description, note string
+		wantWords         int
+		wantSeconds       int
+	}{
+		{name: "empty"},
+		{name: "one Russian word", description: "Задача", wantWords: 1, wantSeconds: 1},
+		{
+			name: "description and note", description: "Сделать финальное задание темы REST API",
+			note: "Если сегодня сделаю, то завтра будет свободный день. Ура!", wantWords: 15, wantSeconds: 5,
+		},
+		{name: "repeated and unusual spaces", description: "  ещё одна\tзадача\n\nсо  сносками  ", wantWords: 5, wantSeconds: 2},
+		{name: "separate punctuation is a word", description: "Позвонить — обязательно !", wantWords: 4, wantSeconds: 2},
+		{name: "note only", note: "Ёлка, щётка, объём", wantWords: 3, wantSeconds: 1},
+		// 200 words are read in exactly a minute.
+		{name: "a minute of reading", description: strings.Repeat("слово ", 200), wantWords: 200, wantSeconds: 60},
+		{name: "rounded up", description: strings.Repeat("слово ", 201), wantWords: 201, wantSeconds: 61},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := Task{Description: tt.description, Note: tt.note, WordCount: 99, ReadingTimeSeconds: 99}
+			ComputeMetrics(&task)
+			if task.WordCount != tt.wantWords || task.ReadingTimeSeconds != tt.wantSeconds {
+				t.Errorf("ComputeMetrics() = %d words, %d s; want %d words, %d s",
+					task.WordCount, task.ReadingTimeSeconds, tt.wantWords, tt.wantSeconds)
+			}
+		})
+	}
+}
+
+func TestMetricsOmittedWhenEmpty(t *testing.T) {
+	task := Task{ID: "1", Applications: []string{}}
+	ComputeMetrics(&task)
+	encoded, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	decodeJSON(t, encoded, &fields)
+	for _, name := range []string{"word_count", "reading_time_seconds"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("%s is set for a task without text: %s", name, encoded)
+		}
+	}
+}
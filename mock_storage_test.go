@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MockStorage is a Storage whose methods call the function fields of
+// the same name, so a test can make the storage return whatever data
+// or error it needs. A method whose field is nil returns an error
+// saying so. Every call is recorded in Calls, with its arguments.
+type MockStorage struct {
+	GetAllFn        func(ctx context.Context, filter Filter) ([]Task, error)
+	GetFn           func(ctx context.Context, id string) (Task, error)
+	CreateFn        func(ctx context.Context, task Task) error
+	UpdateFn        func(ctx context.Context, task Task) error
+	DeleteFn        func(ctx context.Context, id string) error
+	SearchFn        func(ctx context.Context, query string, fuzziness int) ([]SearchResult, bool, error)
+	AutocompleteFn  func(ctx context.Context, prefix string, limit int) ([]string, error)
+	CreateUniqueFn  func(ctx context.Context, task Task) error
+	SnapshotFn      func(ctx context.Context) (uint64, []Task, error)
+	ChangesFn       func(ctx context.Context, since uint64) (uint64, []TaskChange, error)
+	WaitForChangeFn func(ctx context.Context, since uint64) (uint64, error)
+
+	mu    sync.Mutex
+	calls []MockCall
+}
+
+// MockCall is a call of a MockStorage method. Args leave out the
+// context.
+type MockCall struct {
+	Method string
+	Args   []interface{}
+}
+
+// Calls returns the calls made so far, oldest first.
+func (storage *MockStorage) Calls() []MockCall {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	return append([]MockCall(nil), storage.calls...)
+}
+
+// CallsTo returns the calls of the named method made so far.
+func (storage *MockStorage) CallsTo(method string) []MockCall {
+	var calls []MockCall
+	for _, call := range storage.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (storage *MockStorage) record(method string, args ...interface{}) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	storage.calls = append(storage.calls, MockCall{Method: method, Args: args})
+}
+
+func errMockNotSet(method string) error {
+	return fmt.Errorf("MockStorage.%sFn is not set", method)
+}
+
+func (storage *MockStorage) GetAll(ctx context.Context, filter Filter) ([]Task, error) {
+	storage.record("GetAll", filter)
+	if storage.GetAllFn == nil {
+		return nil, errMockNotSet("GetAll")
+	}
+	return storage.GetAllFn(ctx, filter)
+}
+
+func (storage *MockStorage) Get(ctx context.Context, id string) (Task, error) {
+	storage.record("Get", id)
+	if storage.GetFn == nil {
+		return Task{}, errMockNotSet("Get")
+	}
+	return storage.GetFn(ctx, id)
+}
+
+func (storage *MockStorage) Create(ctx context.Context, task Task) error {
+	storage.record("Create", task)
+	if storage.CreateFn == nil {
+		return errMockNotSet("Create")
+	}
+	return storage.CreateFn(ctx, task)
+}
+
+func (storage *MockStorage) Update(ctx context.Context, task Task) error {
+	storage.record("Update", task)
+	if storage.UpdateFn == nil {
+		return errMockNotSet("Update")
+	}
+	return storage.UpdateFn(ctx, task)
+}
+
+func (storage *MockStorage) Delete(ctx context.Context, id string) error {
+	storage.record("Delete", id)
+	if storage.DeleteFn == nil {
+		return errMockNotSet("Delete")
+	}
+	return storage.DeleteFn(ctx, id)
+}
+
+func (storage *MockStorage) Search(ctx context.Context, query string, fuzziness int) ([]SearchResult, bool, error) {
+	storage.record("Search", query, fuzziness)
+	if storage.SearchFn == nil {
+		return nil, false, errMockNotSet("Search")
+	}
+	return storage.SearchFn(ctx, query, fuzziness)
+}
+
+func (storage *MockStorage) Autocomplete(ctx context.Context, prefix string, limit int) ([]string, error) {
+	storage.record("Autocomplete", prefix, limit)
+	if storage.AutocompleteFn == nil {
+		return nil, errMockNotSet("Autocomplete")
+	}
+	return storage.AutocompleteFn(ctx, prefix, limit)
+}
+
+func (storage *MockStorage) CreateUnique(ctx context.Context, task Task) error {
+	storage.record("CreateUnique", task)
+	if storage.CreateUniqueFn == nil {
+		return errMockNotSet("CreateUnique")
+	}
+	return storage.CreateUniqueFn(ctx, task)
+}
+
+func (storage *MockStorage) Snapshot(ctx context.Context) (uint64, []Task, error) {
+	storage.record("Snapshot")
+	if storage.SnapshotFn == nil {
+		return 0, nil, errMockNotSet("Snapshot")
+	}
+	return storage.SnapshotFn(ctx)
+}
+
+func (storage *MockStorage) Changes(ctx context.Context, since uint64) (uint64, []TaskChange, error) {
+	storage.record("Changes", since)
+	if storage.ChangesFn == nil {
+		return 0, nil, errMockNotSet("Changes")
+	}
+	return storage.ChangesFn(ctx, since)
+}
+
+func (storage *MockStorage) WaitForChange(ctx context.Context, since uint64) (uint64, error) {
+	storage.record("WaitForChange", since)
+	if storage.WaitForChangeFn == nil {
+		return 0, errMockNotSet("WaitForChange")
+	}
+	return storage.WaitForChangeFn(ctx, since)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ErrNotPinned is returned when a pin is placed after, or removed
+// from, a task that is not pinned.
+var ErrNotPinned = errors.New("task is not pinned")
+
+// PinBoard keeps the IDs of the pinned tasks in pin order. The pin
+// order of a task is its position on the board, starting at 0.
+type PinBoard struct {
+	mu  sync.RWMutex
+	ids []string
+}
+
+// Pin moves the task to just after afterID, or to the top if afterID
+// is empty, and returns its new pin order. It returns ErrNotPinned if
+// afterID is not pinned.
+func (board *PinBoard) Pin(id, afterID string) (int, error) {
+	board.mu.Lock()
+	defer board.mu.Unlock()
+
+	if afterID != "" && board.index(afterID) < 0 {
+		return 0, ErrNotPinned
+	}
+	board.remove(id)
+	position := 0
+	if afterID != "" {
+		position = board.index(afterID) + 1
+	}
+
+	board.ids = append(board.ids, "")
+	copy(board.ids[position+1:], board.ids[position:])
+	board.ids[position] = id
+	return position, nil
+}
+
+// Unpin removes the task from the board. It returns ErrNotPinned if the
+// task was not pinned.
+func (board *PinBoard) Unpin(id string) error {
+	board.mu.Lock()
+	defer board.mu.Unlock()
+
+	if !board.remove(id) {
+		return ErrNotPinned
+	}
+	return nil
+}
+
+// Order returns the pin order of the task, or false if it is not pinned.
+func (board *PinBoard) Order(id string) (int, bool) {
+	board.mu.RLock()
+	defer board.mu.RUnlock()
+
+	index := board.index(id)
+	return index, index >= 0
+}
+
+func (board *PinBoard) index(id string) int {
+	for i, pinned := range board.ids {
+		if pinned == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (board *PinBoard) remove(id string) bool {
+	index := board.index(id)
+	if index < 0 {
+		return false
+	}
+	board.ids = append(board.ids[:index], board.ids[index+1:]...)
+	return true
+}
+
+// pinRequest is the body of POST /tasks/{id}/pin.
+type pinRequest struct {
+	AfterID string `json:"after_id"`
+}
+
+// pinTask handles the HTTP request to pin the task with the ID given
+// in the URL. The body names the pinned task to place it after, as
+// {"after_id":"2"}; an empty object pins it at the top. A task that is
+// already pinned is moved. It responds with the task ID and its new
+// pin order. If the task does not exist, it responds with the status
+// chosen by storageErrorStatus; if after_id is not pinned or is the
+// task itself, with a HTTP 422 Unprocessable Entity.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the task ID and the position.
+func (s *Server) pinTask(writer http.ResponseWriter, request *http.Request) {
+	taskID := chi.URLParam(request, "id")
+
+	var body pinRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело запроса закрепления", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.store.Get(request.Context(), taskID); errors.Is(err, ErrNotFound) {
+		s.logger.Warn("Задача для закрепления не найдена", "id", taskID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось получить задачу", "id", taskID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	if body.AfterID == taskID {
+		http.Error(writer, "A task cannot be pinned after itself", http.StatusUnprocessableEntity)
+		return
+	}
+	order, err := s.pins.Pin(taskID, body.AfterID)
+	if err != nil {
+		s.logger.Warn("Задача, после которой закрепляют, не закреплена", "id", taskID, "after_id", body.AfterID)
+		http.Error(writer, "after_id: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, map[string]interface{}{"id": taskID, "pin_order": order})
+	s.logger.Info("Задача закреплена", "id", taskID, "pin_order", order)
+}
+
+// unpinTask handles the HTTP request to unpin the task with the ID
+// given in the URL. It responds with a HTTP 204 No Content, or with a
+// HTTP 400 Bad Request if the task is not pinned.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the task ID.
+func (s *Server) unpinTask(writer http.ResponseWriter, request *http.Request) {
+	taskID := chi.URLParam(request, "id")
+	if err := s.pins.Unpin(taskID); err != nil {
+		s.logger.Warn("Задача для открепления не закреплена", "id", taskID)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	s.logger.Info("Задача откреплена", "id", taskID)
+}
@@ -2,21 +2,58 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type Task struct {
-	ID           string   `json:"id"`
-	Description  string   `json:"description"`
-	Note         string   `json:"note"`
-	Applications []string `json:"applications"`
+	ID           string         `json:"id"`
+	Description  string         `json:"description"`
+	Note         string         `json:"note"`
+	Applications []string       `json:"applications"`
+	CreatedAt    time.Time      `json:"created_at"`
+	TTL          *time.Duration `json:"ttl,omitempty"`
+	// WordCount and ReadingTimeSeconds are computed by ComputeMetrics
+	// and left out while the description and note are empty.
+	WordCount          int `json:"word_count,omitempty"`
+	ReadingTimeSeconds int `json:"reading_time_seconds,omitempty"`
+	// SLADeadlineMinutes is how long after its creation the task is
+	// due, zero for no deadline. SLAStatus is computed from it when
+	// the task is written to a client, see slaStatus.
+	SLADeadlineMinutes int    `json:"sla_deadline_minutes,omitempty"`
+	SLAStatus          string `json:"sla_status,omitempty"`
+	// ComputedPriority is computed by computedPriority when the task
+	// is written to a client.
+	ComputedPriority float64 `json:"computed_priority"`
+	// PinOrder is the position of the task among the pinned tasks,
+	// or nil if it is not pinned. It is taken from the PinBoard when
+	// the task is written to a client.
+	PinOrder *int `json:"pin_order,omitempty"`
+	// SuggestedTags are the tags the Categorizer suggested when the
+	// task was created.
+	SuggestedTags []string `json:"suggested_tags,omitempty"`
+	// ProjectID is the project the task belongs to, if any.
+	ProjectID string `json:"project_id,omitempty"`
+	// SplitFrom is the ID of the task this one was split off by
+	// POST /tasks/{id}/split, if any.
+	SplitFrom string `json:"split_from,omitempty"`
+	// Reminders are sent ahead of the SLA deadline, see
+	// scheduleReminders.
+	Reminders []Reminder `json:"reminders,omitempty"`
 }
 
-var tasks = map[string]Task{
+// seedTasks are loaded into the storage when the server starts.
+var seedTasks = map[string]Task{
 	"1": {
 		ID:          "1",
 		Description: "Сделать финальное задание темы REST API",
@@ -42,17 +79,103 @@ var tasks = map[string]Task{
 
 // getTasks handles the HTTP request to retrieve a list of tasks.
 // It writes the tasks in JSON format to the provided http.ResponseWriter.
+// If the filter query parameter is present, only the tasks matching
+// the expression (see ParseFilter) are written; saved_search names a
+// SavedSearch whose query applies as well, and answers a HTTP 404 Not
+// Found if there is no such search. Without the sort query
+// parameter the tasks are written as an object keyed by task ID; with
+// it (see ParseSort) they are written as an array in the requested order;
+// sort=-computed_priority puts the most pressing tasks first.
+// The fields query parameter (see ParseFields) limits each written task
+// to the listed fields, and the sla_status query parameter keeps only
+// the tasks with that SLA status (see slaStatus). The pinned query
+// parameter keeps only the pinned or only the unpinned tasks; pinned
+// tasks are written as an array in pin order unless sort is given.
 //
-// In case of an error during the JSON marshaling process,
-// it responds with an HTTP 500 Internal Server Error and
-// writes the error message to the response body.
+// If the filter expression, the sort, the fields, pinned or the SLA
+// status are malformed, it responds with an HTTP 400 Bad Request and
+// the reason. If the request context is cancelled while the tasks are
+// being filtered, it stops early and responds with an HTTP 503 Service
+// Unavailable. In case of an error from the storage or during the JSON
+// marshaling process, it responds with an HTTP 500 Internal Server
+// Error and writes the error message to the response body.
 //
 // Parameters:
 //   - writer: The http.ResponseWriter used to write the response.
-//   - req: The http.Request received from the client. This parameter is ignored in this function.
-func getTasks(writer http.ResponseWriter, _ *http.Request) {
-	response, err := json.Marshal(tasks)
+//   - request: The http.Request received from the client, including the optional filter,
+//     saved_search, sort, fields, pinned and sla_status query parameters.
+func (s *Server) getTasks(writer http.ResponseWriter, request *http.Request) {
+	query, ok := s.parseTaskQuery(writer, request)
+	if !ok {
+		return
+	}
+
+	var specs []SortSpec
+	if param := request.URL.Query().Get("sort"); param != "" {
+		var err error
+		if specs, err = ParseSort(param); err != nil {
+			s.logger.Warn("Некорректная сортировка задач", "sort", param, "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if query.pinned != nil && *query.pinned {
+		specs = []SortSpec{{Field: "pin_order"}}
+	}
+
+	var fields []string
+	if param := request.URL.Query().Get("fields"); param != "" {
+		var err error
+		if fields, err = ParseFields(param); err != nil {
+			s.logger.Warn("Некорректный список полей задачи", "fields", param, "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	matching, err := s.queryTasks(request.Context(), query)
 	if err != nil {
+		status := storageErrorStatus(err)
+		if status == http.StatusServiceUnavailable {
+			s.logger.Warn("Запрос списка задач прерван", "error", err)
+		} else {
+			s.logger.Error("Не удалось получить список задач", "error", err)
+		}
+		http.Error(writer, err.Error(), status)
+		return
+	}
+
+	var view interface{}
+	if specs == nil {
+		if fields == nil {
+			byID := make(map[string]Task, len(matching))
+			for _, task := range matching {
+				byID[task.ID] = task
+			}
+			view = byID
+		} else {
+			sparse := make(map[string]sparseTask, len(matching))
+			for _, task := range matching {
+				sparse[task.ID] = sparseTask{task: task, fields: fields}
+			}
+			view = sparse
+		}
+	} else {
+		sort.Stable(TaskSorter{Tasks: matching, Specs: specs})
+
+		if fields == nil {
+			view = matching
+		} else {
+			sparse := make([]sparseTask, len(matching))
+			for i, task := range matching {
+				sparse[i] = sparseTask{task: task, fields: fields}
+			}
+			view = sparse
+		}
+	}
+
+	response, err := json.Marshal(view)
+	if err != nil {
+		s.logger.Error("Не удалось сериализовать список задач", "error", err)
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -60,28 +183,141 @@ func getTasks(writer http.ResponseWriter, _ *http.Request) {
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusOK)
 	writer.Write(response)
+	s.logger.Info("Отправлен список задач", "count", len(matching))
+}
+
+// taskQuery selects the tasks of a GET or HEAD /tasks request.
+type taskQuery struct {
+	filter Filter
+	// pinned, if not nil, keeps only the pinned or the unpinned tasks.
+	pinned *bool
+	// sla, if not empty, keeps only the tasks with that SLA status.
+	sla string
+}
+
+// parseTaskQuery reads the filter and saved_search (see
+// requestFilter), pinned and sla_status query parameters of a GET or
+// HEAD /tasks request. It answers the request itself, and returns
+// false, if one of them is malformed.
+func (s *Server) parseTaskQuery(writer http.ResponseWriter, request *http.Request) (taskQuery, bool) {
+	filter, ok := s.requestFilter(writer, request)
+	if !ok {
+		return taskQuery{}, false
+	}
+	query := taskQuery{filter: filter}
+
+	if param := request.URL.Query().Get("pinned"); param != "" {
+		value, err := strconv.ParseBool(param)
+		if err != nil {
+			s.logger.Warn("Некорректный параметр pinned", "pinned", param, "error", err)
+			http.Error(writer, "pinned must be true or false", http.StatusBadRequest)
+			return taskQuery{}, false
+		}
+		query.pinned = &value
+	}
+
+	if param := request.URL.Query().Get("sla_status"); param != "" {
+		var err error
+		if query.sla, err = parseSLAStatus(param); err != nil {
+			s.logger.Warn("Некорректный статус SLA", "sla_status", param, "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return taskQuery{}, false
+		}
+	}
+	return query, true
+}
+
+// queryTasks returns the tasks selected by query, presented as they
+// are written to clients, in no particular order.
+func (s *Server) queryTasks(ctx context.Context, query taskQuery) ([]Task, error) {
+	matching, err := s.store.GetAll(ctx, query.filter)
+	if err != nil {
+		return nil, err
+	}
+	presented := matching[:0]
+	for _, task := range matching {
+		s.presentTask(&task)
+		if (query.sla == "" || task.SLAStatus == query.sla) && (query.pinned == nil || *query.pinned == (task.PinOrder != nil)) {
+			presented = append(presented, task)
+		}
+	}
+	return presented, nil
+}
+
+// countTasks handles the HTTP HEAD request for the task list. It
+// writes no body, only the number of tasks GET /tasks would write for
+// the same filter, saved_search, pinned and sla_status query
+// parameters (see parseTaskQuery) in the X-Total-Count header. A
+// malformed parameter is rejected with a HTTP 400 Bad Request, and an
+// unknown saved search with a HTTP 404 Not Found; a storage error is
+// reported with the status chosen by storageErrorStatus.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the optional filter,
+//     saved_search, pinned and sla_status query parameters.
+func (s *Server) countTasks(writer http.ResponseWriter, request *http.Request) {
+	query, ok := s.parseTaskQuery(writer, request)
+	if !ok {
+		return
+	}
+
+	matching, err := s.queryTasks(request.Context(), query)
+	if err != nil {
+		s.logger.Error("Не удалось подсчитать задачи", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	writer.Header().Set("X-Total-Count", strconv.Itoa(len(matching)))
+	writer.WriteHeader(http.StatusOK)
 }
 
 // getTask retrieves a task by its ID from the provided URL parameter
 // and writes its JSON representation to the http.ResponseWriter. If
 // the task is not found, it sends a HTTP 400 Bad Request response.
-// In case of an error during JSON marshaling, it responds with
-// a HTTP 400 Bad Request along with the error message.
+// The fields query parameter (see ParseFields) limits the response to
+// the listed fields; unknown field names are rejected with a HTTP 400
+// Bad Request. In case of an error during JSON marshaling, it responds
+// with a HTTP 400 Bad Request along with the error message.
 //
 // Parameters:
 //   - writer: The http.ResponseWriter used to send the response to the client.
 //   - request: The http.Request object that contains the URL parameters,
-//     including the task ID to be retrieved.
-func getTask(writer http.ResponseWriter, request *http.Request) {
+//     including the task ID to be retrieved, and the optional fields query parameter.
+func (s *Server) getTask(writer http.ResponseWriter, request *http.Request) {
+	var fields []string
+	if param := request.URL.Query().Get("fields"); param != "" {
+		var err error
+		if fields, err = ParseFields(param); err != nil {
+			s.logger.Warn("Некорректный список полей задачи", "fields", param, "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	targetID := chi.URLParam(request, "id")
-	task, wasFound := tasks[targetID]
-	if !wasFound {
-		http.Error(writer, "Task with given ID was not found", http.StatusBadRequest)
+	task, err := s.store.Get(request.Context(), targetID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("Задача не найдена", "id", targetID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	}
+	if err != nil {
+		s.logger.Error("Не удалось получить задачу", "id", targetID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
 		return
 	}
 
-	response, err := json.Marshal(task)
+	s.presentTask(&task)
+	var view interface{} = task
+	if fields != nil {
+		view = sparseTask{task: task, fields: fields}
+	}
+
+	response, err := json.Marshal(view)
 	if err != nil {
+		s.logger.Error("Не удалось сериализовать задачу", "id", targetID, "error", err)
 		http.Error(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -89,36 +325,122 @@ func getTask(writer http.ResponseWriter, request *http.Request) {
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusOK)
 	writer.Write(response)
+	s.logger.Info("Отправлена задача", "id", targetID)
 }
 
 // postTask handles the creation of a new task. It reads the task's
 // information from the HTTP request body, unmarshals it into a
-// Task struct, and stores it in the tasks map. Upon successful
-// creation, it responds with a HTTP 201 Created status. If any
-// errors occur during reading the request body or unmarshaling,
-// it responds with a HTTP 400 Bad Request along with the error message.
+// Task struct, stamps its creation time and stores it in the
+// storage. The body has already been checked against the request
+// schema by validateRequest. The suggested_tags of the body, such as
+// POST /tasks/quick-capture returns, are kept along with the tags the
+// categorizer suggests. Unless disabled by a feature flag, HTML tags
+// are stripped from the text fields, and a description left empty by
+// that is rejected with a HTTP 422 Unprocessable Entity. Upon
+// successful creation, it responds with a HTTP 201 Created status. If
+// any errors occur during reading the request body or unmarshaling, it
+// responds with a HTTP 400 Bad Request along with the error message.
+// If a task with the same ID already exists, it responds with a
+// HTTP 409 Conflict, and if its project does not exist, or it has
+// reminders and no SLA deadline to schedule them from, with a HTTP
+// 422 Unprocessable Entity. Unless the force query parameter is true,
+// a task whose description equals that of an existing task, ignoring
+// case, is also refused with a HTTP 409 Conflict, whose JSON body
+// names the existing task in existing_id.
 //
 // Parameters:
 //   - writer: The http.ResponseWriter used to send the response to the client.
-//   - request: The http.Request object that contains the new task data in the body.
-func postTask(writer http.ResponseWriter, request *http.Request) {
+//   - request: The http.Request object that contains the new task data in the body,
+//     and the optional force query parameter.
+func (s *Server) postTask(writer http.ResponseWriter, request *http.Request) {
 	var newTask Task
 	var buffer bytes.Buffer
 
 	_, err := buffer.ReadFrom(request.Body)
 	if err != nil {
+		s.logger.Warn("Не удалось прочитать тело запроса", "error", err)
 		http.Error(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	if err = json.Unmarshal(buffer.Bytes(), &newTask); err != nil {
+		s.logger.Warn("Некорректное тело задачи", "error", err)
 		http.Error(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	tasks[newTask.ID] = newTask
+	if s.featureFlags().EnableHTMLSanitization {
+		sanitizeTask(&newTask)
+		if strings.TrimSpace(newTask.Description) == "" {
+			s.logger.Warn("Описание задачи пусто после удаления HTML", "id", newTask.ID)
+			localizedError(writer, request, MsgTaskDescriptionEmpty, http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	force := false
+	if param := request.URL.Query().Get("force"); param != "" {
+		if force, err = strconv.ParseBool(param); err != nil {
+			s.logger.Warn("Некорректный параметр force", "force", param, "error", err)
+			http.Error(writer, "force must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+	if newTask.ProjectID != "" {
+		if _, err = s.projects.Get(request.Context(), newTask.ProjectID); errors.Is(err, ErrProjectNotFound) {
+			s.logger.Warn("Проект задачи не найден", "id", newTask.ID, "project_id", newTask.ProjectID)
+			localizedError(writer, request, MsgTaskProjectMissing, http.StatusUnprocessableEntity)
+			return
+		} else if err != nil {
+			s.logger.Error("Не удалось получить проект задачи", "id", newTask.ID, "project_id", newTask.ProjectID, "error", err)
+			http.Error(writer, err.Error(), storageErrorStatus(err))
+			return
+		}
+	}
+
+	newTask.CreatedAt = s.clock.Now()
+	if err = scheduleReminders(&newTask); errors.Is(err, errReminderWithoutDeadline) {
+		s.logger.Warn("Напоминания задачи без срока SLA", "id", newTask.ID)
+		localizedError(writer, request, MsgTaskReminderNoDeadline, http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось запланировать напоминания", "id", newTask.ID, "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	newTask.SuggestedTags = mergeTags(newTask.SuggestedTags, s.categorizer.Categorize(request.Context(), newTask.Description))
+	create := s.store.CreateUnique
+	if force {
+		create = s.store.Create
+	}
+	var duplicate *DuplicateDescriptionError
+	if err = create(request.Context(), newTask); errors.Is(err, ErrAlreadyExists) {
+		s.logger.Warn("Задача с таким ID уже существует", "id", newTask.ID)
+		localizedError(writer, request, MsgTaskAlreadyExists, storageErrorStatus(err))
+		return
+	} else if errors.As(err, &duplicate) {
+		s.logger.Warn("Задача с таким описанием уже существует", "id", newTask.ID, "existing_id", duplicate.ExistingID)
+		message, tag := catalog.Message(request.Header.Get("Accept-Language"), MsgTaskDuplicate)
+		writer.Header().Set("Content-Language", tag.String())
+		s.writeJSON(writer, storageErrorStatus(err), map[string]string{"message": message, "existing_id": duplicate.ExistingID})
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось сохранить задачу", "id", newTask.ID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	if s.queue != nil && s.featureFlags().EnableTaskQueue {
+		if err = s.queue.Enqueue(request.Context(), newTask); err != nil {
+			s.logger.Error("Не удалось поставить задачу в очередь", "id", newTask.ID, "error", err)
+		}
+	}
+
+	s.embedCreated(request.Context(), newTask)
+
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusCreated)
+	s.logger.Info("Создана задача", "id", newTask.ID)
 }
 
 // deleteTask handles the deletion of a task identified by the
@@ -130,31 +452,88 @@ func postTask(writer http.ResponseWriter, request *http.Request) {
 // Parameters:
 //   - writer: The http.ResponseWriter used to send the response to the client.
 //   - request: The http.Request object that contains the URL parameters, including the task ID.
-func deleteTask(writer http.ResponseWriter, request *http.Request) {
+func (s *Server) deleteTask(writer http.ResponseWriter, request *http.Request) {
 	taskID := chi.URLParam(request, "id")
 
-	_, wasFound := tasks[taskID]
-	if !wasFound {
-		http.Error(writer, "Task was not found.", http.StatusBadRequest)
+	err := s.store.Delete(request.Context(), taskID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("Задача для удаления не найдена", "id", taskID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	}
+	if err != nil {
+		s.logger.Error("Не удалось удалить задачу", "id", taskID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	s.forgetTask(taskID)
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	s.logger.Info("Удалена задача", "id", taskID)
+}
+
+// forgetTask drops what the server keeps about a deleted task besides
+// the task itself: its pin, its embedding and its reported SLA breach.
+func (s *Server) forgetTask(id string) {
+	s.pins.Unpin(id)
+	s.embeddings.Remove(id)
+	s.breaches.Delete(id)
+}
+
+// getQueueStats writes the current depth, capacity and consumer
+// count of the task queue in JSON format to the provided
+// http.ResponseWriter. The route is only registered when the
+// queue is enabled.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - req: The http.Request received from the client. This parameter is ignored in this function.
+func (s *Server) getQueueStats(writer http.ResponseWriter, _ *http.Request) {
+	response, err := json.Marshal(s.queue.Stats())
+	if err != nil {
+		s.logger.Error("Не удалось сериализовать статистику очереди", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	delete(tasks, taskID)
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusOK)
+	writer.Write(response)
 }
 
 func main() {
-	router := chi.NewRouter()
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		slog.Error("Некорректная конфигурация", "error", err)
+		os.Exit(1)
+	}
 
-	router.Get("/tasks", getTasks)
-	router.Post("/tasks", postTask)
-	router.Get("/tasks/{id}", getTask)
-	router.Delete("/tasks/{id}", deleteTask)
+	logger, err := newLogger(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		slog.Error("Некорректная настройка логирования", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
 
-	if err := http.ListenAndServe(":8080", router); err != nil {
-		fmt.Printf("Ошибка при запуске сервера: %s", err.Error())
-		return
+	if cfg.LogFormat != "json" {
+		printBanner(os.Stdout, cfg, readBuildInfo())
+	}
+
+	startedAt := time.Now()
+	seed := make([]Task, 0, len(seedTasks))
+	for _, task := range seedTasks {
+		task.CreatedAt = startedAt
+		seed = append(seed, task)
+	}
+
+	var store Storage = NewInMemoryStorage(seed...)
+	if cfg.StorageRetryAttempts > 1 {
+		store = NewRetryStorage(store, cfg.StorageRetryAttempts, cfg.StorageRetryBackoff, logger)
+	}
+
+	if err := NewServer(cfg, store).WithLogger(logger).Start(); err != nil {
+		logger.Error("Ошибка при запуске сервера", "error", err)
+		os.Exit(1)
 	}
-	fmt.Println("Listen and Serve")
 }
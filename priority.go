@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// priorityAgeWeight is the priority a task gains per day of age,
+	// so that stale tasks rise over time.
+	priorityAgeWeight = 0.1
+	// priorityUrgencyWeight is the priority of a task that has used up
+	// its whole SLA deadline. A task halfway there has half of it, and
+	// an overdue task keeps gaining.
+	priorityUrgencyWeight = 1.0
+)
+
+// computedPriority scores how pressing the task is at now, higher
+// first: its age in days times priorityAgeWeight, plus, if it has an
+// SLA deadline, the share of the deadline elapsed times
+// priorityUrgencyWeight. The score is rounded to three decimals.
+func computedPriority(task Task, now time.Time) float64 {
+	age := now.Sub(task.CreatedAt)
+	if age < 0 {
+		age = 0
+	}
+	score := priorityAgeWeight * age.Hours() / 24
+
+	if task.SLADeadlineMinutes > 0 {
+		deadline := time.Duration(task.SLADeadlineMinutes) * time.Minute
+		score += priorityUrgencyWeight * float64(age) / float64(deadline)
+	}
+	return math.Round(score*1000) / 1000
+}
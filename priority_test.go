@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComputedPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		task Task
+		want float64
+	}{
+		{name: "new task", task: Task{CreatedAt: testNow}, want: 0},
+		{name: "created in the future", task: Task{CreatedAt: testNow.Add(time.Hour), SLADeadlineMinutes: 60}, want: 0},
+		{name: "a day old", task: Task{CreatedAt: testNow.AddDate(0, 0, -1)}, want: 0.1},
+		{name: "ten days old", task: Task{CreatedAt: testNow.AddDate(0, 0, -10)}, want: 1},
+		// A sixth of a day of age and half of the deadline.
+		{name: "halfway to the deadline", task: Task{CreatedAt: testNow.Add(-4 * time.Hour), SLADeadlineMinutes: 480}, want: 0.517},
+		{name: "at the deadline", task: Task{CreatedAt: testNow.Add(-time.Hour), SLADeadlineMinutes: 60}, want: 1.004},
+		{name: "overdue", task: Task{CreatedAt: testNow.Add(-2 * time.Hour), SLADeadlineMinutes: 60}, want: 2.008},
+		{name: "rounded to three decimals", task: Task{CreatedAt: testNow.Add(-time.Minute)}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computedPriority(tt.task, testNow); got != tt.want {
+				t.Errorf("computedPriority() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputedPriorityRises(t *testing.T) {
+	task := Task{CreatedAt: testNow, SLADeadlineMinutes: 120}
+	previous := -1.0
+	for now := testNow; now.Before(testNow.Add(4 * time.Hour)); now = now.Add(10 * time.Minute) {
+		priority := computedPriority(task, now)
+		if priority <= previous {
+			t.Fatalf("priority at %s = %v, not above %v", now, priority, previous)
+		}
+		previous = priority
+	}
+
+	// An SLA deadline outweighs a few days of age.
+	stale := Task{CreatedAt: testNow.AddDate(0, 0, -3)}
+	urgent := Task{CreatedAt: testNow.Add(-time.Hour), SLADeadlineMinutes: 90}
+	if computedPriority(urgent, testNow) <= computedPriority(stale, testNow) {
+		t.Errorf("priority of a task near its deadline = %v, not above %v of a stale one",
+			computedPriority(urgent, testNow), computedPriority(stale, testNow))
+	}
+}
+
+func TestGetTasksSortedByComputedPriority(t *testing.T) {
+	s, clock := newTestServer(t,
+		Task{ID: "new", Description: "Новая", Applications: []string{}, CreatedAt: testNow},
+		Task{ID: "stale", Description: "Старая", Applications: []string{}, CreatedAt: testNow.AddDate(0, 0, -3)},
+		Task{ID: "urgent", Description: "Срочная", Applications: []string{}, CreatedAt: testNow, SLADeadlineMinutes: 60},
+	)
+
+	order := func() []string {
+		response := serve(s, http.MethodGet, "/tasks?sort=-computed_priority", "")
+		assertStatus(t, response, http.StatusOK)
+		var tasks []Task
+		decodeJSON(t, response.Body.Bytes(), &tasks)
+		return taskIDs(tasks)
+	}
+	if got, want := order(), []string{"stale", "new", "urgent"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order now = %q, want %q", got, want)
+	}
+	// The priority is computed at each request: an hour later the
+	// urgent task is due.
+	clock.Advance(time.Hour)
+	if got, want := order(), []string{"urgent", "stale", "new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order an hour later = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Project groups tasks, as a board does.
+type Project struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Color       string    `json:"color"`
+	OwnerID     string    `json:"owner_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ProjectStorage keeps projects. It reports failures like Storage,
+// with *StorageError wrapping ErrProjectNotFound or
+// ErrProjectAlreadyExists.
+type ProjectStorage interface {
+	GetAll(ctx context.Context) ([]Project, error)
+	Get(ctx context.Context, id string) (Project, error)
+	Create(ctx context.Context, project Project) error
+	Update(ctx context.Context, project Project) error
+	Delete(ctx context.Context, id string) error
+}
+
+// InMemoryProjectStorage is a ProjectStorage backed by a map guarded by
+// a mutex.
+type InMemoryProjectStorage struct {
+	mu       sync.RWMutex
+	projects map[string]Project
+}
+
+// NewInMemoryProjectStorage creates an empty InMemoryProjectStorage.
+func NewInMemoryProjectStorage() *InMemoryProjectStorage {
+	return &InMemoryProjectStorage{projects: make(map[string]Project)}
+}
+
+func (storage *InMemoryProjectStorage) GetAll(_ context.Context) ([]Project, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	projects := make([]Project, 0, len(storage.projects))
+	for _, project := range storage.projects {
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+func (storage *InMemoryProjectStorage) Get(_ context.Context, id string) (Project, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	project, wasFound := storage.projects[id]
+	if !wasFound {
+		return Project{}, &StorageError{Op: "get", Entity: "project", ID: id, Err: ErrProjectNotFound}
+	}
+	return project, nil
+}
+
+func (storage *InMemoryProjectStorage) Create(_ context.Context, project Project) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, wasFound := storage.projects[project.ID]; wasFound {
+		return &StorageError{Op: "create", Entity: "project", ID: project.ID, Err: ErrProjectAlreadyExists}
+	}
+	storage.projects[project.ID] = project
+	return nil
+}
+
+func (storage *InMemoryProjectStorage) Update(_ context.Context, project Project) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, wasFound := storage.projects[project.ID]; !wasFound {
+		return &StorageError{Op: "update", Entity: "project", ID: project.ID, Err: ErrProjectNotFound}
+	}
+	storage.projects[project.ID] = project
+	return nil
+}
+
+func (storage *InMemoryProjectStorage) Delete(_ context.Context, id string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, wasFound := storage.projects[id]; !wasFound {
+		return &StorageError{Op: "delete", Entity: "project", ID: id, Err: ErrProjectNotFound}
+	}
+	delete(storage.projects, id)
+	return nil
+}
+
+// projectFilter matches the tasks of one project.
+type projectFilter struct {
+	id string
+}
+
+func (filter projectFilter) Match(task Task) bool {
+	return task.ProjectID == filter.id
+}
+
+// getProjects writes every project, ordered by ID, in JSON format to
+// the provided http.ResponseWriter. In case of a storage error, it
+// responds with the status chosen by storageErrorStatus.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client.
+func (s *Server) getProjects(writer http.ResponseWriter, request *http.Request) {
+	projects, err := s.projects.GetAll(request.Context())
+	if err != nil {
+		s.logger.Error("Не удалось получить список проектов", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	s.writeJSON(writer, http.StatusOK, projects)
+}
+
+// getProject writes the project with the ID given in the URL in JSON
+// format to the provided http.ResponseWriter. If there is no such
+// project, it responds with a HTTP 404 Not Found.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the project ID.
+func (s *Server) getProject(writer http.ResponseWriter, request *http.Request) {
+	projectID := chi.URLParam(request, "id")
+	project, err := s.projects.Get(request.Context(), projectID)
+	if err != nil {
+		s.reportProjectError(writer, request, projectID, err)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, project)
+}
+
+// postProject creates the project described by the request body, which
+// has been checked against its schema by validateRequest, and responds
+// with a HTTP 201 Created. If a project with the same ID exists, it
+// responds with a HTTP 409 Conflict.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, with the new project in the body.
+func (s *Server) postProject(writer http.ResponseWriter, request *http.Request) {
+	var project Project
+	if err := json.NewDecoder(request.Body).Decode(&project); err != nil {
+		s.logger.Warn("Некорректное тело проекта", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	project.CreatedAt = s.clock.Now()
+	if err := s.projects.Create(request.Context(), project); err != nil {
+		s.reportProjectError(writer, request, project.ID, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	s.logger.Info("Создан проект", "id", project.ID)
+}
+
+// putProject replaces the name, description, color and owner of the
+// project with the ID given in the URL with those in the request body,
+// and writes the updated project. The creation time is kept. An id in
+// the body must match the URL, or the request is rejected with a HTTP
+// 422 Unprocessable Entity. If there is no such project, it responds
+// with a HTTP 404 Not Found.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the project ID and the new fields.
+func (s *Server) putProject(writer http.ResponseWriter, request *http.Request) {
+	projectID := chi.URLParam(request, "id")
+
+	var update Project
+	if err := json.NewDecoder(request.Body).Decode(&update); err != nil {
+		s.logger.Warn("Некорректное тело проекта", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if update.ID != "" && update.ID != projectID {
+		http.Error(writer, "The project ID in the body does not match the URL", http.StatusUnprocessableEntity)
+		return
+	}
+
+	project, err := s.projects.Get(request.Context(), projectID)
+	if err != nil {
+		s.reportProjectError(writer, request, projectID, err)
+		return
+	}
+	project.Name = update.Name
+	project.Description = update.Description
+	project.Color = update.Color
+	project.OwnerID = update.OwnerID
+
+	if err = s.projects.Update(request.Context(), project); err != nil {
+		s.reportProjectError(writer, request, projectID, err)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, project)
+	s.logger.Info("Изменён проект", "id", projectID)
+}
+
+// deleteProject deletes the project with the ID given in the URL. With
+// cascade=true its tasks are deleted too; otherwise they are kept
+// without a project. It responds with a HTTP 204 No Content, a HTTP
+// 400 Bad Request for a malformed cascade, or a HTTP 404 Not Found if
+// there is no such project.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the project ID and the optional cascade query parameter.
+func (s *Server) deleteProject(writer http.ResponseWriter, request *http.Request) {
+	projectID := chi.URLParam(request, "id")
+
+	cascade := false
+	if param := request.URL.Query().Get("cascade"); param != "" {
+		var err error
+		if cascade, err = strconv.ParseBool(param); err != nil {
+			s.logger.Warn("Некорректный параметр cascade", "cascade", param, "error", err)
+			http.Error(writer, "cascade must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := s.projects.Get(request.Context(), projectID); err != nil {
+		s.reportProjectError(writer, request, projectID, err)
+		return
+	}
+
+	tasks, err := s.store.GetAll(request.Context(), projectFilter{id: projectID})
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи проекта", "id", projectID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	for _, task := range tasks {
+		if cascade {
+			err = s.store.Delete(request.Context(), task.ID)
+			s.forgetTask(task.ID)
+		} else {
+			task.ProjectID = ""
+			err = s.store.Update(request.Context(), task)
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			s.logger.Error("Не удалось обработать задачу удаляемого проекта", "id", projectID, "task_id", task.ID, "error", err)
+			http.Error(writer, err.Error(), storageErrorStatus(err))
+			return
+		}
+	}
+
+	if err = s.projects.Delete(request.Context(), projectID); err != nil {
+		s.reportProjectError(writer, request, projectID, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	s.logger.Info("Удалён проект", "id", projectID, "cascade", cascade, "tasks", len(tasks))
+}
+
+// getProjectTasks writes the tasks of the project with the ID given in
+// the URL, ordered by ID, in JSON format to the provided
+// http.ResponseWriter. If there is no such project, it responds with a
+// HTTP 404 Not Found.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the project ID.
+func (s *Server) getProjectTasks(writer http.ResponseWriter, request *http.Request) {
+	projectID := chi.URLParam(request, "id")
+	if _, err := s.projects.Get(request.Context(), projectID); err != nil {
+		s.reportProjectError(writer, request, projectID, err)
+		return
+	}
+
+	tasks, err := s.store.GetAll(request.Context(), projectFilter{id: projectID})
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи проекта", "id", projectID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	for i := range tasks {
+		s.presentTask(&tasks[i])
+	}
+	s.writeJSON(writer, http.StatusOK, tasks)
+}
+
+// moveTask handles the HTTP request to move the task with the ID given
+// in the URL to the project named by project_id in the body, or out of
+// every project if project_id is empty, and writes the moved task. The
+// move is an update of the task, so /tasks/diff and /tasks/sync report
+// it. If the task does not exist, it responds with the status chosen
+// by storageErrorStatus; if the project does not, with a HTTP 404 Not
+// Found, since the project is named in the request itself.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the task ID and the target project.
+func (s *Server) moveTask(writer http.ResponseWriter, request *http.Request) {
+	var body struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело запроса переноса", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskID := chi.URLParam(request, "id")
+	task, err := s.store.Get(request.Context(), taskID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("Задача для переноса не найдена", "id", taskID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось получить задачу", "id", taskID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	if body.ProjectID != "" {
+		if _, err = s.projects.Get(request.Context(), body.ProjectID); err != nil {
+			s.reportProjectError(writer, request, body.ProjectID, err)
+			return
+		}
+	}
+
+	from := task.ProjectID
+	if from != body.ProjectID {
+		task.ProjectID = body.ProjectID
+		if err = s.store.Update(request.Context(), task); err != nil {
+			s.logger.Error("Не удалось перенести задачу", "id", taskID, "error", err)
+			http.Error(writer, err.Error(), storageErrorStatus(err))
+			return
+		}
+	}
+
+	s.presentTask(&task)
+	s.writeJSON(writer, http.StatusOK, task)
+	s.logger.Info("Задача перенесена", "id", taskID, "from", from, "to", body.ProjectID)
+}
+
+// reportProjectError answers a request that failed with a storage
+// error about the project with the given ID.
+func (s *Server) reportProjectError(writer http.ResponseWriter, request *http.Request, projectID string, err error) {
+	switch {
+	case errors.Is(err, ErrProjectNotFound):
+		s.logger.Warn("Проект не найден", "id", projectID)
+		localizedError(writer, request, MsgProjectNotFound, storageErrorStatus(err))
+	case errors.Is(err, ErrProjectAlreadyExists):
+		s.logger.Warn("Проект с таким ID уже существует", "id", projectID)
+		localizedError(writer, request, MsgProjectAlreadyExists, storageErrorStatus(err))
+	default:
+		s.logger.Error("Ошибка хранилища проектов", "id", projectID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+	}
+}
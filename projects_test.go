@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// newProjectTestServer returns a server with the projects p1 and p2,
+// where the tasks 1 and 2 are in p1, 3 is in p2 and 4 is in none.
+func newProjectTestServer(t *testing.T) (*Server, *InMemoryStorage) {
+	t.Helper()
+	store := NewInMemoryStorage(
+		Task{ID: "1", Description: "Первая", ProjectID: "p1", CreatedAt: testNow},
+		Task{ID: "2", Description: "Вторая", ProjectID: "p1", CreatedAt: testNow},
+		Task{ID: "3", Description: "Третья", ProjectID: "p2", CreatedAt: testNow},
+		Task{ID: "4", Description: "Четвёртая", CreatedAt: testNow},
+	)
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), store)
+	for _, project := range []Project{{ID: "p1", Name: "Работа"}, {ID: "p2", Name: "Дом"}} {
+		if err := s.projects.Create(context.Background(), project); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, store
+}
+
+func TestProjectHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "list", method: http.MethodGet, target: "/projects", wantStatus: http.StatusOK},
+		{name: "get", method: http.MethodGet, target: "/projects/p1", wantStatus: http.StatusOK},
+		{name: "get unknown", method: http.MethodGet, target: "/projects/p9", wantStatus: http.StatusNotFound},
+		{name: "create", method: http.MethodPost, target: "/projects", body: `{"id":"p3","name":"Учёба","color":"#00ff00"}`, wantStatus: http.StatusCreated},
+		{name: "create existing", method: http.MethodPost, target: "/projects", body: `{"id":"p1","name":"Работа"}`, wantStatus: http.StatusConflict},
+		{name: "create without a name", method: http.MethodPost, target: "/projects", body: `{"id":"p3"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "create with a bad color", method: http.MethodPost, target: "/projects", body: `{"id":"p3","name":"Учёба","color":"green"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "update", method: http.MethodPut, target: "/projects/p1", body: `{"name":"Офис","owner_id":"u1"}`, wantStatus: http.StatusOK},
+		{name: "update unknown", method: http.MethodPut, target: "/projects/p9", body: `{"name":"Офис"}`, wantStatus: http.StatusNotFound},
+		{name: "update another ID", method: http.MethodPut, target: "/projects/p1", body: `{"id":"p2","name":"Офис"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "delete", method: http.MethodDelete, target: "/projects/p2", wantStatus: http.StatusNoContent},
+		{name: "delete unknown", method: http.MethodDelete, target: "/projects/p9", wantStatus: http.StatusNotFound},
+		{name: "delete with a bad cascade", method: http.MethodDelete, target: "/projects/p2?cascade=maybe", wantStatus: http.StatusBadRequest},
+		{name: "tasks of unknown", method: http.MethodGet, target: "/projects/p9/tasks", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newProjectTestServer(t)
+			assertStatus(t, serve(s, tt.method, tt.target, tt.body), tt.wantStatus)
+		})
+	}
+}
+
+func TestProjectCRUD(t *testing.T) {
+	s, _ := newProjectTestServer(t)
+
+	assertStatus(t, serve(s, http.MethodPost, "/projects", `{"id":"p3","name":"Учёба","color":"#00ff00"}`), http.StatusCreated)
+	response := serve(s, http.MethodGet, "/projects/p3", "")
+	assertStatus(t, response, http.StatusOK)
+	var created Project
+	decodeJSON(t, response.Body.Bytes(), &created)
+	if created.Name != "Учёба" || created.Color != "#00ff00" || !created.CreatedAt.Equal(testNow) {
+		t.Errorf("created project = %+v", created)
+	}
+
+	response = serve(s, http.MethodPut, "/projects/p3", `{"name":"Курсы","owner_id":"u1"}`)
+	assertStatus(t, response, http.StatusOK)
+	var updated Project
+	decodeJSON(t, response.Body.Bytes(), &updated)
+	if updated.Name != "Курсы" || updated.OwnerID != "u1" || updated.Color != "" || !updated.CreatedAt.Equal(testNow) {
+		t.Errorf("updated project = %+v, want the new fields and the creation time kept", updated)
+	}
+
+	response = serve(s, http.MethodGet, "/projects", "")
+	assertStatus(t, response, http.StatusOK)
+	var projects []Project
+	decodeJSON(t, response.Body.Bytes(), &projects)
+	var ids []string
+	for _, project := range projects {
+		ids = append(ids, project.ID)
+	}
+	if want := []string{"p1", "p2", "p3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("projects = %q, want %q", ids, want)
+	}
+
+	assertStatus(t, serve(s, http.MethodDelete, "/projects/p3", ""), http.StatusNoContent)
+	assertStatus(t, serve(s, http.MethodGet, "/projects/p3", ""), http.StatusNotFound)
+}
+
+func TestGetProjectTasks(t *testing.T) {
+	s, _ := newProjectTestServer(t)
+
+	response := serve(s, http.MethodGet, "/projects/p1/tasks", "")
+	assertStatus(t, response, http.StatusOK)
+	var tasks []Task
+	decodeJSON(t, response.Body.Bytes(), &tasks)
+	if got, want := taskIDs(tasks), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks of p1 = %q, want %q", got, want)
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantDeleted bool
+	}{
+		{name: "orphans the tasks", query: "", wantDeleted: false},
+		{name: "orphans the tasks when asked", query: "?cascade=false", wantDeleted: false},
+		{name: "cascades", query: "?cascade=true", wantDeleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newProjectTestServer(t)
+
+			assertStatus(t, serve(s, http.MethodDelete, "/projects/p1"+tt.query, ""), http.StatusNoContent)
+			assertStatus(t, serve(s, http.MethodGet, "/projects/p1", ""), http.StatusNotFound)
+			for _, id := range []string{"1", "2"} {
+				task, err := store.Get(context.Background(), id)
+				switch {
+				case tt.wantDeleted && !errors.Is(err, ErrNotFound):
+					t.Errorf("task %s: err = %v, want ErrNotFound", id, err)
+				case !tt.wantDeleted && err != nil:
+					t.Errorf("task %s: err = %v, want it kept", id, err)
+				case !tt.wantDeleted && task.ProjectID != "":
+					t.Errorf("task %s: project = %q, want none", id, task.ProjectID)
+				}
+			}
+			// The tasks of other projects are left alone.
+			if task, err := store.Get(context.Background(), "3"); err != nil || task.ProjectID != "p2" {
+				t.Errorf("task 3 = %+v, %v, want it kept in p2", task, err)
+			}
+		})
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	s, store := newProjectTestServer(t)
+	since, _, err := store.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := serve(s, http.MethodPost, "/tasks/1/move", `{"project_id":"p2"}`)
+	assertStatus(t, response, http.StatusOK)
+	var moved Task
+	decodeJSON(t, response.Body.Bytes(), &moved)
+	if moved.ID != "1" || moved.ProjectID != "p2" {
+		t.Errorf("moved task = %+v, want task 1 in p2", moved)
+	}
+	if task, err := store.Get(context.Background(), "1"); err != nil || task.ProjectID != "p2" {
+		t.Errorf("stored task = %+v, %v, want it in p2", task, err)
+	}
+
+	_, changes, err := store.Changes(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Type != TaskUpdated || changes[0].ID != "1" || changes[0].Task.ProjectID != "p2" {
+		t.Errorf("changes = %+v, want the move as an update of task 1", changes)
+	}
+
+	// Out of every project.
+	assertStatus(t, serve(s, http.MethodPost, "/tasks/1/move", `{"project_id":""}`), http.StatusOK)
+	if task, err := store.Get(context.Background(), "1"); err != nil || task.ProjectID != "" {
+		t.Errorf("stored task = %+v, %v, want it in no project", task, err)
+	}
+}
+
+func TestMoveTaskRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "unknown project", target: "/tasks/1/move", body: `{"project_id":"p9"}`, wantStatus: http.StatusNotFound},
+		{name: "unknown task", target: "/tasks/9/move", body: `{"project_id":"p2"}`, wantStatus: http.StatusBadRequest},
+		{name: "no project_id", target: "/tasks/1/move", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newProjectTestServer(t)
+
+			assertStatus(t, serve(s, http.MethodPost, tt.target, tt.body), tt.wantStatus)
+			if task, err := store.Get(context.Background(), "1"); err != nil || task.ProjectID != "p1" {
+				t.Errorf("task 1 = %+v, %v, want it left in p1", task, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrQueueFull is returned by Enqueue when the queue has no free
+// capacity left for another task.
+var ErrQueueFull = errors.New("task queue is full")
+
+// TaskQueue describes a queue of tasks waiting for downstream
+// processing. Producers call Enqueue, consumers receive tasks from
+// the channel returned by Consume until their context is cancelled.
+type TaskQueue interface {
+	Enqueue(ctx context.Context, task Task) error
+	Consume(ctx context.Context) (<-chan Task, error)
+}
+
+// QueueStats is a point-in-time snapshot of a queue's state.
+type QueueStats struct {
+	Depth     int   `json:"depth"`
+	Capacity  int   `json:"capacity"`
+	Consumers int64 `json:"consumers"`
+}
+
+// InMemoryQueue is a TaskQueue backed by a buffered channel. All
+// consumers share the same channel, so every task is delivered to
+// exactly one of them.
+type InMemoryQueue struct {
+	tasks     chan Task
+	consumers atomic.Int64
+}
+
+// NewInMemoryQueue creates an InMemoryQueue that buffers up to
+// capacity tasks before Enqueue starts returning ErrQueueFull.
+func NewInMemoryQueue(capacity int) *InMemoryQueue {
+	return &InMemoryQueue{tasks: make(chan Task, capacity)}
+}
+
+// Enqueue adds the task to the queue without blocking. It returns
+// ErrQueueFull if the buffer is exhausted, or the context error if
+// ctx is already done.
+func (queue *InMemoryQueue) Enqueue(ctx context.Context, task Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case queue.tasks <- task:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Consume registers a consumer and returns the channel it should read
+// tasks from. The consumer is unregistered once ctx is done.
+func (queue *InMemoryQueue) Consume(ctx context.Context) (<-chan Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	queue.consumers.Add(1)
+	go func() {
+		<-ctx.Done()
+		queue.consumers.Add(-1)
+	}()
+
+	return queue.tasks, nil
+}
+
+// Stats returns the current depth, capacity and consumer count of the queue.
+func (queue *InMemoryQueue) Stats() QueueStats {
+	return QueueStats{
+		Depth:     len(queue.tasks),
+		Capacity:  cap(queue.tasks),
+		Consumers: queue.consumers.Load(),
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QuickCapture is a task parsed by POST /tasks/quick-capture, not yet
+// saved. Tasks have no assignee, so the @mention is reported next to
+// the task rather than in it. DueAt is the deadline the task's SLA
+// deadline was set from, if the text named one.
+type QuickCapture struct {
+	Task     Task       `json:"task"`
+	Assignee string     `json:"assignee,omitempty"`
+	DueAt    *time.Time `json:"due_at,omitempty"`
+}
+
+// quickCaptureTime matches the time of a due date, such as 17:00, 5pm
+// or 5:30pm, capturing the hour, the minutes and am or pm.
+var quickCaptureTime = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+// quickCaptureDay returns the UTC day a word names relative to now:
+// today, tomorrow, a weekday, meaning its next occurrence with today
+// included, or a date such as 2024-01-19.
+func quickCaptureDay(word string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch word {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if word == strings.ToLower(weekday.String()) {
+			return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+7)%7), true
+		}
+	}
+	if day, err := time.Parse(time.DateOnly, word); err == nil {
+		return day, true
+	}
+	return time.Time{}, false
+}
+
+// quickCaptureClock returns the time of day a word names, such as
+// 17:00, 5pm or noon.
+func quickCaptureClock(word string) (time.Duration, bool) {
+	if word == "noon" {
+		return 12 * time.Hour, true
+	}
+	match := quickCaptureTime.FindStringSubmatch(word)
+	if match == nil {
+		return 0, false
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	switch {
+	case match[3] != "" && (hour < 1 || hour > 12):
+		return 0, false
+	case match[3] == "am" && hour == 12:
+		hour = 0
+	case match[3] == "pm" && hour < 12:
+		hour += 12
+	}
+	if hour > 23 || minute > 59 {
+		return 0, false
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, true
+}
+
+// isMentionName reports whether name, following an @, is a user name:
+// letters, digits, -, _ and dots.
+func isMentionName(name string) bool {
+	return name != "" && strings.IndexFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.'
+	}) < 0
+}
+
+// parseQuickCapture splits the text of a quick capture into the task
+// description, its due date and its assignee, after the #tags have
+// been taken out by parseSlackCommandText. The due date is the first
+// day, see quickCaptureDay, optionally preceded by by, on or due and
+// followed by at and a time, see quickCaptureClock; without a time it
+// is the end of the day. Dates are UTC, as in GET /tasks/calendar.
+// The assignee is the first @mention. The words of both are left out
+// of the description.
+func parseQuickCapture(text string, now time.Time) (description string, due *time.Time, assignee string) {
+	words := strings.Fields(text)
+	var kept []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		lower := strings.ToLower(strings.TrimRight(word, ".,;!?"))
+
+		if name, isMention := strings.CutPrefix(lower, "@"); isMention && assignee == "" && isMentionName(name) {
+			assignee = name
+			continue
+		}
+
+		if due == nil {
+			dayAt := i
+			if lower == "by" || lower == "on" || lower == "due" {
+				dayAt++
+			}
+			if dayAt < len(words) {
+				if day, ok := quickCaptureDay(strings.ToLower(strings.TrimRight(words[dayAt], ".,;!?")), now); ok {
+					moment := day.AddDate(0, 0, 1)
+					next := dayAt + 1
+					if next+1 < len(words) && strings.ToLower(words[next]) == "at" {
+						if clock, ok := quickCaptureClock(strings.ToLower(strings.TrimRight(words[next+1], ".,;!?"))); ok {
+							moment = day.Add(clock)
+							next += 2
+						}
+					}
+					due = &moment
+					i = next - 1
+					continue
+				}
+			}
+		}
+
+		kept = append(kept, word)
+	}
+	return strings.Join(kept, " "), due, assignee
+}
+
+// postQuickCapture handles the HTTP request to parse the plain text
+// of a task, given as {"text":...}, such as "Finish report by Friday
+// at 5pm #work @alice", into a task under a generated ID, for the user
+// to confirm with POST /tasks. The #tags are suggested, as for Slack
+// commands, along with the tags the categorizer suggests; a due date
+// sets the SLA deadline, counted from now; an @mention is reported as
+// the assignee, see QuickCapture. Nothing is saved. A text with no
+// description left, or a due date already past, is rejected with a
+// HTTP 422 Unprocessable Entity.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, with the text in the body.
+func (s *Server) postQuickCapture(writer http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело быстрой записи", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := s.clock.Now()
+	text, tags := parseSlackCommandText(body.Text)
+	description, due, assignee := parseQuickCapture(text, now)
+	if description == "" {
+		s.logger.Warn("Быстрая запись без описания", "text", body.Text)
+		http.Error(writer, "the text has no task description", http.StatusUnprocessableEntity)
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		s.logger.Error("Не удалось создать ID задачи", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	capture := QuickCapture{
+		Task: Task{
+			ID:            id,
+			Description:   description,
+			Applications:  []string{},
+			CreatedAt:     now,
+			SuggestedTags: mergeTags(tags, s.categorizer.Categorize(request.Context(), description)),
+		},
+		Assignee: assignee,
+		DueAt:    due,
+	}
+	if due != nil {
+		if !due.After(now) {
+			s.logger.Warn("Срок быстрой записи уже прошёл", "due_at", *due)
+			http.Error(writer, fmt.Sprintf("the due date %s has already passed", due.Format(time.RFC3339)), http.StatusUnprocessableEntity)
+			return
+		}
+		capture.Task.SLADeadlineMinutes = int((due.Sub(now) + time.Minute - 1) / time.Minute)
+	}
+
+	s.presentTask(&capture.Task)
+	s.writeJSON(writer, http.StatusOK, capture)
+}
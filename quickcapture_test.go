@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuickCapture(t *testing.T) {
+	// testNow is Monday 2024-01-15 12:00 UTC.
+	at := func(day, hour, minute int) *time.Time {
+		moment := time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+		return &moment
+	}
+	tests := []struct {
+		text            string
+		wantDescription string
+		wantDue         *time.Time
+		wantAssignee    string
+	}{
+		{text: "Finish report by Friday at 5pm @alice", wantDescription: "Finish report", wantDue: at(19, 17, 0), wantAssignee: "alice"},
+		{text: "Finish report by friday", wantDescription: "Finish report", wantDue: at(20, 0, 0)},
+		{text: "Call Bob tomorrow at 9:30am", wantDescription: "Call Bob", wantDue: at(16, 9, 30)},
+		{text: "Call Bob today at 17:45.", wantDescription: "Call Bob", wantDue: at(15, 17, 45)},
+		{text: "Lunch on Monday at noon", wantDescription: "Lunch", wantDue: at(15, 12, 0)},
+		{text: "Pay rent due 2024-02-01", wantDescription: "Pay rent", wantDue: at(33, 0, 0)},
+		{text: "Release on 2024-01-31 at 12am", wantDescription: "Release", wantDue: at(31, 0, 0)},
+		{text: "Meet @bob.smith and @carol at 13pm tomorrow", wantDescription: "Meet and @carol at 13pm", wantDue: at(17, 0, 0), wantAssignee: "bob.smith"},
+		{text: "Write down by the way", wantDescription: "Write down by the way"},
+		{text: "Email @ the team", wantDescription: "Email @ the team"},
+		{text: "Позвонить маме", wantDescription: "Позвонить маме"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			description, due, assignee := parseQuickCapture(tt.text, testNow)
+			if description != tt.wantDescription || assignee != tt.wantAssignee {
+				t.Errorf("parseQuickCapture() = %q, @%q, want %q, @%q", description, assignee, tt.wantDescription, tt.wantAssignee)
+			}
+			if (due == nil) != (tt.wantDue == nil) || due != nil && !due.Equal(*tt.wantDue) {
+				t.Errorf("due = %v, want %v", due, tt.wantDue)
+			}
+		})
+	}
+}
+
+func TestPostQuickCapture(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	response := serve(s, http.MethodPost, "/tasks/quick-capture", `{"text":"Finish report by Friday at 5pm #work #Q1 @alice"}`)
+	assertStatus(t, response, http.StatusOK)
+	var capture QuickCapture
+	decodeJSON(t, response.Body.Bytes(), &capture)
+	task := capture.Task
+	if task.ID == "" || task.Description != "Finish report" || !reflect.DeepEqual(task.SuggestedTags, []string{"q1", "work"}) {
+		t.Errorf("task = %+v, want the description and tags parsed", task)
+	}
+	// Friday 17:00 is four days and five hours away.
+	if task.SLADeadlineMinutes != (4*24+5)*60 || capture.DueAt == nil || !capture.DueAt.Equal(time.Date(2024, 1, 19, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("sla_deadline_minutes = %d, due_at = %v, want Friday 17:00", task.SLADeadlineMinutes, capture.DueAt)
+	}
+	if capture.Assignee != "alice" {
+		t.Errorf("assignee = %q, want alice", capture.Assignee)
+	}
+	if tasks, _ := s.store.GetAll(context.Background(), nil); len(tasks) != 0 {
+		t.Errorf("stored %d tasks, want none before confirmation", len(tasks))
+	}
+
+	// Confirmed as is, the task keeps its tags.
+	confirm := `{"id":"` + task.ID + `","description":"Finish report","sla_deadline_minutes":6060,"suggested_tags":["q1","work"]}`
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", confirm), http.StatusCreated)
+	if stored, err := s.store.Get(context.Background(), task.ID); err != nil || !reflect.DeepEqual(stored.SuggestedTags, []string{"q1", "work"}) {
+		t.Errorf("stored task = %+v, %v, want the tags kept", stored, err)
+	}
+}
+
+func TestPostQuickCaptureRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "past due date", body: `{"text":"Finish report today at 9am"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "only tags", body: `{"text":"#work @alice"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "empty text", body: `{"text":""}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "no text", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed", body: `{"text":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			assertStatus(t, serve(s, http.MethodPost, "/tasks/quick-capture", tt.body), tt.wantStatus)
+		})
+	}
+}
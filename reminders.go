@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// reminderInterval is how often sendReminders looks for due reminders.
+const reminderInterval = time.Minute
+
+// Reminder is a notification sent LeadTime before the SLA deadline of
+// its task, at NotifyAt. Sent is set once it has been sent.
+type Reminder struct {
+	ID       string        `json:"id"`
+	LeadTime time.Duration `json:"lead_time"`
+	NotifyAt time.Time     `json:"notify_at"`
+	Sent     bool          `json:"sent"`
+}
+
+// errReminderWithoutDeadline is returned by scheduleReminders for a
+// task with reminders and no SLA deadline to count them back from.
+var errReminderWithoutDeadline = errors.New("reminders need an SLA deadline")
+
+// scheduleReminders sets the NotifyAt of every reminder of the task
+// from its SLA deadline, and an ID to those without one. A reminder
+// whose NotifyAt moves is sent again.
+func scheduleReminders(task *Task) error {
+	if len(task.Reminders) == 0 {
+		return nil
+	}
+	deadline, ok := slaDeadline(*task)
+	if !ok {
+		return errReminderWithoutDeadline
+	}
+
+	for i := range task.Reminders {
+		reminder := &task.Reminders[i]
+		if reminder.ID == "" {
+			id, err := newID()
+			if err != nil {
+				return err
+			}
+			reminder.ID = id
+		}
+		if notifyAt := deadline.Add(-reminder.LeadTime); !notifyAt.Equal(reminder.NotifyAt) {
+			reminder.NotifyAt = notifyAt
+			reminder.Sent = false
+		}
+	}
+	return nil
+}
+
+// sendReminders sends the due reminders every interval until ctx is
+// cancelled. Like expireTasks, it judges them against the server
+// clock. It is meant to be run in its own goroutine.
+func (s *Server) sendReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sendDueReminders(ctx, s.clock.Now())
+		}
+	}
+}
+
+// sendDueReminders sends every reminder not yet sent whose NotifyAt
+// is at or before now, and marks it sent. Reminders are sent as an
+// info log entry, which is how the server reports SLA breaches too.
+// The sweep stops early, leaving the remaining reminders for the next
+// tick, once ctx is done.
+func (s *Server) sendDueReminders(ctx context.Context, now time.Time) {
+	tasks, err := s.store.GetAll(ctx, nil)
+	if err != nil {
+		s.logger.Error("Не удалось получить задачи для напоминаний", "error", err)
+		return
+	}
+
+	for _, task := range tasks {
+		due := false
+		for i := range task.Reminders {
+			reminder := &task.Reminders[i]
+			if reminder.Sent || reminder.NotifyAt.After(now) {
+				continue
+			}
+			s.logger.Info("Напоминание о задаче", "id", task.ID, "reminder_id", reminder.ID, "notify_at", reminder.NotifyAt, "description", task.Description)
+			reminder.Sent = true
+			due = true
+		}
+		if !due {
+			continue
+		}
+		if err := s.store.Update(ctx, task); errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			s.logger.Error("Не удалось отметить напоминания отправленными", "id", task.ID, "error", err)
+			return
+		}
+	}
+}
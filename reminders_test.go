@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPostTaskSchedulesReminders(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	body := `{"id":"1","description":"Сдать отчёт","note":"","applications":[],"sla_deadline_minutes":120,
+		"reminders":[{"lead_time":3600000000000},{"id":"last-call","lead_time":600000000000}]}`
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", body), http.StatusCreated)
+
+	task, err := s.store.Get(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(task.Reminders) != 2 {
+		t.Fatalf("reminders = %+v, want 2", task.Reminders)
+	}
+	first, second := task.Reminders[0], task.Reminders[1]
+	if first.ID == "" || !first.NotifyAt.Equal(testNow.Add(time.Hour)) || first.Sent {
+		t.Errorf("first reminder = %+v, want a generated ID and notify_at an hour before the deadline", first)
+	}
+	if second.ID != "last-call" || !second.NotifyAt.Equal(testNow.Add(110*time.Minute)) || second.Sent {
+		t.Errorf("second reminder = %+v, want notify_at 10 minutes before the deadline", second)
+	}
+}
+
+func TestPostTaskRemindersRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "no deadline", body: `{"id":"1","description":"Сдать","reminders":[{"lead_time":60000000000}]}`},
+		{name: "negative lead time", body: `{"id":"1","description":"Сдать","sla_deadline_minutes":60,"reminders":[{"lead_time":-1}]}`},
+		{name: "no lead time", body: `{"id":"1","description":"Сдать","sla_deadline_minutes":60,"reminders":[{}]}`},
+		{name: "already sent", body: `{"id":"1","description":"Сдать","sla_deadline_minutes":60,"reminders":[{"lead_time":0,"sent":true}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			assertStatus(t, serve(s, http.MethodPost, "/tasks", tt.body), http.StatusUnprocessableEntity)
+		})
+	}
+}
+
+func TestSendDueReminders(t *testing.T) {
+	due := Task{ID: "1", Description: "Сдать отчёт", CreatedAt: testNow, SLADeadlineMinutes: 120,
+		Reminders: []Reminder{{ID: "early", LeadTime: time.Hour}, {ID: "late", LeadTime: 10 * time.Minute}}}
+	if err := scheduleReminders(&due); err != nil {
+		t.Fatal(err)
+	}
+	store := NewInMemoryStorage(due, Task{ID: "2", Description: "Без напоминаний", CreatedAt: testNow})
+	s, clock := newTestServerWithConfig(t, DefaultConfig(), store)
+	ctx := context.Background()
+
+	sent := func() map[string]bool {
+		task, err := store.Get(ctx, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent := make(map[string]bool)
+		for _, reminder := range task.Reminders {
+			sent[reminder.ID] = reminder.Sent
+		}
+		return sent
+	}
+
+	s.sendDueReminders(ctx, clock.Now())
+	if got := sent(); got["early"] || got["late"] {
+		t.Errorf("sent before any is due = %v", got)
+	}
+
+	clock.Advance(time.Hour)
+	s.sendDueReminders(ctx, clock.Now())
+	if got := sent(); !got["early"] || got["late"] {
+		t.Errorf("sent an hour before the deadline = %v, want only early", got)
+	}
+	generation, _, err := store.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A sent reminder is not sent again.
+	s.sendDueReminders(ctx, clock.Now())
+	if current, _, _ := store.Snapshot(ctx); current != generation {
+		t.Errorf("generation = %d, want %d: nothing to update", current, generation)
+	}
+
+	clock.Advance(time.Hour)
+	s.sendDueReminders(ctx, clock.Now())
+	if got := sent(); !got["early"] || !got["late"] {
+		t.Errorf("sent past the deadline = %v, want both", got)
+	}
+}
+
+func TestScheduleReminders(t *testing.T) {
+	task := Task{ID: "1", CreatedAt: testNow, SLADeadlineMinutes: 60,
+		Reminders: []Reminder{{ID: "r", LeadTime: 30 * time.Minute}}}
+	if err := scheduleReminders(&task); err != nil {
+		t.Fatal(err)
+	}
+	task.Reminders[0].Sent = true
+
+	// The same deadline keeps the reminder sent.
+	if err := scheduleReminders(&task); err != nil || !task.Reminders[0].Sent {
+		t.Errorf("reminder = %+v, %v, want it still sent", task.Reminders[0], err)
+	}
+	// A later deadline sends it again.
+	task.SLADeadlineMinutes = 120
+	if err := scheduleReminders(&task); err != nil || task.Reminders[0].Sent || !task.Reminders[0].NotifyAt.Equal(testNow.Add(90*time.Minute)) {
+		t.Errorf("reminder = %+v, %v, want it rescheduled and not sent", task.Reminders[0], err)
+	}
+
+	task.SLADeadlineMinutes = 0
+	if err := scheduleReminders(&task); err != errReminderWithoutDeadline {
+		t.Errorf("scheduleReminders() without a deadline error = %v, want errReminderWithoutDeadline", err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// isRetryable reports whether a storage operation failing with err may
+// succeed if tried again: err wraps ErrUnavailable, a net.Error, or an
+// error whose Temporary method reports true. The Err* sentinels, such
+// as ErrNotFound, and context errors are final.
+func isRetryable(err error) bool {
+	// context.DeadlineExceeded is a net.Error too.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// RetryStorage is a Storage that retries the operations of another
+// failing with a retryable error, see isRetryable. Before attempt n+1
+// it waits BackoffBase * 2^n, give or take a random half of it so that
+// clients failing together do not retry together, and it gives up
+// after MaxAttempts attempts with the last error. A write that failed
+// may have been made all the same, so a retried Create can report
+// ErrAlreadyExists. WaitForChange is not retried, since it fails only
+// once its context is done. The optional interfaces of the wrapped
+// storage, such as Pager, are not passed through.
+type RetryStorage struct {
+	Storage     Storage
+	MaxAttempts int
+	BackoffBase time.Duration
+	Logger      *slog.Logger
+}
+
+// NewRetryStorage creates a RetryStorage wrapping storage.
+func NewRetryStorage(storage Storage, maxAttempts int, backoffBase time.Duration, logger *slog.Logger) *RetryStorage {
+	return &RetryStorage{Storage: storage, MaxAttempts: maxAttempts, BackoffBase: backoffBase, Logger: logger}
+}
+
+// backoff returns how long to wait after the given failed attempt,
+// counted from 0.
+func (storage *RetryStorage) backoff(attempt int) time.Duration {
+	delay := storage.BackoffBase << attempt
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)))
+}
+
+// retry calls operation until it succeeds, fails with an error that is
+// not retryable, or has been called MaxAttempts times.
+func retry[T any](ctx context.Context, storage *RetryStorage, op string, operation func() (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := operation()
+		if err == nil || !isRetryable(err) || attempt+1 >= storage.MaxAttempts {
+			return result, err
+		}
+
+		delay := storage.backoff(attempt)
+		storage.Logger.Warn("Повтор операции хранилища", "op", op, "attempt", attempt+1, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			var zero T
+			return zero, &StorageError{Op: op, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
+
+// retryErr is retry for operations returning only an error.
+func retryErr(ctx context.Context, storage *RetryStorage, op string, operation func() error) error {
+	_, err := retry(ctx, storage, op, func() (struct{}, error) {
+		return struct{}{}, operation()
+	})
+	return err
+}
+
+func (storage *RetryStorage) GetAll(ctx context.Context, filter Filter) ([]Task, error) {
+	return retry(ctx, storage, "get", func() ([]Task, error) {
+		return storage.Storage.GetAll(ctx, filter)
+	})
+}
+
+func (storage *RetryStorage) Get(ctx context.Context, id string) (Task, error) {
+	return retry(ctx, storage, "get", func() (Task, error) {
+		return storage.Storage.Get(ctx, id)
+	})
+}
+
+func (storage *RetryStorage) Create(ctx context.Context, task Task) error {
+	return retryErr(ctx, storage, "create", func() error {
+		return storage.Storage.Create(ctx, task)
+	})
+}
+
+func (storage *RetryStorage) Update(ctx context.Context, task Task) error {
+	return retryErr(ctx, storage, "update", func() error {
+		return storage.Storage.Update(ctx, task)
+	})
+}
+
+func (storage *RetryStorage) Delete(ctx context.Context, id string) error {
+	return retryErr(ctx, storage, "delete", func() error {
+		return storage.Storage.Delete(ctx, id)
+	})
+}
+
+func (storage *RetryStorage) Search(ctx context.Context, query string, fuzziness int) ([]SearchResult, bool, error) {
+	var fuzzy bool
+	results, err := retry(ctx, storage, "search", func() (results []SearchResult, err error) {
+		results, fuzzy, err = storage.Storage.Search(ctx, query, fuzziness)
+		return results, err
+	})
+	return results, fuzzy, err
+}
+
+func (storage *RetryStorage) Autocomplete(ctx context.Context, prefix string, limit int) ([]string, error) {
+	return retry(ctx, storage, "autocomplete", func() ([]string, error) {
+		return storage.Storage.Autocomplete(ctx, prefix, limit)
+	})
+}
+
+func (storage *RetryStorage) CreateUnique(ctx context.Context, task Task) error {
+	return retryErr(ctx, storage, "create", func() error {
+		return storage.Storage.CreateUnique(ctx, task)
+	})
+}
+
+func (storage *RetryStorage) Snapshot(ctx context.Context) (uint64, []Task, error) {
+	var tasks []Task
+	generation, err := retry(ctx, storage, "snapshot", func() (generation uint64, err error) {
+		generation, tasks, err = storage.Storage.Snapshot(ctx)
+		return generation, err
+	})
+	return generation, tasks, err
+}
+
+func (storage *RetryStorage) Changes(ctx context.Context, since uint64) (uint64, []TaskChange, error) {
+	var changes []TaskChange
+	generation, err := retry(ctx, storage, "changes", func() (generation uint64, err error) {
+		generation, changes, err = storage.Storage.Changes(ctx, since)
+		return generation, err
+	})
+	return generation, changes, err
+}
+
+func (storage *RetryStorage) WaitForChange(ctx context.Context, since uint64) (uint64, error) {
+	return storage.Storage.WaitForChange(ctx, since)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingGets returns a MockStorage whose Get fails with err the given
+// number of times, then returns the task with the asked ID.
+func failingGets(failures int, err error) *MockStorage {
+	return &MockStorage{GetFn: func(_ context.Context, id string) (Task, error) {
+		if failures > 0 {
+			failures--
+			return Task{}, &StorageError{Op: "get", ID: id, Err: err}
+		}
+		return Task{ID: id}, nil
+	}}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unavailable", err: &StorageError{Op: "get", Err: ErrUnavailable}, want: true},
+		{name: "network error", err: &StorageError{Op: "get", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, want: true},
+		{name: "not found", err: &StorageError{Op: "get", Err: ErrNotFound}},
+		{name: "duplicate description", err: &DuplicateDescriptionError{ExistingID: "1"}},
+		{name: "conflict", err: &StorageError{Op: "update", Err: ErrConflict}},
+		{name: "deadline exceeded", err: &StorageError{Op: "get", Err: context.DeadlineExceeded}},
+		{name: "canceled", err: &StorageError{Op: "get", Err: context.Canceled}},
+		{name: "unknown", err: errors.New("disk is full")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryStorage(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "no failure", wantCalls: 1},
+		{name: "transient failures", failures: 2, err: ErrUnavailable, wantCalls: 3},
+		{name: "too many failures", failures: 5, err: ErrUnavailable, wantErr: ErrUnavailable, wantCalls: 3},
+		{name: "final error", failures: 1, err: ErrNotFound, wantErr: ErrNotFound, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			mock := failingGets(tt.failures, tt.err)
+			storage := NewRetryStorage(mock, 3, time.Millisecond, slog.New(slog.NewTextHandler(&logs, nil)))
+
+			task, err := storage.Get(context.Background(), "1")
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && task.ID != "1" {
+				t.Errorf("Get() = %+v, want task 1", task)
+			}
+			if got := len(mock.CallsTo("Get")); got != tt.wantCalls {
+				t.Errorf("%d calls, want %d", got, tt.wantCalls)
+			}
+			if got := strings.Count(logs.String(), "Повтор операции хранилища"); got != tt.wantCalls-1 {
+				t.Errorf("%d retries logged, want %d", got, tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestRetryStorageBackoff(t *testing.T) {
+	storage := NewRetryStorage(nil, 5, 100*time.Millisecond, nil)
+	for attempt, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if delay := storage.backoff(attempt); delay < base/2 || delay >= base*3/2 {
+				t.Fatalf("backoff(%d) = %v, want within half of %v", attempt, delay, base)
+			}
+		}
+	}
+}
+
+func TestRetryStorageStopsWhenContextDone(t *testing.T) {
+	mock := failingGets(10, ErrUnavailable)
+	storage := NewRetryStorage(mock, 10, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := storage.Get(ctx, "1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want the context error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() took %v after the context was done", elapsed)
+	}
+	if got := len(mock.CallsTo("Get")); got != 1 {
+		t.Errorf("%d calls, want 1", got)
+	}
+}
+
+func TestRetryStorageServesRequests(t *testing.T) {
+	mock := failingGets(1, ErrUnavailable)
+	storage := NewRetryStorage(mock, 2, time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), storage)
+	assertStatus(t, serve(s, http.MethodGet, "/tasks/1", ""), http.StatusOK)
+
+	// Once the attempts are used up, the failure is a 503.
+	mock = failingGets(2, ErrUnavailable)
+	storage.Storage = mock
+	assertStatus(t, serve(s, http.MethodGet, "/tasks/1", ""), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// stripHTML removes every HTML tag from text, together with the
+// contents of script and style elements. The remaining text is kept
+// as written, so entities such as &lt; stay escaped and cannot turn
+// back into markup, and it is not otherwise shortened.
+func stripHTML(text string) string {
+	var result strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(text))
+	skipDepth := 0
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return result.String()
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); isRawTextElement(name) {
+				skipDepth++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); isRawTextElement(name) && skipDepth > 0 {
+				skipDepth--
+			}
+		case html.TextToken:
+			if skipDepth == 0 {
+				result.Write(tokenizer.Raw())
+			}
+		}
+	}
+}
+
+func isRawTextElement(name []byte) bool {
+	return string(name) == "script" || string(name) == "style"
+}
+
+// sanitizeTask strips HTML from every free-text field of the task,
+// dropping the suggested tags left empty by it.
+func sanitizeTask(task *Task) {
+	task.Description = stripHTML(task.Description)
+	task.Note = stripHTML(task.Note)
+	for i, application := range task.Applications {
+		task.Applications[i] = stripHTML(application)
+	}
+	tags := task.SuggestedTags[:0]
+	for _, tag := range task.SuggestedTags {
+		if tag = strings.TrimSpace(stripHTML(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	task.SuggestedTags = tags
+}
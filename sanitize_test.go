@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain text", text: "Сдать задание", want: "Сдать задание"},
+		{name: "script", text: "<script>alert(1)</script>", want: ""},
+		{name: "script around text", text: "Сдать <script>alert(1)</script>задание", want: "Сдать задание"},
+		{name: "script with attributes", text: `<script src="https://evil.example/x.js"></script>Сдать`, want: "Сдать"},
+		{name: "uppercase script", text: "<SCRIPT>alert(1)</SCRIPT>Сдать", want: "Сдать"},
+		{name: "style", text: "<style>body { display: none }</style>Сдать", want: "Сдать"},
+		{name: "unclosed script", text: "Сдать<script>alert(1)", want: "Сдать"},
+		{name: "nested tags", text: "<div><p><b>Сдать</b> <i>задание</i></p></div>", want: "Сдать задание"},
+		{name: "event handler", text: `<img src=x onerror="alert(1)">Сдать`, want: "Сдать"},
+		{name: "escaped script stays escaped", text: "&lt;script&gt;alert(1)&lt;/script&gt;", want: "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{name: "comment", text: "Сдать<!-- <script>alert(1)</script> -->", want: "Сдать"},
+		{name: "empty", text: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripHTML(tt.text); got != tt.want {
+				t.Errorf("stripHTML(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeTask(t *testing.T) {
+	task := Task{
+		ID:            "<b>1</b>",
+		Description:   "<b>Сдать</b> задание",
+		Note:          "<script>alert(1)</script>Срочно",
+		Applications:  []string{"<a href=\"javascript:alert(1)\">git</a>", "<style>*{}</style>", "vim"},
+		SuggestedTags: []string{"<i>учёба</i>", "<script>alert(1)</script>"},
+	}
+
+	sanitizeTask(&task)
+	want := Task{
+		ID:            "<b>1</b>",
+		Description:   "Сдать задание",
+		Note:          "Срочно",
+		Applications:  []string{"git", "", "vim"},
+		SuggestedTags: []string{"учёба"},
+	}
+	if !reflect.DeepEqual(task, want) {
+		t.Errorf("sanitized task = %+v, want %+v", task, want)
+	}
+}
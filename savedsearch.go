@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SavedSearch is a filter expression (see ParseFilter) a user stored
+// under a name, run with GET /tasks?saved_search=<id>. Users are not
+// authenticated, so UserID only groups the searches.
+type SavedSearch struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SavedSearchStorage keeps saved searches. It reports failures like
+// Storage, with *StorageError wrapping ErrSavedSearchNotFound.
+type SavedSearchStorage interface {
+	// GetAll returns the searches saved by the user, in no
+	// particular order.
+	GetAll(ctx context.Context, userID string) ([]SavedSearch, error)
+	Get(ctx context.Context, id string) (SavedSearch, error)
+	Create(ctx context.Context, search SavedSearch) error
+	Update(ctx context.Context, search SavedSearch) error
+	Delete(ctx context.Context, id string) error
+}
+
+// InMemorySavedSearchStorage is a SavedSearchStorage backed by a map
+// guarded by a mutex.
+type InMemorySavedSearchStorage struct {
+	mu       sync.RWMutex
+	searches map[string]SavedSearch
+}
+
+// NewInMemorySavedSearchStorage creates an empty
+// InMemorySavedSearchStorage.
+func NewInMemorySavedSearchStorage() *InMemorySavedSearchStorage {
+	return &InMemorySavedSearchStorage{searches: make(map[string]SavedSearch)}
+}
+
+func (storage *InMemorySavedSearchStorage) GetAll(_ context.Context, userID string) ([]SavedSearch, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	searches := make([]SavedSearch, 0)
+	for _, search := range storage.searches {
+		if search.UserID == userID {
+			searches = append(searches, search)
+		}
+	}
+	return searches, nil
+}
+
+func (storage *InMemorySavedSearchStorage) Get(_ context.Context, id string) (SavedSearch, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	search, wasFound := storage.searches[id]
+	if !wasFound {
+		return SavedSearch{}, &StorageError{Op: "get", Entity: "saved search", ID: id, Err: ErrSavedSearchNotFound}
+	}
+	return search, nil
+}
+
+func (storage *InMemorySavedSearchStorage) Create(_ context.Context, search SavedSearch) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	storage.searches[search.ID] = search
+	return nil
+}
+
+func (storage *InMemorySavedSearchStorage) Update(_ context.Context, search SavedSearch) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, wasFound := storage.searches[search.ID]; !wasFound {
+		return &StorageError{Op: "update", Entity: "saved search", ID: search.ID, Err: ErrSavedSearchNotFound}
+	}
+	storage.searches[search.ID] = search
+	return nil
+}
+
+func (storage *InMemorySavedSearchStorage) Delete(_ context.Context, id string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, wasFound := storage.searches[id]; !wasFound {
+		return &StorageError{Op: "delete", Entity: "saved search", ID: id, Err: ErrSavedSearchNotFound}
+	}
+	delete(storage.searches, id)
+	return nil
+}
+
+// requestFilter returns the filter of a GET or HEAD /tasks request:
+// the filter query parameter, the query of the search named by the
+// saved_search query parameter, or both combined with AND. It answers
+// the request itself, and returns false, if either is malformed or the
+// saved search does not exist.
+func (s *Server) requestFilter(writer http.ResponseWriter, request *http.Request) (Filter, bool) {
+	var filters []Filter
+	expressions := []string{request.URL.Query().Get("filter")}
+
+	if searchID := request.URL.Query().Get("saved_search"); searchID != "" {
+		search, err := s.savedSearches.Get(request.Context(), searchID)
+		if err != nil {
+			s.reportSavedSearchError(writer, request, searchID, err)
+			return nil, false
+		}
+		expressions = append(expressions, search.Query)
+	}
+
+	for _, expression := range expressions {
+		if expression == "" {
+			continue
+		}
+		filter, err := ParseFilter(expression)
+		if err != nil {
+			s.logger.Warn("Некорректный фильтр задач", "filter", expression, "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		filters = append(filters, filter)
+	}
+
+	switch len(filters) {
+	case 0:
+		return nil, true
+	case 1:
+		return filters[0], true
+	default:
+		return andFilter{left: filters[0], right: filters[1]}, true
+	}
+}
+
+// decodeSavedSearch reads the name and query of a saved search from
+// the request body and checks the query. It answers the request
+// itself, and returns false, if either is malformed.
+func (s *Server) decodeSavedSearch(writer http.ResponseWriter, request *http.Request) (SavedSearch, bool) {
+	var search SavedSearch
+	if err := json.NewDecoder(request.Body).Decode(&search); err != nil {
+		s.logger.Warn("Некорректное тело сохранённого поиска", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return SavedSearch{}, false
+	}
+	if _, err := ParseFilter(search.Query); err != nil {
+		s.logger.Warn("Некорректный запрос сохранённого поиска", "query", search.Query, "error", err)
+		http.Error(writer, err.Error(), http.StatusUnprocessableEntity)
+		return SavedSearch{}, false
+	}
+	return search, true
+}
+
+// getSavedSearches writes the searches saved by the user with the ID
+// given in the URL, ordered by name, in JSON format to the provided
+// http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the user ID.
+func (s *Server) getSavedSearches(writer http.ResponseWriter, request *http.Request) {
+	userID := chi.URLParam(request, "id")
+	searches, err := s.savedSearches.GetAll(request.Context(), userID)
+	if err != nil {
+		s.logger.Error("Не удалось получить сохранённые поиски", "user_id", userID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	sort.Slice(searches, func(i, j int) bool {
+		if searches[i].Name != searches[j].Name {
+			return searches[i].Name < searches[j].Name
+		}
+		return searches[i].ID < searches[j].ID
+	})
+	s.writeJSON(writer, http.StatusOK, searches)
+}
+
+// getSavedSearch writes the saved search with the ID given in the URL
+// in JSON format to the provided http.ResponseWriter. If the user has
+// no such search, it responds with a HTTP 404 Not Found.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the user and search IDs.
+func (s *Server) getSavedSearch(writer http.ResponseWriter, request *http.Request) {
+	search, ok := s.userSavedSearch(writer, request)
+	if !ok {
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, search)
+}
+
+// postSavedSearch saves the search described by the request body, which
+// has been checked against its schema by validateRequest, for the user
+// with the ID given in the URL, and responds with a HTTP 201 Created
+// and the search with its new ID. A query that is not a valid filter
+// expression is rejected with a HTTP 422 Unprocessable Entity.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the user ID and the search.
+func (s *Server) postSavedSearch(writer http.ResponseWriter, request *http.Request) {
+	search, ok := s.decodeSavedSearch(writer, request)
+	if !ok {
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		s.logger.Error("Не удалось создать сохранённый поиск", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	search.ID = id
+	search.UserID = chi.URLParam(request, "id")
+	search.CreatedAt = s.clock.Now()
+	if err = s.savedSearches.Create(request.Context(), search); err != nil {
+		s.reportSavedSearchError(writer, request, search.ID, err)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusCreated, search)
+	s.logger.Info("Сохранён поиск", "id", search.ID, "user_id", search.UserID)
+}
+
+// putSavedSearch replaces the name and query of the saved search with
+// the ID given in the URL with those in the request body, and writes
+// the updated search. A query that is not a valid filter expression is
+// rejected with a HTTP 422 Unprocessable Entity. If the user has no
+// such search, it responds with a HTTP 404 Not Found.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the user and search IDs and the new fields.
+func (s *Server) putSavedSearch(writer http.ResponseWriter, request *http.Request) {
+	update, ok := s.decodeSavedSearch(writer, request)
+	if !ok {
+		return
+	}
+	search, ok := s.userSavedSearch(writer, request)
+	if !ok {
+		return
+	}
+
+	search.Name = update.Name
+	search.Query = update.Query
+	if err := s.savedSearches.Update(request.Context(), search); err != nil {
+		s.reportSavedSearchError(writer, request, search.ID, err)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, search)
+	s.logger.Info("Изменён сохранённый поиск", "id", search.ID)
+}
+
+// deleteSavedSearch deletes the saved search with the ID given in the
+// URL and responds with a HTTP 204 No Content, or with a HTTP 404 Not
+// Found if the user has no such search.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the user and search IDs.
+func (s *Server) deleteSavedSearch(writer http.ResponseWriter, request *http.Request) {
+	search, ok := s.userSavedSearch(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := s.savedSearches.Delete(request.Context(), search.ID); err != nil {
+		s.reportSavedSearchError(writer, request, search.ID, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	s.logger.Info("Удалён сохранённый поиск", "id", search.ID)
+}
+
+// userSavedSearch returns the saved search named by the searchID URL
+// parameter if it belongs to the user named by the id URL parameter.
+// Otherwise it answers the request with a HTTP 404 Not Found, or the
+// status of the storage error, and returns false.
+func (s *Server) userSavedSearch(writer http.ResponseWriter, request *http.Request) (SavedSearch, bool) {
+	searchID := chi.URLParam(request, "searchID")
+	search, err := s.savedSearches.Get(request.Context(), searchID)
+	if err == nil && search.UserID != chi.URLParam(request, "id") {
+		err = &StorageError{Op: "get", Entity: "saved search", ID: searchID, Err: ErrSavedSearchNotFound}
+	}
+	if err != nil {
+		s.reportSavedSearchError(writer, request, searchID, err)
+		return SavedSearch{}, false
+	}
+	return search, true
+}
+
+// reportSavedSearchError answers a request that failed with a storage
+// error about the saved search with the given ID.
+func (s *Server) reportSavedSearchError(writer http.ResponseWriter, request *http.Request, searchID string, err error) {
+	if errors.Is(err, ErrSavedSearchNotFound) {
+		s.logger.Warn("Сохранённый поиск не найден", "id", searchID)
+		localizedError(writer, request, MsgSavedSearchNotFound, storageErrorStatus(err))
+		return
+	}
+	s.logger.Error("Ошибка хранилища сохранённых поисков", "id", searchID, "error", err)
+	http.Error(writer, err.Error(), storageErrorStatus(err))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// saveSearch saves a search for the user and returns it.
+func saveSearch(t *testing.T, s *Server, userID, body string) SavedSearch {
+	t.Helper()
+	response := serve(s, http.MethodPost, "/users/"+userID+"/saved-searches", body)
+	assertStatus(t, response, http.StatusCreated)
+	var search SavedSearch
+	decodeJSON(t, response.Body.Bytes(), &search)
+	return search
+}
+
+// savedSearchTaskIDs runs GET /tasks with the given query and returns
+// the sorted IDs of the tasks found.
+func savedSearchTaskIDs(t *testing.T, s *Server, query string) []string {
+	t.Helper()
+	response := serve(s, http.MethodGet, "/tasks?"+query, "")
+	assertStatus(t, response, http.StatusOK)
+	var tasks map[string]Task
+	decodeJSON(t, response.Body.Bytes(), &tasks)
+	ids := make([]string, 0, len(tasks))
+	for id := range tasks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSaveSearch(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	search := saveSearch(t, s, "alice", `{"name":"Задания","query":"description:задание"}`)
+	if search.ID == "" || search.UserID != "alice" || search.Name != "Задания" || search.Query != "description:задание" || !search.CreatedAt.Equal(testNow) {
+		t.Errorf("saved search = %+v", search)
+	}
+	other := saveSearch(t, s, "alice", `{"name":"Git","query":"application:git"}`)
+
+	response := serve(s, http.MethodGet, "/users/alice/saved-searches", "")
+	assertStatus(t, response, http.StatusOK)
+	var searches []SavedSearch
+	decodeJSON(t, response.Body.Bytes(), &searches)
+	if want := []SavedSearch{other, search}; !reflect.DeepEqual(searches, want) {
+		t.Errorf("searches of alice = %+v, want %+v ordered by name", searches, want)
+	}
+
+	response = serve(s, http.MethodGet, "/users/alice/saved-searches/"+search.ID, "")
+	assertStatus(t, response, http.StatusOK)
+	var got SavedSearch
+	decodeJSON(t, response.Body.Bytes(), &got)
+	if !reflect.DeepEqual(got, search) {
+		t.Errorf("GET saved search = %+v, want %+v", got, search)
+	}
+	// The searches of a user are not visible under another.
+	assertStatus(t, serve(s, http.MethodGet, "/users/bob/saved-searches/"+search.ID, ""), http.StatusNotFound)
+}
+
+func TestSaveSearchRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "unbalanced parenthesis", body: `{"name":"Сломанный","query":"(description:задание"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown field", body: `{"name":"Сломанный","query":"priority:1"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "no query", body: `{"name":"Пустой"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "no name", body: `{"query":"note:ура"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", body: `{"name":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			assertStatus(t, serve(s, http.MethodPost, "/users/alice/saved-searches", tt.body), tt.wantStatus)
+			if searches, err := s.savedSearches.GetAll(context.Background(), "alice"); err != nil || len(searches) != 0 {
+				t.Errorf("searches after a rejected save = %+v, %v", searches, err)
+			}
+		})
+	}
+}
+
+func TestExecuteSavedSearch(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	search := saveSearch(t, s, "alice", `{"name":"Задания","query":"description:задание"}`)
+
+	if got, want := savedSearchTaskIDs(t, s, "saved_search="+search.ID), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks of the saved search = %q, want %q", got, want)
+	}
+	// A filter narrows the saved search down.
+	if got, want := savedSearchTaskIDs(t, s, "saved_search="+search.ID+"&filter=application:postman"), []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks of the saved search and filter = %q, want %q", got, want)
+	}
+
+	assertStatus(t, serve(s, http.MethodGet, "/tasks?saved_search=unknown", ""), http.StatusNotFound)
+	assertStatus(t, serve(s, http.MethodHead, "/tasks?saved_search=unknown", ""), http.StatusNotFound)
+}
+
+func TestUpdateSavedSearch(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	search := saveSearch(t, s, "alice", `{"name":"Задания","query":"description:задание"}`)
+
+	response := serve(s, http.MethodPut, "/users/alice/saved-searches/"+search.ID, `{"name":"Postman","query":"application:postman"}`)
+	assertStatus(t, response, http.StatusOK)
+	var updated SavedSearch
+	decodeJSON(t, response.Body.Bytes(), &updated)
+	want := search
+	want.Name, want.Query = "Postman", "application:postman"
+	if !reflect.DeepEqual(updated, want) {
+		t.Errorf("updated search = %+v, want %+v", updated, want)
+	}
+	// Running the search runs its new query.
+	if got, want := savedSearchTaskIDs(t, s, "saved_search="+search.ID), []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks of the updated search = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid query", target: "/users/alice/saved-searches/" + search.ID, body: `{"name":"Сломанный","query":"note:"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown search", target: "/users/alice/saved-searches/unknown", body: `{"name":"Новый","query":"note:ура"}`, wantStatus: http.StatusNotFound},
+		{name: "search of another user", target: "/users/bob/saved-searches/" + search.ID, body: `{"name":"Чужой","query":"note:ура"}`, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, serve(s, http.MethodPut, tt.target, tt.body), tt.wantStatus)
+			if got, err := s.savedSearches.Get(context.Background(), search.ID); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("search after a rejected update = %+v, %v, want %+v", got, err, want)
+			}
+		})
+	}
+}
+
+func TestDeleteSavedSearch(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	search := saveSearch(t, s, "alice", `{"name":"Задания","query":"description:задание"}`)
+
+	assertStatus(t, serve(s, http.MethodDelete, "/users/bob/saved-searches/"+search.ID, ""), http.StatusNotFound)
+	assertStatus(t, serve(s, http.MethodDelete, "/users/alice/saved-searches/"+search.ID, ""), http.StatusNoContent)
+	assertStatus(t, serve(s, http.MethodDelete, "/users/alice/saved-searches/"+search.ID, ""), http.StatusNotFound)
+	assertStatus(t, serve(s, http.MethodGet, "/tasks?saved_search="+search.ID, ""), http.StatusNotFound)
+}
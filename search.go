@@ -0,0 +1,220 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenize splits text into lowercase words, breaking at every rune
+// that is neither a letter nor a digit.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchIndex is an inverted index over the description and note of
+// tasks. It is not safe for concurrent use; InMemoryStorage guards it
+// with its own lock.
+type searchIndex struct {
+	// postings maps every term to the number of times it occurs in
+	// each task that contains it.
+	postings map[string]map[string]int
+	// lengths holds the number of terms of every indexed task.
+	lengths map[string]int
+	// terms holds the distinct terms of every indexed task, so that
+	// removing a task does not need its text.
+	terms map[string][]string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string]int),
+		lengths:  make(map[string]int),
+		terms:    make(map[string][]string),
+	}
+}
+
+// add indexes the task, replacing an earlier version of it.
+func (index *searchIndex) add(task Task) {
+	index.remove(task.ID)
+
+	tokens := append(tokenize(task.Description), tokenize(task.Note)...)
+	for _, term := range tokens {
+		postings, ok := index.postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			index.postings[term] = postings
+		}
+		if postings[task.ID] == 0 {
+			index.terms[task.ID] = append(index.terms[task.ID], term)
+		}
+		postings[task.ID]++
+	}
+	index.lengths[task.ID] = len(tokens)
+}
+
+func (index *searchIndex) remove(id string) {
+	for _, term := range index.terms[id] {
+		delete(index.postings[term], id)
+		if len(index.postings[term]) == 0 {
+			delete(index.postings, term)
+		}
+	}
+	delete(index.terms, id)
+	delete(index.lengths, id)
+}
+
+// scores returns the TF-IDF score of every task containing at least
+// one of the query's terms. The term frequency is normalized by the
+// length of the task, and the inverse document frequency is smoothed
+// as ln(1 + N/df) so that a term found in every task still counts.
+func (index *searchIndex) scores(query string) map[string]float64 {
+	scores := make(map[string]float64)
+	total := float64(len(index.lengths))
+	for _, term := range tokenize(query) {
+		postings := index.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		idf := math.Log(1 + total/float64(len(postings)))
+		for id, count := range postings {
+			scores[id] += float64(count) / float64(index.lengths[id]) * idf
+		}
+	}
+	return scores
+}
+
+// fuzzyScores scores the tasks like scores, but lets every query term
+// match the indexed terms within maxDistance edits of it. A match at
+// distance d counts 1/(1+d) of an exact one.
+func (index *searchIndex) fuzzyScores(query string, maxDistance int) map[string]float64 {
+	scores := make(map[string]float64)
+	total := float64(len(index.lengths))
+	for _, queryTerm := range tokenize(query) {
+		for term, postings := range index.postings {
+			distance := levenshtein(queryTerm, term, maxDistance)
+			if distance > maxDistance {
+				continue
+			}
+			weight := math.Log(1+total/float64(len(postings))) / float64(1+distance)
+			for id, count := range postings {
+				scores[id] += float64(count) / float64(index.lengths[id]) * weight
+			}
+		}
+	}
+	return scores
+}
+
+// levenshtein returns the number of single-rune insertions, deletions
+// and substitutions turning a into b, or any number above limit once
+// the distance is known to exceed it.
+func levenshtein(a, b string, limit int) int {
+	source, target := []rune(a), []rune(b)
+	if difference := len(source) - len(target); difference > limit || -difference > limit {
+		return limit + 1
+	}
+
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		rowMinimum := current[0]
+		for j := 1; j <= len(target); j++ {
+			substitution := previous[j-1]
+			if source[i-1] != target[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+			rowMinimum = min(rowMinimum, current[j])
+		}
+		if rowMinimum > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(target)]
+}
+
+// SearchResult is a task found by a search, with its relevance.
+type SearchResult struct {
+	Task
+	Score float64 `json:"_score"`
+}
+
+// sortSearchResults orders results by descending score, then by task ID.
+func sortSearchResults(results []SearchResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+}
+
+// defaultSearchFuzziness is the edit distance a search falls back to
+// when no task contains the query words exactly; maxSearchFuzziness
+// bounds what the fuzzy query parameter may ask for.
+const (
+	defaultSearchFuzziness = 2
+	maxSearchFuzziness     = 2
+)
+
+// SearchResponse is the result of a task search. Fuzzy tells whether
+// no task matched exactly and the results are approximate matches.
+type SearchResponse struct {
+	Fuzzy   bool           `json:"fuzzy"`
+	Results []SearchResult `json:"results"`
+}
+
+// searchTasks handles the HTTP request to search the descriptions and
+// notes of the tasks for the words of the q query parameter. It writes
+// the matching tasks, most relevant first, each with its TF-IDF score
+// in _score. If no task contains the words exactly, the words are
+// matched to those within the edit distance given by the fuzzy query
+// parameter, 2 by default, and fuzzy is set in the response; fuzzy=0
+// turns that off. A missing q, or a fuzzy that is not between 0 and 2,
+// is rejected with a HTTP 400 Bad Request; a storage error is reported
+// with the status chosen by storageErrorStatus.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the q and fuzzy query parameters.
+func (s *Server) searchTasks(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		s.logger.Warn("Пустой поисковый запрос")
+		http.Error(writer, "q must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	fuzziness := defaultSearchFuzziness
+	if param := request.URL.Query().Get("fuzzy"); param != "" {
+		var err error
+		if fuzziness, err = strconv.Atoi(param); err != nil || fuzziness < 0 || fuzziness > maxSearchFuzziness {
+			s.logger.Warn("Некорректная нечёткость поиска", "fuzzy", param)
+			http.Error(writer, "fuzzy must be an edit distance from 0 to 2", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, fuzzy, err := s.store.Search(request.Context(), query, fuzziness)
+	if err != nil {
+		s.logger.Error("Не удалось выполнить поиск задач", "q", query, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	for i := range results {
+		s.presentTask(&results[i].Task)
+	}
+
+	s.writeJSON(writer, http.StatusOK, SearchResponse{Fuzzy: fuzzy, Results: results})
+	s.logger.Info("Выполнен поиск задач", "q", query, "fuzzy", fuzzy, "count", len(results))
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// searchTestTasks are the tasks the search tests look through.
+var searchTestTasks = []Task{
+	{ID: "report", Description: "Отчёт", Note: "Квартальный отчёт, отчёт для банка", Applications: []string{}},
+	{ID: "meeting", Description: "Встреча с командой", Note: "Обсудить отчёт и план на неделю", Applications: []string{}},
+	{ID: "review", Description: "Ревью кода", Note: "", Applications: []string{}},
+}
+
+// search runs GET /tasks/search with the given query parameters.
+func search(t *testing.T, s *Server, query url.Values) SearchResponse {
+	t.Helper()
+	response := serve(s, http.MethodGet, "/tasks/search?"+query.Encode(), "")
+	assertStatus(t, response, http.StatusOK)
+	var results SearchResponse
+	decodeJSON(t, response.Body.Bytes(), &results)
+	return results
+}
+
+func searchResultIDs(results []SearchResult) string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSearchRanksByRelevance(t *testing.T) {
+	s, _ := newTestServer(t, searchTestTasks...)
+
+	results := search(t, s, url.Values{"q": {"отчёт"}})
+	if results.Fuzzy {
+		t.Error("fuzzy = true for an exact match")
+	}
+	// Three of the six words of "report" are the term, against one of
+	// the nine words of "meeting".
+	if got := searchResultIDs(results.Results); got != "report,meeting" {
+		t.Fatalf("results = %s, want report,meeting", got)
+	}
+	if first, second := results.Results[0].Score, results.Results[1].Score; first <= second {
+		t.Errorf("_score of report = %v, not above _score of meeting = %v", first, second)
+	}
+}
+
+func TestSearchIndexScores(t *testing.T) {
+	index := newSearchIndex()
+	for _, task := range searchTestTasks {
+		index.add(task)
+	}
+
+	scores := index.scores("ОТЧЁТ")
+	// idf = ln(1 + 3 tasks / 2 containing the term)
+	idf := math.Log(1 + 3.0/2)
+	want := map[string]float64{"report": 3.0 / 6 * idf, "meeting": 1.0 / 9 * idf}
+	if len(scores) != len(want) {
+		t.Fatalf("scores = %v, want %v", scores, want)
+	}
+	for id, score := range want {
+		if math.Abs(scores[id]-score) > 1e-9 {
+			t.Errorf("score of %s = %v, want %v", id, scores[id], score)
+		}
+	}
+
+	if scores := index.scores("отпуск"); len(scores) != 0 {
+		t.Errorf("scores of an unknown term = %v, want none", scores)
+	}
+}
+
+func TestSearchFollowsStorageChanges(t *testing.T) {
+	store := NewInMemoryStorage(searchTestTasks...)
+	ctx := context.Background()
+
+	updated := searchTestTasks[2]
+	updated.Note = "Проверить отчёт стажёра"
+	if err := store.Update(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "report"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(ctx, Task{ID: "budget", Description: "Бюджет", Note: "отчёт", Applications: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, _, err := store.Search(ctx, "отчёт", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := searchResultIDs(results); got != "budget,review,meeting" {
+		t.Errorf("results = %s, want budget,review,meeting", got)
+	}
+}
+
+func TestSearchRejectsMalformedQueries(t *testing.T) {
+	s, _ := newTestServer(t, searchTestTasks...)
+	for _, target := range []string{"/tasks/search", "/tasks/search?q=+", "/tasks/search?q=a&fuzzy=3", "/tasks/search?q=a&fuzzy=many"} {
+		assertStatus(t, serve(s, http.MethodGet, target, ""), http.StatusBadRequest)
+	}
+}
+
+func TestSearchFuzzyTypos(t *testing.T) {
+	s, _ := newTestServer(t, searchTestTasks...)
+
+	tests := []struct {
+		name      string
+		query     url.Values
+		wantFuzzy bool
+		want      string
+	}{
+		{name: "exact match is not fuzzy", query: url.Values{"q": {"ревью"}}, want: "review"},
+		{name: "substituted letter", query: url.Values{"q": {"ревбю"}}, wantFuzzy: true, want: "review"},
+		{name: "missing letter", query: url.Values{"q": {"встрча"}}, wantFuzzy: true, want: "meeting"},
+		{name: "extra letter", query: url.Values{"q": {"кодда"}}, wantFuzzy: true, want: "review"},
+		{name: "two typos", query: url.Values{"q": {"отчрт"}, "fuzzy": {"2"}}, wantFuzzy: true, want: "report,meeting"},
+		{name: "two typos beyond fuzzy=1", query: url.Values{"q": {"атчрт"}, "fuzzy": {"1"}}, wantFuzzy: true},
+		{name: "fuzzy=1 within reach", query: url.Values{"q": {"отчот"}, "fuzzy": {"1"}}, wantFuzzy: true, want: "report,meeting"},
+		{name: "fuzzy=0 turns it off", query: url.Values{"q": {"ревбю"}, "fuzzy": {"0"}}},
+		{name: "three typos", query: url.Values{"q": {"рвбюю"}}, wantFuzzy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := search(t, s, tt.query)
+			if results.Fuzzy != tt.wantFuzzy {
+				t.Errorf("fuzzy = %v, want %v", results.Fuzzy, tt.wantFuzzy)
+			}
+			if got := searchResultIDs(results.Results); got != tt.want {
+				t.Errorf("results = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFuzzyRanksCloserMatchesHigher(t *testing.T) {
+	s, _ := newTestServer(t,
+		Task{ID: "close", Description: "Кофе", Applications: []string{}},
+		Task{ID: "far", Description: "Кофта", Applications: []string{}},
+	)
+	results := search(t, s, url.Values{"q": {"кофн"}})
+	if got := searchResultIDs(results.Results); !results.Fuzzy || got != "close,far" {
+		t.Errorf("fuzzy = %v, results = %s, want close,far", results.Fuzzy, got)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{a: "отчёт", b: "отчёт", limit: 2, want: 0},
+		{a: "отчёт", b: "отчет", limit: 2, want: 1},
+		{a: "встреча", b: "встрча", limit: 2, want: 1},
+		{a: "kitten", b: "sitting", limit: 3, want: 3},
+		{a: "", b: "код", limit: 3, want: 3},
+		// Beyond the limit, any distance above it is reported.
+		{a: "kitten", b: "sitting", limit: 2, want: 3},
+		{a: "а", b: "абвгд", limit: 2, want: 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("levenshtein(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import "net/http"
+
+// SecurityHeadersConfig holds the values of the security headers set
+// on every response.
+type SecurityHeadersConfig struct {
+	ContentSecurityPolicy   string
+	StrictTransportSecurity string
+}
+
+// DefaultSecurityHeadersConfig returns the policy suited to a JSON
+// API: no content may be loaded by a page framing a response, and
+// browsers must only use HTTPS for a year.
+func DefaultSecurityHeadersConfig() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		ContentSecurityPolicy:   "default-src 'none'",
+		StrictTransportSecurity: "max-age=31536000; includeSubDomains",
+	}
+}
+
+// Middleware sets X-Content-Type-Options, X-Frame-Options,
+// Content-Security-Policy and Strict-Transport-Security on every
+// response before calling the next handler.
+func (config SecurityHeadersConfig) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			header := writer.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("X-Frame-Options", "DENY")
+			header.Set("Content-Security-Policy", config.ContentSecurityPolicy)
+			header.Set("Strict-Transport-Security", config.StrictTransportSecurity)
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// SecurityHeadersMiddleware sets the security headers with the default policy.
+func SecurityHeadersMiddleware() func(http.Handler) http.Handler {
+	return DefaultSecurityHeadersConfig().Middleware()
+}
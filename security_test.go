@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	custom := SecurityHeadersConfig{
+		ContentSecurityPolicy:   "default-src 'self'; img-src *",
+		StrictTransportSecurity: "max-age=60",
+	}
+	defaults := map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Content-Security-Policy":   "default-src 'none'",
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	}
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		status     int
+		want       map[string]string
+	}{
+		{
+			name: "default policy", middleware: SecurityHeadersMiddleware(), status: http.StatusOK,
+			want: defaults,
+		},
+		{
+			name: "error response", middleware: SecurityHeadersMiddleware(), status: http.StatusInternalServerError,
+			want: defaults,
+		},
+		{
+			name: "custom policy", middleware: custom.Middleware(), status: http.StatusOK,
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Content-Security-Policy":   "default-src 'self'; img-src *",
+				"Strict-Transport-Security": "max-age=60",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.middleware(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				http.Error(writer, "body", tt.status)
+			}))
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assertStatus(t, response, tt.status)
+			for name, want := range tt.want {
+				if got := response.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoutersSetSecurityHeaders(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminSecret = "s3cret"
+	s, _ := newTestServerWithConfig(t, cfg, LoadFixtures(t))
+	routers := map[string]http.Handler{"main": s.router, "admin": s.adminRouter}
+	for name, router := range routers {
+		t.Run(name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/no-such-route", nil))
+
+			assertStatus(t, response, http.StatusNotFound)
+			for _, header := range []string{"X-Content-Type-Options", "X-Frame-Options", "Content-Security-Policy", "Strict-Transport-Security"} {
+				if response.Header().Get(header) == "" {
+					t.Errorf("%s is missing", header)
+				}
+			}
+		})
+	}
+}
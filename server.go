@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Server wires the task handlers to a router and to their dependencies.
+type Server struct {
+	config Config
+	store  Storage
+	router *chi.Mux
+	// adminRouter serves the admin API on its own address. It is nil
+	// unless Config.AdminSecret is set.
+	adminRouter *chi.Mux
+	startedAt   time.Time
+	clock       Clock
+	logger      *slog.Logger
+	schemas     *SchemaRegistry
+	// responseSchemas is only compiled, and responses only checked
+	// against it, when Config.Debug is set.
+	responseSchemas *SchemaRegistry
+	blocklist       *FileBlocklist
+	// geoip is opened by Start when Config.GeoIPDB is set.
+	geoip CountryResolver
+	// queue receives every newly created task for downstream
+	// processing. It is nil, and posting a task enqueues nothing,
+	// unless Config.TaskQueueSize is positive.
+	queue *InMemoryQueue
+	flags atomic.Pointer[FeatureFlags]
+	// idempotency keeps the responses to POST /tasks requests made
+	// with an Idempotency-Key header.
+	idempotency IdempotencyStore
+	// breaches maps the IDs of the tasks already reported as breaching
+	// their SLA to the breach reported, see reportBreach.
+	breaches sync.Map
+	projects ProjectStorage
+	// savedSearches keeps the filters run by GET /tasks?saved_search=.
+	savedSearches SavedSearchStorage
+	jobs          JobStore
+	// cursors signs the cursors of GET /tasks/sync.
+	cursors *CursorSigner
+	// embedder and embeddings back GET /tasks/similar/{id}.
+	embedder   Embedder
+	embeddings EmbeddingIndex
+	// categorizer suggests the tags of new tasks.
+	categorizer Categorizer
+	// runner processes the jobs kept in jobs, see Start.
+	runner *JobRunner
+	// pins orders the pinned tasks.
+	pins *PinBoard
+	// analytics records the requests to the main router.
+	analytics *RequestAnalytics
+	// experiments assigns requests to the variants of the A/B
+	// experiments in Config.Experiments.
+	experiments *Experiments
+	// buildInfo is read once, since the binary does not change.
+	buildInfo BuildInfo
+	// deprecations lists the retired endpoints, see deprecate.
+	deprecations DeprecationPolicy
+}
+
+// NewServer creates a Server serving the tasks kept in store. Settings
+// left zero in cfg that the server cannot run without get their
+// DefaultConfig values, see Config.withDefaults.
+func NewServer(cfg Config, store Storage) *Server {
+	cfg = cfg.withDefaults()
+	server := &Server{
+		config:        cfg,
+		store:         store,
+		router:        chi.NewRouter(),
+		clock:         RealClock{},
+		logger:        slog.Default(),
+		schemas:       mustCompileSchemas(requestSchemas),
+		blocklist:     NewFileBlocklist(cfg.BlocklistFile),
+		idempotency:   NewInMemoryIdempotencyStore(),
+		projects:      NewInMemoryProjectStorage(),
+		savedSearches: NewInMemorySavedSearchStorage(),
+		jobs:          NewInMemoryJobStore(),
+		pins:          &PinBoard{},
+		cursors:       NewCursorSigner(cfg.SyncCursorSecret),
+		embedder:      HashingEmbedder{},
+		embeddings:    NewBruteForceIndex(),
+		categorizer:   NewKeywordCategorizer(cfg.CategoryKeywords),
+		analytics:     &RequestAnalytics{},
+		experiments:   NewExperiments(cfg.Experiments...),
+		buildInfo:     readBuildInfo(),
+		deprecations:  deprecationPolicy,
+	}
+	flags := cfg.FeatureFlags
+	server.flags.Store(&flags)
+	server.runner = NewJobRunner(server.jobs, server.clock, server.logger)
+	server.runner.Register(importJobType, importWorker{server: server})
+	server.runner.Register(exportJobType, exportWorker{server: server})
+	server.runner.Register(webhookJobType, webhookWorker{server: server, client: &http.Client{}})
+	if cfg.Debug {
+		server.responseSchemas = mustCompileSchemas(responseSchemas)
+	}
+	if cfg.TaskQueueSize > 0 {
+		server.queue = NewInMemoryQueue(cfg.TaskQueueSize)
+	}
+
+	server.routes()
+	return server
+}
+
+// WithLogger replaces the logger the server reports to.
+func (s *Server) WithLogger(logger *slog.Logger) *Server {
+	s.logger = logger
+	s.runner.logger = logger
+	return s
+}
+
+// WithDeprecations replaces the list of retired endpoints.
+func (s *Server) WithDeprecations(policy DeprecationPolicy) *Server {
+	s.deprecations = policy
+	return s
+}
+
+// WithIdempotencyStore replaces the store that keeps responses for
+// Idempotency-Key replays.
+func (s *Server) WithIdempotencyStore(store IdempotencyStore) *Server {
+	s.idempotency = store
+	return s
+}
+
+// WithEmbedder replaces the embedder GET /tasks/similar/{id} compares
+// tasks with, such as with a client of a hosted embedding model.
+func (s *Server) WithEmbedder(embedder Embedder) *Server {
+	s.embedder = embedder
+	return s
+}
+
+// WithCategorizer replaces the categorizer that suggests the tags of
+// new tasks.
+func (s *Server) WithCategorizer(categorizer Categorizer) *Server {
+	s.categorizer = categorizer
+	return s
+}
+
+// WithClock replaces the clock the server stamps and expires tasks with.
+func (s *Server) WithClock(clock Clock) *Server {
+	s.clock = clock
+	s.runner.clock = clock
+	return s
+}
+
+func (s *Server) routes() {
+	s.router.Use(middleware.RequestID)
+	// Before collectAnalytics, which reports the country.
+	if s.config.GeoIPDB != "" {
+		s.router.Use(s.geolocate)
+	}
+	s.router.Use(s.collectAnalytics)
+	s.router.Use(SecurityHeadersMiddleware())
+	s.router.Use(BlocklistMiddleware(s.blocklist))
+
+	s.router.Group(func(router chi.Router) {
+		if s.config.CSRFProtection {
+			router.Use(CSRFMiddleware)
+		}
+		if s.config.WrapResponses {
+			router.Use(s.wrapResponses)
+		}
+		router.Use(s.deprecate)
+		router.Use(s.validateRequest)
+		if s.responseSchemas != nil {
+			router.Use(s.validateResponse)
+		}
+
+		router.Get("/tasks", s.getTasks)
+		router.Head("/tasks", s.countTasks)
+		router.With(s.idempotent).Post("/tasks", s.postTask)
+		router.Post("/tasks/merge", s.postMergeTasks)
+		router.Post("/tasks/import", s.postImportTasks)
+		router.Post("/tasks/quick-capture", s.postQuickCapture)
+		router.Get("/tasks/export", s.exportTasks)
+		router.Get("/tasks/search", s.searchTasks)
+		router.Get("/tasks/autocomplete", s.autocompleteTasks)
+		router.Get("/tasks/snapshot", s.getTasksSnapshot)
+		router.Get("/tasks/diff", s.getTasksDiff)
+		router.Get("/tasks/sync", s.getTasksSync)
+		router.Get("/tasks/poll", s.getTasksPoll)
+		router.Get("/tasks/calendar", s.getTasksCalendar)
+		router.Get("/tasks/digest", s.getTasksDigest)
+		router.Get("/tasks/similar/{id}", s.getSimilarTasks)
+		router.Get("/tasks/{id}", s.getTask)
+		router.Delete("/tasks/{id}", s.deleteTask)
+		router.Post("/tasks/{id}/merge", s.postThreeWayMerge)
+		router.Post("/tasks/{id}/move", s.moveTask)
+		router.Post("/tasks/{id}/split", s.postSplitTask)
+		router.Post("/tasks/{id}/pin", s.pinTask)
+		router.Delete("/tasks/{id}/pin", s.unpinTask)
+
+		router.Get("/projects", s.getProjects)
+		router.Post("/projects", s.postProject)
+		router.Get("/projects/{id}", s.getProject)
+		router.Put("/projects/{id}", s.putProject)
+		router.Delete("/projects/{id}", s.deleteProject)
+		router.Get("/projects/{id}/tasks", s.getProjectTasks)
+
+		router.Get("/users/{id}/saved-searches", s.getSavedSearches)
+		router.Post("/users/{id}/saved-searches", s.postSavedSearch)
+		router.Get("/users/{id}/saved-searches/{searchID}", s.getSavedSearch)
+		router.Put("/users/{id}/saved-searches/{searchID}", s.putSavedSearch)
+		router.Delete("/users/{id}/saved-searches/{searchID}", s.deleteSavedSearch)
+
+		router.Get("/jobs", s.getJobs)
+		router.Get("/jobs/{id}", s.getJob)
+
+		router.Get("/version", s.getVersion)
+		router.Get("/changelog", s.getChangelog)
+		router.Get("/changelog/latest", s.getLatestChangelog)
+
+		if s.queue != nil {
+			router.Get("/queue/stats", s.getQueueStats)
+		}
+	})
+
+	// Integrations authenticate their callers by signature, and answer
+	// in the format of the calling service, so they are kept out of
+	// the CSRF check and the response envelope.
+	if s.config.SlackSigningSecret != "" {
+		s.router.Post("/integrations/slack/command", s.postSlackCommand)
+	}
+
+	if s.config.AdminSecret == "" {
+		return
+	}
+
+	s.adminRouter = chi.NewRouter()
+	s.adminRouter.Use(middleware.RequestID)
+	s.adminRouter.Use(SecurityHeadersMiddleware())
+	if s.config.WrapResponses {
+		s.adminRouter.Use(s.wrapResponses)
+	}
+	s.adminRouter.Route("/admin", func(router chi.Router) {
+		router.Use(s.requireAdminSecret)
+
+		router.Group(func(router chi.Router) {
+			router.Use(s.validateRequest)
+
+			router.Get("/stats", s.getAdminStats)
+			router.Get("/flags", s.getAdminFlags)
+			router.Get("/analytics/summary", s.getAdminAnalyticsSummary)
+			router.Get("/experiments", s.getAdminExperiments)
+			router.Get("/tasks/count", s.getAdminTaskCount)
+			router.Post("/cache/flush", s.postAdminCacheFlush)
+			router.Post("/storage/compact", s.postAdminStorageCompact)
+			router.Get("/jobs", s.getAdminJobs)
+			router.Post("/jobs", s.postAdminJob)
+			router.Get("/jobs/{id}", s.getAdminJob)
+			router.Post("/blocklist", s.postBlocklist)
+			router.Post("/webhooks/test", s.postAdminWebhookTest)
+			router.Get("/webhooks/deliveries", s.getAdminWebhookDeliveries)
+		})
+	})
+}
+
+// Start loads the blocklist and the GeoIP database, runs the
+// background loops and the job workers and serves HTTP requests on the
+// configured address, and the admin API on its own address, until a
+// listener fails.
+func (s *Server) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if s.config.GeoIPDB != "" {
+		reader, err := geoip2.Open(s.config.GeoIPDB)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		s.geoip = reader
+	}
+
+	if err := s.blocklist.Load(); err != nil {
+		return err
+	}
+	if err := s.blocklist.Watch(ctx, s.logger); err != nil {
+		return err
+	}
+
+	go s.expireTasks(ctx, s.config.ExpirationInterval)
+	go s.sendReminders(ctx, reminderInterval)
+	go s.sweepIdempotencyKeys(ctx, idempotencySweepInterval)
+	go s.sweepJobs(ctx, jobSweepInterval)
+	go s.reloadFeatureFlagsOnHangup(ctx)
+	go s.runner.Run(ctx, s.config.JobWorkers)
+
+	s.startedAt = s.clock.Now()
+	failed := make(chan error, 2)
+	go func() {
+		s.logger.Info("Listen and Serve", "addr", s.config.Addr)
+		failed <- http.ListenAndServe(s.config.Addr, s.router)
+	}()
+	if s.adminRouter != nil {
+		go func() {
+			s.logger.Info("Listen and Serve admin API", "addr", s.config.AdminAddr)
+			failed <- http.ListenAndServe(s.config.AdminAddr, s.adminRouter)
+		}()
+	}
+
+	return <-failed
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNow is the moment the fake clock of test servers starts at.
+var testNow = time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+// newTestServer creates a Server with the default configuration over a
+// fresh InMemoryStorage holding tasks. It logs nowhere and runs on a
+// FakeClock frozen at testNow.
+func newTestServer(t *testing.T, tasks ...Task) (*Server, *FakeClock) {
+	t.Helper()
+	return newTestServerWithConfig(t, DefaultConfig(), NewInMemoryStorage(tasks...))
+}
+
+// newTestServerWithConfig is newTestServer with the given
+// configuration and storage.
+func newTestServerWithConfig(t *testing.T, cfg Config, store Storage) (*Server, *FakeClock) {
+	t.Helper()
+	clock := NewFakeClock(testNow)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewServer(cfg, store).WithLogger(logger).WithClock(clock), clock
+}
+
+// serve sends a request with the given body, if not empty, through the
+// main router and returns the recorded response.
+func serve(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	return serveRequest(s, newRequest(method, target, body))
+}
+
+// newRequest creates a request with the given body, if not empty, sent
+// as JSON.
+func newRequest(method, target, body string) *http.Request {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return request
+}
+
+// serveRequest sends the request through the main router and returns
+// the recorded response.
+func serveRequest(s *Server, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// ttl returns a pointer to d, for Task.TTL.
+func ttl(d time.Duration) *time.Duration {
+	return &d
+}
+
+func assertStatus(t *testing.T, response *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if response.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", response.Code, want, response.Body.String())
+	}
+}
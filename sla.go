@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SLA statuses, as reported in Task.SLAStatus.
+const (
+	SLAOnTrack  = "on_track"
+	SLAAtRisk   = "at_risk"
+	SLABreached = "breached"
+)
+
+// slaAtRiskFraction is the share of the SLA deadline after which a
+// task is at risk.
+const slaAtRiskFraction = 0.75
+
+// slaDeadline returns when the task is due, and false if it has no
+// SLA deadline.
+func slaDeadline(task Task) (time.Time, bool) {
+	if task.SLADeadlineMinutes <= 0 {
+		return time.Time{}, false
+	}
+	return task.CreatedAt.Add(time.Duration(task.SLADeadlineMinutes) * time.Minute), true
+}
+
+// slaStatus returns the SLA status of the task at now, or "" if the
+// task has no SLA deadline.
+func slaStatus(task Task, now time.Time) string {
+	if task.SLADeadlineMinutes <= 0 {
+		return ""
+	}
+
+	deadline := time.Duration(task.SLADeadlineMinutes) * time.Minute
+	elapsed := now.Sub(task.CreatedAt)
+	switch {
+	case elapsed >= deadline:
+		return SLABreached
+	case elapsed >= time.Duration(float64(deadline)*slaAtRiskFraction):
+		return SLAAtRisk
+	default:
+		return SLAOnTrack
+	}
+}
+
+// parseSLAStatus checks a value of the sla_status query parameter.
+func parseSLAStatus(param string) (string, error) {
+	switch param {
+	case SLAOnTrack, SLAAtRisk, SLABreached:
+		return param, nil
+	default:
+		return "", fmt.Errorf("unknown SLA status %q, expected %s, %s or %s", param, SLAOnTrack, SLAAtRisk, SLABreached)
+	}
+}
+
+// reportBreach logs a warning the first time a task is seen breaching
+// its SLA. A breach is identified by the creation time and the deadline
+// of the task, so that a task created again under the same ID, or
+// given another deadline, is reported anew.
+func (s *Server) reportBreach(task Task) {
+	breach := strconv.FormatInt(task.CreatedAt.UnixNano(), 10) + "+" + strconv.Itoa(task.SLADeadlineMinutes) + "m"
+	if previous, reported := s.breaches.Swap(task.ID, breach); !reported || previous != breach {
+		s.logger.Warn("Задача нарушила SLA", "id", task.ID, "sla_deadline_minutes", task.SLADeadlineMinutes)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSLAStatus(t *testing.T) {
+	task := Task{ID: "1", CreatedAt: testNow, SLADeadlineMinutes: 60}
+	tests := []struct {
+		name    string
+		task    Task
+		elapsed time.Duration
+		want    string
+	}{
+		{name: "no deadline", task: Task{ID: "1", CreatedAt: testNow}, elapsed: time.Hour},
+		{name: "just created", task: task, want: SLAOnTrack},
+		{name: "before three quarters", task: task, elapsed: 44*time.Minute + 59*time.Second, want: SLAOnTrack},
+		{name: "at three quarters", task: task, elapsed: 45 * time.Minute, want: SLAAtRisk},
+		{name: "at the deadline", task: task, elapsed: time.Hour, want: SLABreached},
+		{name: "past the deadline", task: task, elapsed: 24 * time.Hour, want: SLABreached},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slaStatus(tt.task, testNow.Add(tt.elapsed)); got != tt.want {
+				t.Errorf("slaStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// newSLATestServer creates a test server holding one task due an hour
+// after testNow, logging to the returned buffer.
+func newSLATestServer(t *testing.T) (*Server, *FakeClock, *bytes.Buffer) {
+	t.Helper()
+	s, clock := newTestServer(t,
+		Task{ID: "1", Description: "Сдать отчёт", Applications: []string{}, CreatedAt: testNow, SLADeadlineMinutes: 60},
+		Task{ID: "2", Description: "Проверить отчёт", Applications: []string{}, CreatedAt: testNow},
+	)
+	logs := &bytes.Buffer{}
+	s.WithLogger(slog.New(slog.NewTextHandler(logs, nil)))
+	return s, clock, logs
+}
+
+// breachWarnings counts the SLA breach warnings in logs.
+func breachWarnings(logs *bytes.Buffer) int {
+	return strings.Count(logs.String(), "Задача нарушила SLA")
+}
+
+func TestSLAStatusFollowsTheClock(t *testing.T) {
+	s, clock, _ := newSLATestServer(t)
+
+	for _, step := range []struct {
+		advance time.Duration
+		want    string
+	}{
+		{want: SLAOnTrack},
+		{advance: 45 * time.Minute, want: SLAAtRisk},
+		{advance: 15 * time.Minute, want: SLABreached},
+	} {
+		clock.Advance(step.advance)
+		response := serve(s, http.MethodGet, "/tasks/1", "")
+		assertStatus(t, response, http.StatusOK)
+		var task Task
+		decodeJSON(t, response.Body.Bytes(), &task)
+		if task.SLAStatus != step.want {
+			t.Errorf("at %v: sla_status = %q, want %q", clock.Now().Sub(testNow), task.SLAStatus, step.want)
+		}
+
+		response = serve(s, http.MethodHead, "/tasks?sla_status="+step.want, "")
+		if got := response.Header().Get("X-Total-Count"); got != "1" {
+			t.Errorf("at %v: X-Total-Count for %s = %q, want 1", clock.Now().Sub(testNow), step.want, got)
+		}
+	}
+}
+
+func TestSLABreachReportedOnce(t *testing.T) {
+	s, clock, logs := newSLATestServer(t)
+
+	serve(s, http.MethodGet, "/tasks/1", "")
+	if got := breachWarnings(logs); got != 0 {
+		t.Fatalf("%d breach warnings before the deadline, want 0", got)
+	}
+
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		serve(s, http.MethodGet, "/tasks/1", "")
+		serve(s, http.MethodGet, "/tasks", "")
+	}
+	if got := breachWarnings(logs); got != 1 {
+		t.Errorf("%d breach warnings after repeated reads, want 1", got)
+	}
+}
+
+func TestSLABreachForgotten(t *testing.T) {
+	breached := func(t *testing.T, s *Server) bool {
+		t.Helper()
+		_, ok := s.breaches.Load("1")
+		return ok
+	}
+
+	t.Run("deleted task", func(t *testing.T) {
+		s, clock, logs := newSLATestServer(t)
+		clock.Advance(time.Hour)
+		serve(s, http.MethodGet, "/tasks/1", "")
+
+		assertStatus(t, serve(s, http.MethodDelete, "/tasks/1", ""), http.StatusOK)
+		if breached(t, s) {
+			t.Error("the breach of a deleted task is still remembered")
+		}
+
+		// The same ID, created again, is a new task breaching anew.
+		body := `{"id":"1","description":"Сдать отчёт заново","note":"","applications":[],"sla_deadline_minutes":60}`
+		assertStatus(t, serve(s, http.MethodPost, "/tasks", body), http.StatusCreated)
+		clock.Advance(time.Hour)
+		serve(s, http.MethodGet, "/tasks/1", "")
+		if got := breachWarnings(logs); got != 2 {
+			t.Errorf("%d breach warnings, want 2", got)
+		}
+	})
+
+	t.Run("merged task", func(t *testing.T) {
+		s, clock, _ := newSLATestServer(t)
+		clock.Advance(time.Hour)
+		serve(s, http.MethodGet, "/tasks/1", "")
+
+		assertStatus(t, serve(s, http.MethodPost, "/tasks/merge", `{"keep_id":"2","discard_id":"1"}`), http.StatusOK)
+		if breached(t, s) {
+			t.Error("the breach of a merged task is still remembered")
+		}
+	})
+
+	t.Run("new deadline", func(t *testing.T) {
+		s, clock, logs := newSLATestServer(t)
+		clock.Advance(time.Hour)
+		serve(s, http.MethodGet, "/tasks/1", "")
+
+		task, err := s.store.Get(context.Background(), "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		task.SLADeadlineMinutes = 90
+		if err := s.store.Update(context.Background(), task); err != nil {
+			t.Fatal(err)
+		}
+		serve(s, http.MethodGet, "/tasks/1", "")
+		clock.Advance(30 * time.Minute)
+		serve(s, http.MethodGet, "/tasks/1", "")
+		if got := breachWarnings(logs); got != 2 {
+			t.Errorf("%d breach warnings, want 2: one per deadline", got)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// slackMaxBodyBytes bounds the size of a slash command request.
+	slackMaxBodyBytes = 64 << 10
+	// slackMaxClockSkew is how old a signed Slack request may be, so
+	// that captured requests cannot be replayed later.
+	slackMaxClockSkew = 5 * time.Minute
+)
+
+// ErrSlackSignatureInvalid is returned when a request does not carry a
+// valid, recent Slack signature.
+var ErrSlackSignatureInvalid = errors.New("invalid Slack request signature")
+
+// verifySlackSignature checks the X-Slack-Signature of a request: the
+// hex HMAC-SHA256, keyed with the signing secret, of "v0:", the
+// X-Slack-Request-Timestamp, ":" and the body, prefixed with "v0=".
+// The timestamp must be within slackMaxClockSkew of now.
+func verifySlackSignature(secret, timestamp, signature string, body []byte, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSlackSignatureInvalid
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > slackMaxClockSkew || skew < -slackMaxClockSkew {
+		return ErrSlackSignatureInvalid
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrSlackSignatureInvalid
+	}
+	return nil
+}
+
+// SlackResponse is the message a slash command answers with.
+// Ephemeral messages are only shown to the user who ran the command.
+type SlackResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func slackReply(text string) SlackResponse {
+	return SlackResponse{ResponseType: "ephemeral", Text: text}
+}
+
+// parseSlackCommandText splits the text of a slash command into the
+// task description and its #tags: words starting with # followed by
+// letters, digits, - or _. The tags are returned lowercased, without
+// the #, sorted and without duplicates, and are removed from the
+// description.
+func parseSlackCommandText(text string) (description string, tags []string) {
+	notTagRune := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	}
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if tag, isTag := strings.CutPrefix(word, "#"); isTag && tag != "" && strings.IndexFunc(tag, notTagRune) < 0 {
+			tags = append(tags, strings.ToLower(tag))
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), mergeTags(tags, nil)
+}
+
+// postSlackCommand handles the callback of a Slack slash command,
+// such as /task Finish the report #work, by creating a task described
+// by the command text, under a generated ID. Like imported tasks, the
+// task goes through importTask, so tags are suggested by the
+// categorizer; the #tags of the text, see parseSlackCommandText, are
+// left out of the description and suggested as well. The request must
+// be signed with the configured Slack signing secret, or it is refused
+// with a HTTP 401 Unauthorized. Slack shows any other status as a
+// failure of its own, so problems with the task are answered with a
+// HTTP 200 OK and an explanatory message.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from Slack, with the form-encoded command in the body.
+func (s *Server) postSlackCommand(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, slackMaxBodyBytes))
+	if err != nil {
+		s.logger.Warn("Не удалось прочитать команду Slack", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = verifySlackSignature(s.config.SlackSigningSecret, request.Header.Get("X-Slack-Request-Timestamp"),
+		request.Header.Get("X-Slack-Signature"), body, s.clock.Now())
+	if err != nil {
+		s.logger.Warn("Неверная подпись запроса Slack", "remote_addr", request.RemoteAddr)
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		s.logger.Warn("Некорректная команда Slack", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	text, tags := parseSlackCommandText(form.Get("text"))
+	if text == "" {
+		s.writeJSON(writer, http.StatusOK, slackReply(fmt.Sprintf("Usage: %s <task description>", form.Get("command"))))
+		return
+	}
+
+	taskID, err := newID()
+	if err != nil {
+		s.logger.Error("Не удалось создать ID задачи", "error", err)
+		s.writeJSON(writer, http.StatusOK, slackReply("The task could not be created: "+err.Error()))
+		return
+	}
+	task := Task{ID: taskID, Description: text, Applications: []string{}, SuggestedTags: tags}
+	if user := form.Get("user_name"); user != "" {
+		task.Note = "Created from Slack by @" + user
+	}
+	if err = s.importTask(request.Context(), task); err != nil {
+		s.logger.Error("Не удалось создать задачу из Slack", "id", taskID, "error", err)
+		s.writeJSON(writer, http.StatusOK, slackReply("The task could not be created: "+err.Error()))
+		return
+	}
+
+	reply := fmt.Sprintf("Created task %s: %s", taskID, text)
+	if len(tags) > 0 {
+		reply += " #" + strings.Join(tags, " #")
+	}
+	s.writeJSON(writer, http.StatusOK, slackReply(reply))
+	s.logger.Info("Создана задача из Slack", "id", taskID, "user", form.Get("user_name"))
+}
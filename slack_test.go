@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseSlackCommandText(t *testing.T) {
+	tests := []struct {
+		text            string
+		wantDescription string
+		wantTags        []string
+	}{
+		{text: "Сдать отчёт до пятницы #work", wantDescription: "Сдать отчёт до пятницы", wantTags: []string{"work"}},
+		{text: "#Срочно Позвонить  #work клиенту #work", wantDescription: "Позвонить клиенту", wantTags: []string{"work", "срочно"}},
+		{text: "Разобрать #q1-планы и #to_do", wantDescription: "Разобрать и", wantTags: []string{"q1-планы", "to_do"}},
+		// Only whole words made of tag characters are tags.
+		{text: "Задача # номер #1, C#", wantDescription: "Задача # номер #1, C#", wantTags: nil},
+		{text: "Без тегов", wantDescription: "Без тегов", wantTags: nil},
+		{text: "#work #home", wantDescription: "", wantTags: []string{"home", "work"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			description, tags := parseSlackCommandText(tt.text)
+			if description != tt.wantDescription || !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("parseSlackCommandText() = %q, %q, want %q, %q", description, tags, tt.wantDescription, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestPostSlackCommandTags(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SlackSigningSecret = "secret"
+	cfg.CategoryKeywords = map[string]string{"отчёт": "docs"}
+	s, _ := newTestServerWithConfig(t, cfg, NewInMemoryStorage())
+
+	send := func(text string) SlackResponse {
+		t.Helper()
+		body := url.Values{"command": {"/task"}, "text": {text}}.Encode()
+		timestamp := strconv.FormatInt(testNow.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(cfg.SlackSigningSecret))
+		mac.Write([]byte("v0:" + timestamp + ":" + body))
+		request := newRequest(http.MethodPost, "/integrations/slack/command", body)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		request.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		response := serveRequest(s, request)
+		assertStatus(t, response, http.StatusOK)
+		var reply SlackResponse
+		decodeJSON(t, response.Body.Bytes(), &reply)
+		return reply
+	}
+
+	reply := send("Сдать отчёт #work #Срочно")
+	tasks, err := s.store.GetAll(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("%d tasks, want 1", len(tasks))
+	}
+	task := tasks[0]
+	if want := []string{"docs", "work", "срочно"}; task.Description != "Сдать отчёт" || !reflect.DeepEqual(task.SuggestedTags, want) {
+		t.Errorf("task = %+v, want description %q and suggested tags %q", task, "Сдать отчёт", want)
+	}
+	if want := "Created task " + task.ID + ": Сдать отчёт #work #срочно"; reply.Text != want {
+		t.Errorf("reply = %q, want %q", reply.Text, want)
+	}
+
+	// A command of tags alone describes no task.
+	if reply := send("#work"); reply.Text != "Usage: /task <task description>" {
+		t.Errorf("reply = %q, want the usage", reply.Text)
+	}
+}
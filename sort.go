@@ -0,0 +1,90 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// SortSpec is one key of a multi-key sort: the field to compare and
+// the direction to order it in.
+type SortSpec struct {
+	Field      string
+	Descending bool
+}
+
+// taskComparators holds, for every sortable field, a function returning
+// a negative number, zero or a positive number when the first task
+// orders before, together with or after the second one.
+var taskComparators = map[string]func(a, b Task) int{
+	"id":                func(a, b Task) int { return strings.Compare(a.ID, b.ID) },
+	"description":       func(a, b Task) int { return strings.Compare(a.Description, b.Description) },
+	"note":              func(a, b Task) int { return strings.Compare(a.Note, b.Note) },
+	"created_at":        func(a, b Task) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"pin_order":         comparePinOrder,
+	"computed_priority": func(a, b Task) int { return cmp.Compare(a.ComputedPriority, b.ComputedPriority) },
+}
+
+// comparePinOrder orders pinned tasks by pin order, before unpinned ones.
+func comparePinOrder(a, b Task) int {
+	switch {
+	case a.PinOrder == nil && b.PinOrder == nil:
+		return 0
+	case a.PinOrder == nil:
+		return 1
+	case b.PinOrder == nil:
+		return -1
+	default:
+		return *a.PinOrder - *b.PinOrder
+	}
+}
+
+// ParseSort parses a comma-separated list of field names, each
+// optionally prefixed with "-" for descending order, e.g.
+// "-created_at,description". It rejects unknown and empty field names.
+func ParseSort(param string) ([]SortSpec, error) {
+	var specs []SortSpec
+	for _, field := range strings.Split(param, ",") {
+		spec := SortSpec{Field: strings.TrimSpace(field)}
+		if strings.HasPrefix(spec.Field, "-") {
+			spec.Field = spec.Field[1:]
+			spec.Descending = true
+		}
+
+		if _, ok := taskComparators[spec.Field]; !ok {
+			return nil, fmt.Errorf("unknown sort field %q", spec.Field)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// TaskSorter orders tasks by a list of SortSpec, consulting each spec
+// only when all previous ones consider two tasks equal. Tasks equal by
+// every spec are ordered by ID, so that the order does not depend on
+// the order the storage returned them in.
+type TaskSorter struct {
+	Tasks []Task
+	Specs []SortSpec
+}
+
+func (sorter TaskSorter) Len() int {
+	return len(sorter.Tasks)
+}
+
+func (sorter TaskSorter) Swap(i, j int) {
+	sorter.Tasks[i], sorter.Tasks[j] = sorter.Tasks[j], sorter.Tasks[i]
+}
+
+func (sorter TaskSorter) Less(i, j int) bool {
+	for _, spec := range sorter.Specs {
+		result := taskComparators[spec.Field](sorter.Tasks[i], sorter.Tasks[j])
+		if spec.Descending {
+			result = -result
+		}
+		if result != 0 {
+			return result < 0
+		}
+	}
+	return sorter.Tasks[i].ID < sorter.Tasks[j].ID
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		param   string
+		want    []SortSpec
+		wantErr bool
+	}{
+		{param: "id", want: []SortSpec{{Field: "id"}}},
+		{param: "-created_at", want: []SortSpec{{Field: "created_at", Descending: true}}},
+		{
+			param: "-created_at, description",
+			want:  []SortSpec{{Field: "created_at", Descending: true}, {Field: "description"}},
+		},
+		{param: "priority", wantErr: true},
+		{param: "id,", wantErr: true},
+		{param: "-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.param, func(t *testing.T) {
+			got, err := ParseSort(tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskSorterBreaksTies(t *testing.T) {
+	early, late := testNow, testNow.Add(time.Hour)
+	tasks := []Task{
+		{ID: "1", Description: "b", CreatedAt: early},
+		{ID: "2", Description: "a", CreatedAt: late},
+		{ID: "3", Description: "a", CreatedAt: early},
+		{ID: "4", Description: "b", CreatedAt: early},
+		{ID: "5", Description: "a", CreatedAt: early},
+	}
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{sort: "description", want: []string{"2", "3", "5", "1", "4"}},
+		{sort: "-description", want: []string{"1", "4", "2", "3", "5"}},
+		{sort: "description,-created_at", want: []string{"2", "3", "5", "1", "4"}},
+		{sort: "created_at,description", want: []string{"3", "5", "1", "4", "2"}},
+		{sort: "-created_at,-description", want: []string{"2", "1", "4", "3", "5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			specs, err := ParseSort(tt.sort)
+			if err != nil {
+				t.Fatalf("ParseSort() error = %v", err)
+			}
+			sorted := append([]Task(nil), tasks...)
+			sort.Stable(TaskSorter{Tasks: sorted, Specs: specs})
+			if got := taskIDs(sorted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTasksOrderIsRepeatable(t *testing.T) {
+	// Many tasks with equal keys, so that the order of the map the
+	// storage iterates shows if ties are not broken.
+	tasks := fixtureTasks(t)
+	for i := 0; i < 50; i++ {
+		tasks = append(tasks, Task{ID: "tie-" + strconv.Itoa(i), Description: "Одинаковая", Applications: []string{}, CreatedAt: testNow})
+	}
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), NewInMemoryStorage(tasks...))
+
+	for _, order := range []string{"created_at", "-created_at", "description", "-description,note", "-computed_priority"} {
+		t.Run(order, func(t *testing.T) {
+			var first []string
+			for i := 0; i < 100; i++ {
+				response := serve(s, http.MethodGet, "/tasks?fields=id&sort="+order, "")
+				assertStatus(t, response, http.StatusOK)
+				var page []Task
+				decodeJSON(t, response.Body.Bytes(), &page)
+				ids := taskIDs(page)
+				if i == 0 {
+					first = ids
+				} else if !reflect.DeepEqual(ids, first) {
+					t.Fatalf("request %d ordered the tasks %v, the first %v", i+1, ids, first)
+				}
+			}
+			if len(first) != len(tasks) {
+				t.Errorf("got %d tasks, want %d", len(first), len(tasks))
+			}
+		})
+	}
+}
+
+// taskIDs returns the IDs of tasks, in order.
+func taskIDs(tasks []Task) []string {
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// splitRequest is the body of POST /tasks/{id}/split.
+type splitRequest struct {
+	Descriptions []string `json:"descriptions"`
+}
+
+// splitTask returns the task parts are split into from task: a task
+// under a new ID with the given description and the note, applications,
+// TTL, SLA deadline and project of task, linked to it by SplitFrom.
+func splitTask(task Task, description string) (Task, error) {
+	id, err := newID()
+	if err != nil {
+		return Task{}, err
+	}
+	return Task{
+		ID:                 id,
+		Description:        description,
+		Note:               task.Note,
+		Applications:       append([]string{}, task.Applications...),
+		TTL:                task.TTL,
+		SLADeadlineMinutes: task.SLADeadlineMinutes,
+		ProjectID:          task.ProjectID,
+		SplitFrom:          task.ID,
+	}, nil
+}
+
+// postSplitTask handles the HTTP request to split the task with the ID
+// given in the URL into one task per entry of descriptions in the body,
+// at least two. The parts get new IDs and inherit the other fields of
+// the task, see splitTask, and its ID as split_from; each is created
+// as by POST /tasks, so a description another task has is refused. The
+// split task is kept as it is, with its pin: tasks have no status for
+// it to be marked done with. The response is a HTTP 201 Created with
+// the parts, in the order of descriptions.
+//
+// If the task does not exist, it responds with the status chosen by
+// storageErrorStatus. A description left empty once HTML is removed is
+// rejected with a HTTP 422 Unprocessable Entity and a part that cannot
+// be created with the status chosen by storageErrorStatus; either way
+// no part is kept and the task is left as it was.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the task ID and the descriptions.
+func (s *Server) postSplitTask(writer http.ResponseWriter, request *http.Request) {
+	var body splitRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело запроса разделения", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskID := chi.URLParam(request, "id")
+	task, err := s.store.Get(request.Context(), taskID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("Задача для разделения не найдена", "id", taskID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось получить задачу", "id", taskID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	parts := make([]Task, len(body.Descriptions))
+	for i, description := range body.Descriptions {
+		if s.featureFlags().EnableHTMLSanitization && strings.TrimSpace(stripHTML(description)) == "" {
+			s.logger.Warn("Описание части задачи пусто после очистки HTML", "id", taskID, "part", i+1)
+			localizedError(writer, request, MsgTaskDescriptionEmpty, http.StatusUnprocessableEntity)
+			return
+		}
+		if parts[i], err = splitTask(task, description); err != nil {
+			s.logger.Error("Не удалось создать идентификатор части задачи", "id", taskID, "error", err)
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// removeParts deletes the parts created so far, should another one
+	// fail.
+	removeParts := func(created []Task) {
+		for _, part := range created {
+			if err := s.store.Delete(request.Context(), part.ID); err != nil && !errors.Is(err, ErrNotFound) {
+				s.logger.Error("Не удалось удалить созданную часть задачи", "id", part.ID, "error", err)
+			}
+			s.forgetTask(part.ID)
+		}
+	}
+	for i := range parts {
+		if err = s.importTask(request.Context(), parts[i]); err == nil {
+			// importTask fills in a copy of the part; read it back.
+			parts[i], err = s.store.Get(request.Context(), parts[i].ID)
+		}
+		if err != nil {
+			s.logger.Error("Не удалось создать часть задачи", "id", taskID, "part", i+1, "error", err)
+			removeParts(parts[:i+1])
+			http.Error(writer, err.Error(), storageErrorStatus(err))
+			return
+		}
+	}
+
+	for i := range parts {
+		s.presentTask(&parts[i])
+	}
+	s.writeJSON(writer, http.StatusCreated, parts)
+	s.logger.Info("Задача разделена", "id", taskID, "parts", len(parts))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPostSplitTask(t *testing.T) {
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+	assertStatus(t, serve(s, http.MethodPost, "/tasks/1/pin", `{}`), http.StatusOK)
+
+	response := serve(s, http.MethodPost, "/tasks/1/split", `{"descriptions":["Написать код","Написать <b>тесты</b>"]}`)
+	assertStatus(t, response, http.StatusCreated)
+	var parts []Task
+	decodeJSON(t, response.Body.Bytes(), &parts)
+
+	original := fixtureTask(t, "1")
+	if len(parts) != 2 || parts[0].Description != "Написать код" || parts[1].Description != "Написать тесты" {
+		t.Fatalf("parts = %+v", parts)
+	}
+	for _, part := range parts {
+		if part.ID == "" || part.ID == original.ID || part.Note != original.Note || !reflect.DeepEqual(part.Applications, original.Applications) {
+			t.Errorf("part = %+v, want a new ID and the note and applications of task 1", part)
+		}
+		if !part.CreatedAt.Equal(testNow) || part.PinOrder != nil || part.SplitFrom != original.ID {
+			t.Errorf("part = %+v, want created now, not pinned and split from task 1", part)
+		}
+		if _, err := s.store.Get(context.Background(), part.ID); err != nil {
+			t.Errorf("part %s: %v", part.ID, err)
+		}
+	}
+	if parts[0].ID == parts[1].ID {
+		t.Errorf("both parts have ID %s", parts[0].ID)
+	}
+
+	// The split task is kept as it was.
+	response = serve(s, http.MethodGet, "/tasks/1", "")
+	assertStatus(t, response, http.StatusOK)
+	var kept Task
+	decodeJSON(t, response.Body.Bytes(), &kept)
+	if kept.Description != original.Description || kept.Note != original.Note || kept.PinOrder == nil || kept.SplitFrom != "" {
+		t.Errorf("split task = %+v, want task 1 unchanged and still pinned", kept)
+	}
+}
+
+func TestPostSplitTaskRejected(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "one description", target: "/tasks/1/split", body: `{"descriptions":["Только одна"]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "empty description", target: "/tasks/1/split", body: `{"descriptions":["Одна",""]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "no descriptions", target: "/tasks/1/split", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "description empty without HTML", target: "/tasks/1/split", body: `{"descriptions":["Одна","<b></b>"]}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", target: "/tasks/1/split", body: `{"descriptions":`, wantStatus: http.StatusBadRequest},
+		{name: "unknown task", target: "/tasks/99/split", body: `{"descriptions":["Одна","Другая"]}`, wantStatus: http.StatusBadRequest},
+		// The second part has the description of task 2.
+		{name: "duplicate description", target: "/tasks/1/split", body: `{"descriptions":["Новая часть","Протестировать задание"]}`, wantStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServerWithConfig(t, DefaultConfig(), LoadFixtures(t))
+			assertStatus(t, serve(s, http.MethodPost, tt.target, tt.body), tt.wantStatus)
+
+			// Nothing was split: the tasks are the fixtures.
+			tasks, err := s.store.GetAll(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != len(fixtureTasks(t)) {
+				t.Errorf("%d tasks after a rejected split, want %d", len(tasks), len(fixtureTasks(t)))
+			}
+			if _, err := s.store.Get(context.Background(), "1"); err != nil {
+				t.Errorf("task 1: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Storage keeps tasks. Implementations must be safe for concurrent use
+// and report failures as *StorageError wrapping one of the Err*
+// sentinels or a context error.
+type Storage interface {
+	// GetAll returns the tasks matched by filter, or every task if
+	// filter is nil, in no particular order.
+	GetAll(ctx context.Context, filter Filter) ([]Task, error)
+	// Get returns the task with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (Task, error)
+	// Create stores a new task, or returns ErrAlreadyExists if its ID
+	// is taken.
+	Create(ctx context.Context, task Task) error
+	// Update replaces the stored task with the same ID, or returns
+	// ErrNotFound.
+	Update(ctx context.Context, task Task) error
+	// Delete removes the task with the given ID, or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+	// Search returns the tasks whose description or note contains
+	// words of the query, most relevant first. If there are none and
+	// fuzziness is positive, it returns the tasks with words within
+	// that edit distance of the query's, and reports true.
+	Search(ctx context.Context, query string, fuzziness int) ([]SearchResult, bool, error)
+	// Autocomplete returns up to limit distinct descriptions with a
+	// word starting with prefix, ignoring case and accents.
+	Autocomplete(ctx context.Context, prefix string, limit int) ([]string, error)
+	// CreateUnique stores a new task like Create, but returns a
+	// *DuplicateDescriptionError if a task has the same description,
+	// ignoring case. The check and the write are atomic.
+	CreateUnique(ctx context.Context, task Task) error
+	// Snapshot returns every task together with the current
+	// generation, which is incremented by every write.
+	Snapshot(ctx context.Context) (uint64, []Task, error)
+	// Changes returns the current generation and the writes made
+	// after generation since, oldest first, or ErrGenerationExpired if
+	// they are no longer known.
+	Changes(ctx context.Context, since uint64) (uint64, []TaskChange, error)
+	// WaitForChange blocks until the generation is later than since,
+	// and returns it, or returns the context error once ctx is done.
+	WaitForChange(ctx context.Context, since uint64) (uint64, error)
+}
+
+// Compacter is implemented by storages that can give back the memory
+// held for tasks that were since deleted.
+type Compacter interface {
+	// Compact rebuilds the internal structures of the storage from the
+	// stored tasks, and returns the number of tasks it holds.
+	Compact(ctx context.Context) (int, error)
+}
+
+// Pager is implemented by storages that can list their tasks a page
+// at a time, so that the whole list need not be copied at once.
+type Pager interface {
+	// Page returns up to limit tasks, in ID order, with IDs after
+	// after; an empty after starts with the first task. The pages are
+	// not a snapshot: a task written between two calls is returned by
+	// the later one if its ID is after its after.
+	Page(ctx context.Context, after string, limit int) ([]Task, error)
+}
+
+// TaskChangeType tells whether a TaskChange created, updated or
+// deleted a task.
+type TaskChangeType string
+
+const (
+	TaskCreated TaskChangeType = "created"
+	TaskUpdated TaskChangeType = "updated"
+	TaskDeleted TaskChangeType = "deleted"
+)
+
+// TaskChange records a write to the storage. Task is the task as
+// written, and is not set for TaskDeleted changes.
+type TaskChange struct {
+	Generation uint64
+	Type       TaskChangeType
+	ID         string
+	Task       Task
+}
+
+// changeHistoryLimit is how many changes InMemoryStorage remembers.
+// Clients that fall further behind must take a new snapshot.
+const changeHistoryLimit = 1000
+
+// InMemoryStorage is a Storage backed by a map guarded by a mutex.
+type InMemoryStorage struct {
+	mu    sync.RWMutex
+	tasks map[string]Task
+	// ids holds the IDs of the tasks in order, for Page.
+	ids      []string
+	index    *searchIndex
+	prefixes *prefixTrie
+	// descriptions maps lowercased descriptions to the IDs of the
+	// tasks that have them.
+	descriptions map[string]map[string]struct{}
+	generation   uint64
+	// changes holds the last changeHistoryLimit changes. Those up to
+	// generation forgotten have been dropped.
+	changes   []TaskChange
+	forgotten uint64
+	// changed is closed, and replaced, by every write, waking the
+	// callers of WaitForChange.
+	changed chan struct{}
+}
+
+// NewInMemoryStorage creates an InMemoryStorage holding the given tasks.
+func NewInMemoryStorage(tasks ...Task) *InMemoryStorage {
+	storage := &InMemoryStorage{tasks: make(map[string]Task, len(tasks)), index: newSearchIndex(), prefixes: newPrefixTrie()}
+	storage.descriptions = make(map[string]map[string]struct{})
+	storage.changed = make(chan struct{})
+	for _, task := range tasks {
+		storage.tasks[task.ID] = task
+		storage.indexTask(task)
+	}
+	storage.sortIDs()
+	return storage
+}
+
+// sortIDs rebuilds the ordered list of task IDs. The caller must hold
+// the write lock.
+func (storage *InMemoryStorage) sortIDs() {
+	storage.ids = make([]string, 0, len(storage.tasks))
+	for id := range storage.tasks {
+		storage.ids = append(storage.ids, id)
+	}
+	sort.Strings(storage.ids)
+}
+
+// insertID adds the ID of a new task to the ordered list. The caller
+// must hold the write lock.
+func (storage *InMemoryStorage) insertID(id string) {
+	i := sort.SearchStrings(storage.ids, id)
+	storage.ids = append(storage.ids, "")
+	copy(storage.ids[i+1:], storage.ids[i:])
+	storage.ids[i] = id
+}
+
+// removeID removes the ID of a deleted task from the ordered list. The
+// caller must hold the write lock.
+func (storage *InMemoryStorage) removeID(id string) {
+	if i := sort.SearchStrings(storage.ids, id); i < len(storage.ids) && storage.ids[i] == id {
+		storage.ids = append(storage.ids[:i], storage.ids[i+1:]...)
+	}
+}
+
+// GetAll returns the tasks matched by filter. It checks ctx before
+// every task and returns the context error if ctx is done.
+func (storage *InMemoryStorage) GetAll(ctx context.Context, filter Filter) ([]Task, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	matching := make([]Task, 0, len(storage.tasks))
+	for _, task := range storage.tasks {
+		if err := ctx.Err(); err != nil {
+			return nil, &StorageError{Op: "list", Err: err}
+		}
+		if filter == nil || filter.Match(task) {
+			matching = append(matching, task)
+		}
+	}
+	return matching, nil
+}
+
+func (storage *InMemoryStorage) Page(ctx context.Context, after string, limit int) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &StorageError{Op: "list", Err: err}
+	}
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	i := sort.SearchStrings(storage.ids, after)
+	if i < len(storage.ids) && storage.ids[i] == after {
+		i++
+	}
+	ids := storage.ids[i:min(i+limit, len(storage.ids))]
+	page := make([]Task, len(ids))
+	for j, id := range ids {
+		page[j] = storage.tasks[id]
+	}
+	return page, nil
+}
+
+func (storage *InMemoryStorage) Get(_ context.Context, id string) (Task, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	task, wasFound := storage.tasks[id]
+	if !wasFound {
+		return Task{}, &StorageError{Op: "get", ID: id, Err: ErrNotFound}
+	}
+	return task, nil
+}
+
+func (storage *InMemoryStorage) Create(_ context.Context, task Task) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, wasFound := storage.tasks[task.ID]; wasFound {
+		return &StorageError{Op: "create", ID: task.ID, Err: ErrAlreadyExists}
+	}
+	storage.tasks[task.ID] = task
+	storage.insertID(task.ID)
+	storage.indexTask(task)
+	storage.record(TaskChange{Type: TaskCreated, ID: task.ID, Task: task})
+	return nil
+}
+
+// CreateUnique reports the smallest ID among the tasks with the
+// description, so that the answer does not change between calls.
+func (storage *InMemoryStorage) CreateUnique(_ context.Context, task Task) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, wasFound := storage.tasks[task.ID]; wasFound {
+		return &StorageError{Op: "create", ID: task.ID, Err: ErrAlreadyExists}
+	}
+	existingID := ""
+	for id := range storage.descriptions[strings.ToLower(task.Description)] {
+		if existingID == "" || id < existingID {
+			existingID = id
+		}
+	}
+	if existingID != "" {
+		return &StorageError{Op: "create", ID: task.ID, Err: &DuplicateDescriptionError{ExistingID: existingID}}
+	}
+	storage.tasks[task.ID] = task
+	storage.insertID(task.ID)
+	storage.indexTask(task)
+	storage.record(TaskChange{Type: TaskCreated, ID: task.ID, Task: task})
+	return nil
+}
+
+func (storage *InMemoryStorage) Update(_ context.Context, task Task) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	previous, wasFound := storage.tasks[task.ID]
+	if !wasFound {
+		return &StorageError{Op: "update", ID: task.ID, Err: ErrNotFound}
+	}
+	storage.unindexTask(previous)
+	storage.tasks[task.ID] = task
+	storage.indexTask(task)
+	storage.record(TaskChange{Type: TaskUpdated, ID: task.ID, Task: task})
+	return nil
+}
+
+func (storage *InMemoryStorage) Delete(_ context.Context, id string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	task, wasFound := storage.tasks[id]
+	if !wasFound {
+		return &StorageError{Op: "delete", ID: id, Err: ErrNotFound}
+	}
+	delete(storage.tasks, id)
+	storage.removeID(id)
+	storage.unindexTask(task)
+	storage.record(TaskChange{Type: TaskDeleted, ID: id})
+	return nil
+}
+
+// indexTask adds the task to the search, prefix and description
+// indexes. The caller must hold the write lock.
+func (storage *InMemoryStorage) indexTask(task Task) {
+	storage.index.add(task)
+	storage.prefixes.add(task)
+
+	key := strings.ToLower(task.Description)
+	if storage.descriptions[key] == nil {
+		storage.descriptions[key] = make(map[string]struct{})
+	}
+	storage.descriptions[key][task.ID] = struct{}{}
+}
+
+// unindexTask removes the task from the indexes. The caller must hold
+// the write lock.
+func (storage *InMemoryStorage) unindexTask(task Task) {
+	storage.index.remove(task.ID)
+	storage.prefixes.remove(task.ID)
+
+	key := strings.ToLower(task.Description)
+	delete(storage.descriptions[key], task.ID)
+	if len(storage.descriptions[key]) == 0 {
+		delete(storage.descriptions, key)
+	}
+}
+
+// record advances the generation, adds the change to the history and
+// wakes the callers of WaitForChange. The caller must hold the write
+// lock.
+func (storage *InMemoryStorage) record(change TaskChange) {
+	storage.generation++
+	change.Generation = storage.generation
+	storage.changes = append(storage.changes, change)
+	if len(storage.changes) > changeHistoryLimit {
+		storage.forgotten = storage.changes[0].Generation
+		storage.changes = storage.changes[1:]
+	}
+	close(storage.changed)
+	storage.changed = make(chan struct{})
+}
+
+func (storage *InMemoryStorage) Search(_ context.Context, query string, fuzziness int) ([]SearchResult, bool, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	scores := storage.index.scores(query)
+	fuzzy := len(scores) == 0 && fuzziness > 0
+	if fuzzy {
+		scores = storage.index.fuzzyScores(query, fuzziness)
+	}
+	results := make([]SearchResult, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, SearchResult{Task: storage.tasks[id], Score: score})
+	}
+	sortSearchResults(results)
+	return results, fuzzy, nil
+}
+
+func (storage *InMemoryStorage) Autocomplete(_ context.Context, prefix string, limit int) ([]string, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	descriptions := make([]string, 0, limit)
+	seen := make(map[string]bool)
+	storage.prefixes.complete(prefix, func(id string) bool {
+		description := storage.tasks[id].Description
+		if !seen[description] {
+			seen[description] = true
+			descriptions = append(descriptions, description)
+		}
+		return len(descriptions) < limit
+	})
+	return descriptions, nil
+}
+
+func (storage *InMemoryStorage) Snapshot(_ context.Context) (uint64, []Task, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	tasks := make([]Task, 0, len(storage.tasks))
+	for _, task := range storage.tasks {
+		tasks = append(tasks, task)
+	}
+	return storage.generation, tasks, nil
+}
+
+func (storage *InMemoryStorage) Changes(_ context.Context, since uint64) (uint64, []TaskChange, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+
+	if since < storage.forgotten {
+		return 0, nil, &StorageError{Op: "diff", Err: ErrGenerationExpired}
+	}
+	var changes []TaskChange
+	for _, change := range storage.changes {
+		if change.Generation > since {
+			changes = append(changes, change)
+		}
+	}
+	return storage.generation, changes, nil
+}
+
+// Compact copies the tasks, the indexes and the change history into
+// new maps and slices. Go maps do not shrink when tasks are deleted,
+// and the search index and prefix trie keep the nodes they grew. The
+// generation is unchanged, since no task is.
+func (storage *InMemoryStorage) Compact(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, &StorageError{Op: "compact", Err: err}
+	}
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	tasks := storage.tasks
+	storage.tasks = make(map[string]Task, len(tasks))
+	storage.index = newSearchIndex()
+	storage.prefixes = newPrefixTrie()
+	storage.descriptions = make(map[string]map[string]struct{})
+	for _, task := range tasks {
+		storage.tasks[task.ID] = task
+		storage.indexTask(task)
+	}
+	storage.sortIDs()
+	storage.changes = append([]TaskChange(nil), storage.changes...)
+	return len(storage.tasks), nil
+}
+
+func (storage *InMemoryStorage) WaitForChange(ctx context.Context, since uint64) (uint64, error) {
+	for {
+		storage.mu.RLock()
+		generation, changed := storage.generation, storage.changed
+		storage.mu.RUnlock()
+		if generation > since {
+			return generation, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return 0, &StorageError{Op: "wait for", Err: ctx.Err()}
+		}
+	}
+}
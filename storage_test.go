@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// filterFunc adapts a function to the Filter interface.
+type filterFunc func(task Task) bool
+
+func (filter filterFunc) Match(task Task) bool {
+	return filter(task)
+}
+
+func TestGetAllStopsWhenCancelledMidway(t *testing.T) {
+	tasks := make([]Task, 100)
+	for i := range tasks {
+		tasks[i] = Task{ID: strconv.Itoa(i), Description: "task " + strconv.Itoa(i)}
+	}
+	store := NewInMemoryStorage(tasks...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	matched := 0
+	_, err := store.GetAll(ctx, filterFunc(func(Task) bool {
+		matched++
+		if matched == 10 {
+			cancel()
+		}
+		return true
+	}))
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetAll() error = %v, want context.Canceled", err)
+	}
+	var storageErr *StorageError
+	if !errors.As(err, &storageErr) {
+		t.Errorf("GetAll() error is %T, want *StorageError", err)
+	}
+	if matched != 10 {
+		t.Errorf("filter ran on %d tasks after cancellation, want it to stop at 10", matched)
+	}
+}
+
+func TestGetTasksCancelledRequest(t *testing.T) {
+	s, _ := newTestServer(t, Task{ID: "1", Description: "one"}, Task{ID: "2", Description: "two"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := httptest.NewRequest(http.MethodGet, "/tasks", nil).WithContext(ctx)
+	response := httptest.NewRecorder()
+	s.router.ServeHTTP(response, request)
+
+	assertStatus(t, response, http.StatusServiceUnavailable)
+}
+
+func TestInMemoryStoragePage(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStorage(Task{ID: "b"}, Task{ID: "d"}, Task{ID: "a"})
+	if err := store.Create(ctx, Task{ID: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateUnique(ctx, Task{ID: "e", Description: "e"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "d"); err != nil {
+		t.Fatal(err)
+	}
+
+	pageIDs := func(after string, limit int) string {
+		t.Helper()
+		page, err := store.Page(ctx, after, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(taskIDs(page), ",")
+	}
+	tests := []struct {
+		after string
+		limit int
+		want  string
+	}{
+		{after: "", limit: 2, want: "a,b"},
+		{after: "b", limit: 2, want: "c,e"},
+		// A deleted or unknown ID still marks a place in the order.
+		{after: "d", limit: 2, want: "e"},
+		{after: "bb", limit: 10, want: "c,e"},
+		{after: "e", limit: 2, want: ""},
+	}
+	for _, tt := range tests {
+		if got := pageIDs(tt.after, tt.limit); got != tt.want {
+			t.Errorf("Page(%q, %d) = %s, want %s", tt.after, tt.limit, got, tt.want)
+		}
+	}
+
+	if _, err := store.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := pageIDs("", 10); got != "a,b,c,e" {
+		t.Errorf("Page() after Compact = %s, want a,b,c,e", got)
+	}
+}
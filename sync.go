@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TaskSnapshot is every task at a generation of the storage.
+type TaskSnapshot struct {
+	Generation uint64 `json:"generation"`
+	Tasks      []Task `json:"tasks"`
+}
+
+// TaskDiff lists what changed after a generation of the storage, up to
+// Generation. A task that was deleted and then created again under the
+// same ID is listed as updated; one created and deleted again is left
+// out.
+type TaskDiff struct {
+	Generation uint64   `json:"generation"`
+	Created    []Task   `json:"created"`
+	Updated    []Task   `json:"updated"`
+	Deleted    []string `json:"deleted"`
+}
+
+// SyncItem is a task changed since the cursor passed to GET
+// /tasks/sync. Deleted tasks have no Task.
+type SyncItem struct {
+	ID      string `json:"id"`
+	Deleted bool   `json:"deleted,omitempty"`
+	Task    *Task  `json:"task,omitempty"`
+}
+
+const (
+	// syncDefaultLimit and syncMaxLimit bound the number of items a
+	// GET /tasks/sync page holds.
+	syncDefaultLimit = 100
+	syncMaxLimit     = 1000
+	// syncCursorHeader carries the cursor of the next page.
+	syncCursorHeader = "X-Next-Cursor"
+	// pollDefaultTimeout and pollMaxTimeout bound how long GET
+	// /tasks/poll waits for a change.
+	pollDefaultTimeout = 30 * time.Second
+	pollMaxTimeout     = 60 * time.Second
+)
+
+// getTasksSnapshot handles the HTTP request to retrieve every task,
+// ordered by ID, together with the generation of the storage they were
+// read at. Clients pass the generation to getTasksDiff to fetch later
+// changes. In case of a storage error, it responds with the status
+// chosen by storageErrorStatus along with the error message.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client.
+func (s *Server) getTasksSnapshot(writer http.ResponseWriter, request *http.Request) {
+	generation, tasks, err := s.store.Snapshot(request.Context())
+	if err != nil {
+		s.logger.Error("Не удалось получить снимок задач", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	for i := range tasks {
+		s.presentTask(&tasks[i])
+	}
+
+	s.writeJSON(writer, http.StatusOK, TaskSnapshot{Generation: generation, Tasks: tasks})
+	s.logger.Info("Отправлен снимок задач", "generation", generation, "count", len(tasks))
+}
+
+// getTasksDiff handles the HTTP request to retrieve the tasks created,
+// updated and deleted after the generation given by the since query
+// parameter. A missing or malformed since, or one later than the
+// current generation, is rejected with a HTTP 400 Bad Request. If the
+// changes since that generation are no longer known, it responds with
+// a HTTP 410 Gone and the client has to take a new snapshot.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the since query parameter.
+func (s *Server) getTasksDiff(writer http.ResponseWriter, request *http.Request) {
+	since, err := strconv.ParseUint(request.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		s.logger.Warn("Некорректное поколение задач", "since", request.URL.Query().Get("since"), "error", err)
+		http.Error(writer, "since must be a generation number", http.StatusBadRequest)
+		return
+	}
+
+	generation, changes, err := s.store.Changes(request.Context(), since)
+	if err != nil {
+		s.logger.Warn("Не удалось получить изменения задач", "since", since, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	if since > generation {
+		s.logger.Warn("Запрошено будущее поколение задач", "since", since, "generation", generation)
+		http.Error(writer, "since is later than the current generation", http.StatusBadRequest)
+		return
+	}
+
+	diff := diffChanges(changes)
+	diff.Generation = generation
+	for i := range diff.Created {
+		s.presentTask(&diff.Created[i])
+	}
+	for i := range diff.Updated {
+		s.presentTask(&diff.Updated[i])
+	}
+	s.writeJSON(writer, http.StatusOK, diff)
+	s.logger.Info("Отправлены изменения задач", "since", since, "generation", generation)
+}
+
+// getTasksPoll handles the HTTP request to wait for the tasks to
+// change after the generation given by the since query parameter, for
+// at most timeout seconds (default 30, at most 60). Without since, it
+// waits for the next change. When there are changes, at once or while
+// waiting, it responds with a TaskDiff as getTasksDiff does; when the
+// timeout expires first, with a HTTP 204 No Content. A malformed since
+// or timeout, or a since later than the current generation, is
+// rejected with a HTTP 400 Bad Request, and a since whose changes are
+// no longer known with a HTTP 410 Gone.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the since and timeout query parameters.
+func (s *Server) getTasksPoll(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	timeout := pollDefaultTimeout
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > pollMaxTimeout {
+			s.logger.Warn("Некорректное время ожидания", "timeout", value)
+			http.Error(writer, fmt.Sprintf("timeout must be a number of seconds from 1 to %d", int(pollMaxTimeout.Seconds())), http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	generation, _, err := s.store.Snapshot(request.Context())
+	if err != nil {
+		s.logger.Error("Не удалось получить поколение задач", "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	since := generation
+	if value := query.Get("since"); value != "" {
+		if since, err = strconv.ParseUint(value, 10, 64); err != nil {
+			s.logger.Warn("Некорректное поколение задач", "since", value, "error", err)
+			http.Error(writer, "since must be a generation number", http.StatusBadRequest)
+			return
+		}
+		if since > generation {
+			s.logger.Warn("Запрошено будущее поколение задач", "since", since, "generation", generation)
+			http.Error(writer, "since is later than the current generation", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), timeout)
+	defer cancel()
+	if _, err = s.store.WaitForChange(ctx, since); err != nil {
+		if request.Context().Err() != nil {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.logger.Error("Не удалось дождаться изменения задач", "since", since, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	generation, changes, err := s.store.Changes(request.Context(), since)
+	if err != nil {
+		s.logger.Warn("Не удалось получить изменения задач", "since", since, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	diff := diffChanges(changes)
+	diff.Generation = generation
+	for i := range diff.Created {
+		s.presentTask(&diff.Created[i])
+	}
+	for i := range diff.Updated {
+		s.presentTask(&diff.Updated[i])
+	}
+	s.writeJSON(writer, http.StatusOK, diff)
+	s.logger.Info("Отправлены изменения задач", "since", since, "generation", generation)
+}
+
+// diffChanges reduces a history of changes, oldest first, to the net
+// difference between the states before and after them.
+func diffChanges(changes []TaskChange) TaskDiff {
+	type net struct {
+		existedBefore bool
+		last          TaskChange
+	}
+	byID := make(map[string]*net)
+	var ids []string
+	for _, change := range changes {
+		entry, ok := byID[change.ID]
+		if !ok {
+			entry = &net{existedBefore: change.Type != TaskCreated}
+			byID[change.ID] = entry
+			ids = append(ids, change.ID)
+		}
+		entry.last = change
+	}
+	sort.Strings(ids)
+
+	diff := TaskDiff{Created: []Task{}, Updated: []Task{}, Deleted: []string{}}
+	for _, id := range ids {
+		entry := byID[id]
+		existsAfter := entry.last.Type != TaskDeleted
+		task := entry.last.Task
+
+		switch {
+		case !entry.existedBefore && existsAfter:
+			diff.Created = append(diff.Created, task)
+		case entry.existedBefore && existsAfter:
+			diff.Updated = append(diff.Updated, task)
+		case entry.existedBefore && !existsAfter:
+			diff.Deleted = append(diff.Deleted, id)
+		}
+	}
+	return diff
+}
+
+// getTasksSync handles the HTTP request to fetch the tasks changed
+// since the opaque cursor query parameter, at most limit (default 100,
+// at most 1000) at a time. Without a cursor, every task is sent, in ID
+// order. The response is a JSON array of SyncItem, and the cursor to
+// pass next time is sent in the X-Next-Cursor header; a page shorter
+// than limit means the client is up to date. A malformed or tampered
+// cursor, or a malformed limit, is rejected with a HTTP 400 Bad
+// Request. If the changes since the cursor are no longer known, it
+// responds with a HTTP 410 Gone and the client has to sync again
+// without a cursor.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the cursor and limit query parameters.
+func (s *Server) getTasksSync(writer http.ResponseWriter, request *http.Request) {
+	limit := syncDefaultLimit
+	if value := request.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > syncMaxLimit {
+			s.logger.Warn("Некорректный лимит синхронизации", "limit", value)
+			http.Error(writer, fmt.Sprintf("limit must be a number from 1 to %d", syncMaxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	cursor := SyncCursor{Snapshot: true}
+	if token := request.URL.Query().Get("cursor"); token != "" {
+		var err error
+		if cursor, err = s.cursors.Decode(token); err != nil {
+			s.logger.Warn("Некорректный курсор синхронизации", "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var items []SyncItem
+	var next SyncCursor
+	var err error
+	if cursor.Snapshot {
+		items, next, err = s.syncSnapshot(request, cursor, limit)
+	} else {
+		items, next, err = s.syncChanges(request, cursor, limit)
+	}
+	if err != nil {
+		s.logger.Warn("Не удалось синхронизировать задачи", "generation", cursor.Generation, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	writer.Header().Set(syncCursorHeader, s.cursors.Encode(next))
+	s.writeJSON(writer, http.StatusOK, items)
+	s.logger.Info("Отправлена страница синхронизации", "count", len(items), "generation", next.Generation)
+}
+
+// syncSnapshot returns the page of every task after cursor.AfterID.
+// The generation of the first page is kept until the last one, so
+// that the changes made while paging are sent afterwards.
+func (s *Server) syncSnapshot(request *http.Request, cursor SyncCursor, limit int) ([]SyncItem, SyncCursor, error) {
+	generation, tasks, err := s.store.Snapshot(request.Context())
+	if err != nil {
+		return nil, SyncCursor{}, err
+	}
+	if cursor.AfterID == "" {
+		cursor.Generation = generation
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	items := []SyncItem{}
+	for i := range tasks {
+		if cursor.AfterID != "" && tasks[i].ID <= cursor.AfterID {
+			continue
+		}
+		if len(items) == limit {
+			return items, SyncCursor{Generation: cursor.Generation, AfterID: items[len(items)-1].ID, Snapshot: true}, nil
+		}
+		s.presentTask(&tasks[i])
+		items = append(items, SyncItem{ID: tasks[i].ID, Task: &tasks[i]})
+	}
+	return items, SyncCursor{Generation: cursor.Generation}, nil
+}
+
+// syncChanges returns the page of the tasks changed after
+// cursor.Generation, each once, in the order of their last change. A
+// cursor later than the current generation was made before the
+// storage was reset, so its changes are reported as expired.
+func (s *Server) syncChanges(request *http.Request, cursor SyncCursor, limit int) ([]SyncItem, SyncCursor, error) {
+	generation, changes, err := s.store.Changes(request.Context(), cursor.Generation)
+	if err != nil {
+		return nil, SyncCursor{}, err
+	}
+	if cursor.Generation > generation {
+		return nil, SyncCursor{}, &StorageError{Op: "sync", Err: ErrGenerationExpired}
+	}
+
+	// Keep the last change of every task, oldest first.
+	last := make(map[string]int, len(changes))
+	for i, change := range changes {
+		last[change.ID] = i
+	}
+	items := []SyncItem{}
+	var sent uint64
+	for i, change := range changes {
+		if last[change.ID] != i {
+			continue
+		}
+		if len(items) == limit {
+			return items, SyncCursor{Generation: sent}, nil
+		}
+		sent = change.Generation
+		item := SyncItem{ID: change.ID, Deleted: change.Type == TaskDeleted}
+		if !item.Deleted {
+			task := change.Task
+			s.presentTask(&task)
+			item.Task = &task
+		}
+		items = append(items, item)
+	}
+	return items, SyncCursor{Generation: generation}, nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// syncTestTasks returns n tasks with the IDs task-0 to task-<n-1>.
+func syncTestTasks(n int) []Task {
+	tasks := make([]Task, n)
+	for i := range tasks {
+		id := "task-" + strconv.Itoa(i)
+		tasks[i] = Task{ID: id, Description: "Задача " + id, Applications: []string{}, CreatedAt: testNow}
+	}
+	return tasks
+}
+
+// syncPage fetches a page of GET /tasks/sync and returns its items and
+// next cursor.
+func syncPage(t *testing.T, s *Server, cursor string, limit int) ([]SyncItem, string) {
+	t.Helper()
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	response := serve(s, http.MethodGet, "/tasks/sync?"+query.Encode(), "")
+	assertStatus(t, response, http.StatusOK)
+	var items []SyncItem
+	decodeJSON(t, response.Body.Bytes(), &items)
+	return items, response.Header().Get(syncCursorHeader)
+}
+
+// syncItemIDs returns the IDs of items, with deleted ones marked by a
+// leading minus.
+func syncItemIDs(items []SyncItem) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+		if item.Deleted {
+			ids[i] = "-" + item.ID
+		}
+	}
+	return ids
+}
+
+func TestGetTasksSync(t *testing.T) {
+	store := NewInMemoryStorage(syncTestTasks(5)...)
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), store)
+	ctx := context.Background()
+
+	// The snapshot in pages of two, with a write in between.
+	items, cursor := syncPage(t, s, "", 2)
+	if got, want := syncItemIDs(items), []string{"task-0", "task-1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("page 1 = %q, want %q", got, want)
+	}
+	if items[0].Task == nil || items[0].Task.Description != "Задача task-0" {
+		t.Errorf("item = %+v, want the task", items[0])
+	}
+	if err := store.Create(ctx, Task{ID: "task-9", Description: "Новая", CreatedAt: testNow}); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for snapshot := true; snapshot; {
+		items, cursor = syncPage(t, s, cursor, 2)
+		ids = append(ids, syncItemIDs(items)...)
+		decoded, err := s.cursors.Decode(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot = decoded.Snapshot
+	}
+	// The snapshot was taken before task-9 was created, but the pages
+	// are read as they come, so task-9 is in the snapshot and in the
+	// changes after it.
+	if want := []string{"task-2", "task-3", "task-4", "task-9"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("rest of the snapshot = %q, want %q", ids, want)
+	}
+	items, cursor = syncPage(t, s, cursor, 2)
+	if got, want := syncItemIDs(items), []string{"task-9"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes during the snapshot = %q, want %q", got, want)
+	}
+
+	// Later changes, each task once with its last change.
+	if err := store.Delete(ctx, "task-1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, description := range []string{"Первая правка", "Вторая правка"} {
+		if err := store.Update(ctx, Task{ID: "task-2", Description: description, CreatedAt: testNow}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, cursor = syncPage(t, s, cursor, 1)
+	if got, want := syncItemIDs(items), []string{"-task-1"}; !reflect.DeepEqual(got, want) || items[0].Task != nil {
+		t.Errorf("page of changes = %+v, want %q", items, want)
+	}
+	items, cursor = syncPage(t, s, cursor, 1)
+	if got, want := syncItemIDs(items), []string{"task-2"}; !reflect.DeepEqual(got, want) || items[0].Task.Description != "Вторая правка" {
+		t.Errorf("page of changes = %+v, want %q as last changed", items, want)
+	}
+
+	// Up to date.
+	items, next := syncPage(t, s, cursor, 1)
+	if len(items) != 0 || next == "" {
+		t.Errorf("page when up to date = %+v, next cursor %q", items, next)
+	}
+}
+
+func TestGetTasksSyncRejected(t *testing.T) {
+	s, _ := newTestServer(t, syncTestTasks(3)...)
+	valid := s.cursors.Encode(SyncCursor{Generation: 0})
+	flipped := []byte(valid)
+	flipped[2] ^= 1
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "flipped byte", query: "cursor=" + url.QueryEscape(string(flipped)), wantStatus: http.StatusBadRequest},
+		{name: "foreign key", query: "cursor=" + NewCursorSigner("other").Encode(SyncCursor{Generation: 0}), wantStatus: http.StatusBadRequest},
+		{name: "garbage", query: "cursor=abc", wantStatus: http.StatusBadRequest},
+		// A generation the storage has not reached, as after a restart.
+		{name: "generation from before a reset", query: "cursor=" + s.cursors.Encode(SyncCursor{Generation: 5}), wantStatus: http.StatusGone},
+		{name: "zero limit", query: "limit=0", wantStatus: http.StatusBadRequest},
+		{name: "limit too large", query: "limit=1001", wantStatus: http.StatusBadRequest},
+		{name: "valid", query: "cursor=" + valid, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, serve(s, http.MethodGet, "/tasks/sync?"+tt.query, ""), tt.wantStatus)
+		})
+	}
+}
+
+func TestGetTasksSyncExpiredGeneration(t *testing.T) {
+	store := NewInMemoryStorage()
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), store)
+	_, cursor := syncPage(t, s, "", 10)
+
+	// More writes than the change history keeps.
+	for i := 0; i <= changeHistoryLimit; i++ {
+		if err := store.Create(context.Background(), Task{ID: strconv.Itoa(i), Description: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertStatus(t, serve(s, http.MethodGet, "/tasks/sync?cursor="+cursor, ""), http.StatusGone)
+}
+
+func TestGetTasksPoll(t *testing.T) {
+	store := NewInMemoryStorage(syncTestTasks(2)...)
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), store)
+	generation, _, err := store.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve(s, http.MethodGet, "/tasks/poll?timeout=10&since="+strconv.FormatUint(generation, 10), "")
+	}()
+	if err := store.Create(context.Background(), Task{ID: "task-9", Description: "Новая", CreatedAt: testNow}); err != nil {
+		t.Fatal(err)
+	}
+
+	var response *httptest.ResponseRecorder
+	select {
+	case response = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll did not return after a write")
+	}
+	assertStatus(t, response, http.StatusOK)
+	var diff TaskDiff
+	decodeJSON(t, response.Body.Bytes(), &diff)
+	if diff.Generation != generation+1 || len(diff.Created) != 1 || diff.Created[0].ID != "task-9" {
+		t.Errorf("diff = %+v, want task-9 created at generation %d", diff, generation+1)
+	}
+}
+
+func TestGetTasksPollTimeout(t *testing.T) {
+	s, _ := newTestServer(t, syncTestTasks(2)...)
+
+	response := serve(s, http.MethodGet, "/tasks/poll?timeout=1", "")
+	assertStatus(t, response, http.StatusNoContent)
+	if response.Body.Len() != 0 {
+		t.Errorf("body = %q, want none", response.Body.String())
+	}
+}
+
+func TestGetTasksPollCancelled(t *testing.T) {
+	s, _ := newTestServer(t, syncTestTasks(2)...)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		serveRequest(s, newRequest(http.MethodGet, "/tasks/poll?timeout=60", "").WithContext(ctx))
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll did not return after the client went away")
+	}
+}
+
+func TestGetTasksPollRejected(t *testing.T) {
+	s, _ := newTestServer(t, syncTestTasks(2)...)
+
+	for _, query := range []string{"timeout=0", "timeout=61", "timeout=soon", "since=-1", "since=100"} {
+		t.Run(query, func(t *testing.T) {
+			assertStatus(t, serve(s, http.MethodGet, "/tasks/poll?"+query, ""), http.StatusBadRequest)
+		})
+	}
+}
+
+// snapshotGeneration returns the generation reported by GET
+// /tasks/snapshot.
+func snapshotGeneration(t *testing.T, s *Server) uint64 {
+	t.Helper()
+	response := serve(s, http.MethodGet, "/tasks/snapshot", "")
+	assertStatus(t, response, http.StatusOK)
+	var snapshot TaskSnapshot
+	decodeJSON(t, response.Body.Bytes(), &snapshot)
+	return snapshot.Generation
+}
+
+func TestGetTasksSnapshotAndDiff(t *testing.T) {
+	store := NewInMemoryStorage(syncTestTasks(3)...)
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), store)
+
+	response := serve(s, http.MethodGet, "/tasks/snapshot", "")
+	assertStatus(t, response, http.StatusOK)
+	var snapshot TaskSnapshot
+	decodeJSON(t, response.Body.Bytes(), &snapshot)
+	if got, want := taskIDs(snapshot.Tasks), []string{"task-0", "task-1", "task-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot = %q, want %q", got, want)
+	}
+	since := snapshot.Generation
+
+	assertStatus(t, serve(s, http.MethodPost, "/tasks", `{"id":"task-9","description":"Новая","note":"","applications":[]}`), http.StatusCreated)
+	if got := snapshotGeneration(t, s); got != since+1 {
+		t.Errorf("generation after a create = %d, want %d", got, since+1)
+	}
+	if err := store.Update(context.Background(), Task{ID: "task-0", Description: "Исправленная", CreatedAt: testNow}); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, serve(s, http.MethodDelete, "/tasks/task-1", ""), http.StatusOK)
+	if got := snapshotGeneration(t, s); got != since+3 {
+		t.Errorf("generation after a delete = %d, want %d", got, since+3)
+	}
+
+	response = serve(s, http.MethodGet, "/tasks/diff?since="+strconv.FormatUint(since, 10), "")
+	assertStatus(t, response, http.StatusOK)
+	var diff TaskDiff
+	decodeJSON(t, response.Body.Bytes(), &diff)
+	if diff.Generation != since+3 {
+		t.Errorf("diff generation = %d, want %d", diff.Generation, since+3)
+	}
+	if got, want := taskIDs(diff.Created), []string{"task-9"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("created = %q, want %q", got, want)
+	}
+	if got, want := taskIDs(diff.Updated), []string{"task-0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("updated = %q, want %q", got, want)
+	} else if diff.Updated[0].Description != "Исправленная" {
+		t.Errorf("updated task = %+v, want the new description", diff.Updated[0])
+	}
+	if want := []string{"task-1"}; !reflect.DeepEqual(diff.Deleted, want) {
+		t.Errorf("deleted = %q, want %q", diff.Deleted, want)
+	}
+}
+
+func TestGetTasksDiffRejected(t *testing.T) {
+	store := NewInMemoryStorage()
+	s, _ := newTestServerWithConfig(t, DefaultConfig(), store)
+	for i := 0; i <= changeHistoryLimit; i++ {
+		if err := store.Create(context.Background(), Task{ID: strconv.Itoa(i), Description: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "older than the history", query: "since=0", wantStatus: http.StatusGone},
+		{name: "oldest in the history", query: "since=1", wantStatus: http.StatusOK},
+		{name: "later than the generation", query: "since=5000", wantStatus: http.StatusBadRequest},
+		{name: "missing", query: "", wantStatus: http.StatusBadRequest},
+		{name: "negative", query: "since=-1", wantStatus: http.StatusBadRequest},
+		{name: "not a number", query: "since=yesterday", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, serve(s, http.MethodGet, "/tasks/diff?"+tt.query, ""), tt.wantStatus)
+		})
+	}
+}
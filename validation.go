@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// requestSchemas holds the JSON Schema every request body is checked
+// against, keyed by method and route pattern. Routes without an entry
+// are not validated.
+var requestSchemas = map[string]string{
+	"POST /tasks": `{
+		"type": "object",
+		"required": ["id", "description"],
+		"properties": {
+			"id": {"type": "string", "minLength": 1},
+			"description": {"type": "string", "minLength": 1},
+			"note": {"type": "string"},
+			"applications": {"type": ["array", "null"], "items": {"type": "string"}},
+			"ttl": {"type": ["integer", "null"], "exclusiveMinimum": 0},
+			"sla_deadline_minutes": {"type": "integer", "minimum": 0},
+			"project_id": {"type": "string"},
+			"suggested_tags": {"type": ["array", "null"], "items": {"type": "string"}},
+			"reminders": {
+				"type": ["array", "null"],
+				"items": {
+					"type": "object",
+					"required": ["lead_time"],
+					"additionalProperties": false,
+					"properties": {
+						"id": {"type": "string"},
+						"lead_time": {"type": "integer", "minimum": 0}
+					}
+				}
+			}
+		}
+	}`,
+	"POST /tasks/quick-capture": `{
+		"type": "object",
+		"required": ["text"],
+		"properties": {
+			"text": {"type": "string", "minLength": 1}
+		}
+	}`,
+	"POST /projects": `{
+		"type": "object",
+		"required": ["id", "name"],
+		"properties": {
+			"id": {"type": "string", "minLength": 1},
+			"name": {"type": "string", "minLength": 1},
+			"description": {"type": "string"},
+			"color": {"type": "string", "pattern": "^#[0-9a-fA-F]{6}$"},
+			"owner_id": {"type": "string"}
+		}
+	}`,
+	"PUT /projects/{id}": `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"id": {"type": "string"},
+			"name": {"type": "string", "minLength": 1},
+			"description": {"type": "string"},
+			"color": {"type": "string", "pattern": "^#[0-9a-fA-F]{6}$"},
+			"owner_id": {"type": "string"}
+		}
+	}`,
+	"POST /tasks/merge": `{
+		"type": "object",
+		"required": ["keep_id", "discard_id"],
+		"properties": {
+			"keep_id": {"type": "string", "minLength": 1},
+			"discard_id": {"type": "string", "minLength": 1}
+		}
+	}`,
+	"POST /tasks/{id}/merge": `{
+		"type": "object",
+		"required": ["base", "theirs", "mine"],
+		"properties": {
+			"base": {"type": "object"},
+			"theirs": {"type": "object"},
+			"mine": {"type": "object"}
+		}
+	}`,
+	"POST /tasks/{id}/move": `{
+		"type": "object",
+		"required": ["project_id"],
+		"additionalProperties": false,
+		"properties": {
+			"project_id": {"type": "string"}
+		}
+	}`,
+	"POST /tasks/{id}/split": `{
+		"type": "object",
+		"required": ["descriptions"],
+		"additionalProperties": false,
+		"properties": {
+			"descriptions": {"type": "array", "minItems": 2, "items": {"type": "string", "minLength": 1}}
+		}
+	}`,
+	"POST /tasks/{id}/pin": `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"after_id": {"type": "string", "minLength": 1}
+		}
+	}`,
+	"POST /users/{id}/saved-searches":           savedSearchSchema,
+	"PUT /users/{id}/saved-searches/{searchID}": savedSearchSchema,
+	"POST /admin/blocklist": `{
+		"type": "object",
+		"required": ["ip"],
+		"properties": {
+			"ip": {"type": "string", "minLength": 1}
+		}
+	}`,
+	"POST /admin/webhooks/test": `{
+		"type": "object",
+		"required": ["event_type", "task_id", "url"],
+		"additionalProperties": false,
+		"properties": {
+			"event_type": {"enum": ["task.created", "task.updated", "task.deleted"]},
+			"task_id": {"type": "string", "minLength": 1},
+			"url": {"type": "string", "minLength": 1}
+		}
+	}`,
+	"POST /admin/jobs": `{
+		"type": "object",
+		"required": ["type"],
+		"properties": {
+			"type": {"type": "string", "minLength": 1},
+			"payload": {}
+		}
+	}`,
+}
+
+// savedSearchSchema describes the body of the saved search endpoints.
+const savedSearchSchema = `{
+	"type": "object",
+	"required": ["name", "query"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"query": {"type": "string", "minLength": 1}
+	}
+}`
+
+// taskSchema describes a serialized task. No field is required,
+// because the fields query parameter may leave any of them out.
+const taskSchema = `{
+	"type": "object",
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string"},
+		"description": {"type": "string"},
+		"note": {"type": "string"},
+		"applications": {"type": ["array", "null"], "items": {"type": "string"}},
+		"created_at": {"type": "string", "format": "date-time"},
+		"ttl": {"type": "integer"},
+		"word_count": {"type": "integer", "minimum": 0},
+		"reading_time_seconds": {"type": "integer", "minimum": 0},
+		"sla_deadline_minutes": {"type": "integer", "minimum": 0},
+		"sla_status": {"enum": ["on_track", "at_risk", "breached"]},
+		"pin_order": {"type": "integer", "minimum": 0},
+		"computed_priority": {"type": "number", "minimum": 0},
+		"suggested_tags": {"type": "array", "items": {"type": "string"}},
+		"project_id": {"type": "string"},
+		"split_from": {"type": "string"},
+		"reminders": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"additionalProperties": false,
+				"properties": {
+					"id": {"type": "string"},
+					"lead_time": {"type": "integer"},
+					"notify_at": {"type": "string", "format": "date-time"},
+					"sent": {"type": "boolean"}
+				}
+			}
+		}
+	}
+}`
+
+// responseSchemas holds the JSON Schema successful responses are
+// checked against in debug mode, keyed like requestSchemas.
+var responseSchemas = map[string]string{
+	"GET /tasks": `{
+		"oneOf": [
+			{"type": "object", "additionalProperties": ` + taskSchema + `},
+			{"type": "array", "items": ` + taskSchema + `}
+		]
+	}`,
+	"GET /tasks/{id}": taskSchema,
+	"GET /version": `{
+		"type": "object",
+		"required": ["version", "commit", "built_at", "go_version"],
+		"properties": {
+			"version": {"type": "string", "minLength": 1},
+			"commit": {"type": "string"},
+			"built_at": {"type": "string"},
+			"go_version": {"type": "string", "minLength": 1}
+		}
+	}`,
+	"GET /queue/stats": `{
+		"type": "object",
+		"required": ["depth", "capacity", "consumers"],
+		"properties": {
+			"depth": {"type": "integer", "minimum": 0},
+			"capacity": {"type": "integer", "minimum": 0},
+			"consumers": {"type": "integer", "minimum": 0}
+		}
+	}`,
+}
+
+// SchemaRegistry holds compiled JSON Schemas keyed by method and route
+// pattern, e.g. "POST /tasks".
+type SchemaRegistry struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+// mustCompileSchemas compiles every schema in sources. The schemas are
+// part of the program, so a schema that does not compile is a
+// programming error and panics.
+func mustCompileSchemas(sources map[string]string) *SchemaRegistry {
+	registry := &SchemaRegistry{schemas: make(map[string]*jsonschema.Schema, len(sources))}
+	for route, source := range sources {
+		registry.schemas[route] = jsonschema.MustCompileString(route, source)
+	}
+	return registry
+}
+
+// lookup returns the schema registered for the method and route
+// pattern, or nil if there is none.
+func (registry *SchemaRegistry) lookup(method, pattern string) *jsonschema.Schema {
+	return registry.schemas[method+" "+pattern]
+}
+
+// FieldError describes one field of a request body that does not
+// satisfy its schema. Field is a JSON pointer into the body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fieldErrors flattens a validation error into the leaf errors that
+// name an actual problem with the instance.
+func fieldErrors(validationErr *jsonschema.ValidationError) []FieldError {
+	if len(validationErr.Causes) == 0 {
+		return []FieldError{{Field: validationErr.InstanceLocation, Message: validationErr.Message}}
+	}
+
+	var result []FieldError
+	for _, cause := range validationErr.Causes {
+		result = append(result, fieldErrors(cause)...)
+	}
+	return result
+}
+
+// validateRequest checks the request body against the schema
+// registered for the matched route before calling the next handler.
+// It must be mounted as an inline middleware, after chi has matched
+// the route. Bodies that are not JSON are rejected with 400 Bad
+// Request, bodies that violate the schema with 422 Unprocessable
+// Entity and a list of field errors.
+func (s *Server) validateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		schema := s.schemas.lookup(request.Method, chi.RouteContext(request.Context()).RoutePattern())
+		if schema == nil {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			s.logger.Warn("Не удалось прочитать тело запроса", "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var instance interface{}
+		if err = decoder.Decode(&instance); err != nil {
+			s.logger.Warn("Тело запроса не является JSON", "error", err)
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var validationErr *jsonschema.ValidationError
+		if err = schema.Validate(instance); errors.As(err, &validationErr) {
+			s.logger.Warn("Тело запроса не соответствует схеме", "path", request.URL.Path, "error", err)
+			response, _ := json.Marshal(map[string][]FieldError{"errors": fieldErrors(validationErr)})
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusUnprocessableEntity)
+			writer.Write(response)
+			return
+		} else if err != nil {
+			s.logger.Error("Не удалось проверить тело запроса", "error", err)
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// recordingWriter passes a response through to the client while
+// keeping a copy of its status code and body.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (writer *recordingWriter) WriteHeader(status int) {
+	writer.status = status
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *recordingWriter) Write(data []byte) (int, error) {
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	writer.body.Write(data)
+	return writer.ResponseWriter.Write(data)
+}
+
+// Flush sends the response so far to the client, if the underlying
+// writer supports it.
+func (writer *recordingWriter) Flush() {
+	flush(writer.ResponseWriter)
+}
+
+// validateResponse checks successful responses against the schema
+// registered for the matched route and logs a warning for every
+// response that does not satisfy it. The response itself is sent
+// unchanged. It must be mounted as an inline middleware, and only in
+// debug mode, since it keeps a copy of every response body.
+func (s *Server) validateResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		schema := s.responseSchemas.lookup(request.Method, chi.RouteContext(request.Context()).RoutePattern())
+		if schema == nil {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: writer}
+		next.ServeHTTP(recorder, request)
+
+		if recorder.status < 200 || recorder.status >= 300 || recorder.body.Len() == 0 {
+			return
+		}
+
+		decoder := json.NewDecoder(&recorder.body)
+		decoder.UseNumber()
+		var instance interface{}
+		if err := decoder.Decode(&instance); err != nil {
+			s.logger.Warn("Ответ не является JSON", "path", request.URL.Path, "error", err)
+			return
+		}
+
+		if err := schema.Validate(instance); err != nil {
+			s.logger.Warn("Ответ не соответствует схеме", "path", request.URL.Path, "error", err)
+		}
+	})
+}
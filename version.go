@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// version, commit and builtAt describe the build. Release builds set
+// them with
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.builtAt=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Left empty, they are taken from the module and VCS information the
+// go command records in the binary, where available.
+var (
+	version string
+	commit  string
+	builtAt string
+)
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuiltAt   string `json:"built_at"`
+	GoVersion string `json:"go_version"`
+}
+
+// readBuildInfo returns the build variables, filled in from
+// debug.ReadBuildInfo where they were not set at link time.
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuiltAt:   builtAt,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+				if len(info.Commit) > 7 {
+					info.Commit = info.Commit[:7]
+				}
+			case setting.Key == "vcs.time" && info.BuiltAt == "":
+				info.BuiltAt = setting.Value
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// getVersion writes the version, commit, build time and Go version of
+// the running binary in JSON format to the provided http.ResponseWriter.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - req: The http.Request received from the client. This parameter is ignored in this function.
+func (s *Server) getVersion(writer http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(writer, http.StatusOK, s.buildInfo)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+)
+
+// setBuildVariables sets the link-time build variables for the rest of
+// the test.
+func setBuildVariables(t *testing.T, v, c, b string) {
+	t.Helper()
+	oldVersion, oldCommit, oldBuiltAt := version, commit, builtAt
+	version, commit, builtAt = v, c, b
+	t.Cleanup(func() { version, commit, builtAt = oldVersion, oldCommit, oldBuiltAt })
+}
+
+func TestReadBuildInfo(t *testing.T) {
+	t.Run("link-time variables", func(t *testing.T) {
+		setBuildVariables(t, "1.2.0", "abc1234", "2024-01-15T12:00:00Z")
+		want := BuildInfo{Version: "1.2.0", Commit: "abc1234", BuiltAt: "2024-01-15T12:00:00Z", GoVersion: runtime.Version()}
+		if got := readBuildInfo(); got != want {
+			t.Errorf("readBuildInfo() = %+v, want %+v", got, want)
+		}
+	})
+	t.Run("unset", func(t *testing.T) {
+		setBuildVariables(t, "", "", "")
+		// Test binaries carry no module version, whatever VCS
+		// information they have.
+		info := readBuildInfo()
+		if info.Version != "dev" || info.GoVersion != runtime.Version() {
+			t.Errorf("readBuildInfo() = %+v, want version dev", info)
+		}
+		if len(info.Commit) > 7 {
+			t.Errorf("Commit = %q, want at most 7 characters", info.Commit)
+		}
+	})
+}
+
+func TestGetVersion(t *testing.T) {
+	setBuildVariables(t, "1.2.0", "abc1234", "2024-01-15T12:00:00Z")
+	s, _ := newTestServer(t)
+
+	response := serve(s, http.MethodGet, "/version", "")
+	assertStatus(t, response, http.StatusOK)
+	if got := response.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var info BuildInfo
+	decodeJSON(t, response.Body.Bytes(), &info)
+	want := BuildInfo{Version: "1.2.0", Commit: "abc1234", BuiltAt: "2024-01-15T12:00:00Z", GoVersion: runtime.Version()}
+	if info != want {
+		t.Errorf("GET /version = %+v, want %+v", info, want)
+	}
+}
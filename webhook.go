@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// webhookJobType is the type of the jobs webhookWorker processes.
+	webhookJobType = "webhook-delivery"
+	// webhookTimeout bounds a webhook delivery.
+	webhookTimeout = 10 * time.Second
+	// webhookMaxResponseBytes is how much of the answer of a receiver
+	// a WebhookResult keeps.
+	webhookMaxResponseBytes = 4 << 10
+)
+
+// WebhookResult is the result of a webhook delivery job. The job drops
+// its payload once finished, so the URL and body sent are kept here
+// for the delivery log of GET /admin/webhooks/deliveries.
+type WebhookResult struct {
+	URL     string          `json:"url"`
+	Payload json.RawMessage `json:"payload"`
+	// StatusCode is the status the receiver answered with, or 0 if it
+	// could not be reached, and ResponseBody the start of its answer,
+	// up to webhookMaxResponseBytes.
+	StatusCode   int    `json:"status_code"`
+	ResponseBody string `json:"response_body,omitempty"`
+	// DurationMs is how long the delivery took, in milliseconds.
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// webhookWorker delivers webhooks. Its payload is {"url":...,
+// "body":...}: body, any JSON value, is sent to url, an http or https
+// URL, in a POST request. A delivery fails unless the receiver answers
+// with a 2xx status; its WebhookResult is recorded either way once the
+// request was sent.
+type webhookWorker struct {
+	server *Server
+	client *http.Client
+}
+
+func (worker webhookWorker) Process(ctx context.Context, job Job) error {
+	var payload struct {
+		URL  string          `json:"url"`
+		Body json.RawMessage `json:"body"`
+	}
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("expected a payload of {\"url\", \"body\"}: %w", err)
+	}
+	target, err := url.Parse(payload.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("webhook URL %q is not an http or https URL", payload.URL)
+	}
+	if len(payload.Body) == 0 {
+		return errors.New("webhook body is missing")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(payload.Body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Webhook-ID", job.ID)
+
+	result := WebhookResult{URL: target.String(), Payload: payload.Body}
+	start := time.Now()
+	response, err := worker.client.Do(request)
+	if err != nil {
+		result.DurationMs = time.Since(start).Milliseconds()
+		if reportErr := worker.server.runner.Report(ctx, job.ID, 0, result); reportErr != nil {
+			return reportErr
+		}
+		return err
+	}
+	defer response.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(response.Body, webhookMaxResponseBytes))
+	io.Copy(io.Discard, response.Body)
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.StatusCode = response.StatusCode
+	result.ResponseBody = string(answer)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		if err = worker.server.runner.Report(ctx, job.ID, 0, result); err != nil {
+			return err
+		}
+		return fmt.Errorf("webhook receiver answered %s", response.Status)
+	}
+	return worker.server.runner.Report(ctx, job.ID, 100, result)
+}
+
+// WebhookEvent is the body of the webhooks POST /admin/webhooks/test
+// fires: the event type and the task as GET /tasks/{id} shows it.
+type WebhookEvent struct {
+	Event string `json:"event"`
+	Task  Task   `json:"task"`
+}
+
+// WebhookDelivery describes a webhook delivery job in the delivery log.
+// The WebhookResult fields are only set once the request was sent.
+type WebhookDelivery struct {
+	ID     string    `json:"job_id"`
+	Status JobStatus `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	WebhookResult
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// webhookDelivery describes a webhook delivery job.
+func webhookDelivery(job Job) WebhookDelivery {
+	delivery := WebhookDelivery{ID: job.ID, Status: job.Status, Error: job.Error, CreatedAt: job.CreatedAt}
+	if len(job.Result) > 0 {
+		// The result is written by webhookWorker, so it decodes.
+		json.Unmarshal(job.Result, &delivery.WebhookResult)
+	}
+	return delivery
+}
+
+func isWebhookJob(job Job) bool {
+	return job.Type == webhookJobType
+}
+
+// postAdminWebhookTest fires the webhook of the event event_type, one
+// of task.created, task.updated and task.deleted, for the task task_id
+// at url, and writes the WebhookDelivery with the status code and body
+// the receiver answered with. The delivery is made right away, as a
+// webhook-delivery job that is listed with the others, and answered
+// with a HTTP 200 OK even if it failed; the delivery tells why. An
+// unknown task is reported with the status chosen by
+// storageErrorStatus.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, with the event type, task ID and URL in the body.
+func (s *Server) postAdminWebhookTest(writer http.ResponseWriter, request *http.Request) {
+	var body struct {
+		EventType string `json:"event_type"`
+		TaskID    string `json:"task_id"`
+		URL       string `json:"url"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		s.logger.Warn("Некорректное тело проверки вебхука", "error", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.Get(request.Context(), body.TaskID)
+	if errors.Is(err, ErrNotFound) {
+		s.logger.Warn("Задача для вебхука не найдена", "id", body.TaskID)
+		localizedError(writer, request, MsgTaskNotFound, storageErrorStatus(err))
+		return
+	} else if err != nil {
+		s.logger.Error("Не удалось получить задачу", "id", body.TaskID, "error", err)
+		http.Error(writer, err.Error(), storageErrorStatus(err))
+		return
+	}
+	s.presentTask(&task)
+
+	event, err := json.Marshal(WebhookEvent{Event: body.EventType, Task: task})
+	if err == nil {
+		event, err = json.Marshal(map[string]interface{}{"url": body.URL, "body": json.RawMessage(event)})
+	}
+	if err != nil {
+		s.logger.Error("Не удалось сериализовать вебхук", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	job, err := s.runner.Do(request.Context(), webhookJobType, event)
+	if err != nil {
+		s.reportSubmitError(writer, err)
+		return
+	}
+
+	s.writeJSON(writer, http.StatusOK, webhookDelivery(job))
+	s.logger.Info("Вебхук отправлен для проверки", "id", job.ID, "event", body.EventType, "status", job.Status)
+}
+
+const (
+	// webhookDeliveriesDefaultLimit and webhookDeliveriesMaxLimit bound
+	// the number of deliveries GET /admin/webhooks/deliveries lists.
+	webhookDeliveriesDefaultLimit = 20
+	webhookDeliveriesMaxLimit     = 100
+)
+
+// getAdminWebhookDeliveries writes the last limit (20 by default, at
+// most 100) webhook delivery jobs, newest first, each as a
+// WebhookDelivery. They are kept as long as other jobs, see jobTTL. A
+// malformed limit is rejected with a HTTP 400 Bad Request.
+//
+// Parameters:
+//   - writer: The http.ResponseWriter used to write the response.
+//   - request: The http.Request received from the client, including the limit query parameter.
+func (s *Server) getAdminWebhookDeliveries(writer http.ResponseWriter, request *http.Request) {
+	limit := webhookDeliveriesDefaultLimit
+	if value := request.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > webhookDeliveriesMaxLimit {
+			s.logger.Warn("Некорректный лимит доставок вебхуков", "limit", value)
+			http.Error(writer, "limit must be a number from 1 to "+strconv.Itoa(webhookDeliveriesMaxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	jobs, err := s.sortedJobs(request.Context(), isWebhookJob)
+	if err != nil {
+		s.logger.Error("Не удалось получить список заданий", "error", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	deliveries := make([]WebhookDelivery, 0, min(limit, len(jobs)))
+	for _, job := range jobs[:min(limit, len(jobs))] {
+		deliveries = append(deliveries, webhookDelivery(job))
+	}
+	s.writeJSON(writer, http.StatusOK, deliveries)
+}